@@ -5,6 +5,7 @@ import (
 	secret "github.com/oligarch316/go-auth-service/pkg/http/secret/command"
 	token "github.com/oligarch316/go-auth-service/pkg/http/token/command"
 	user "github.com/oligarch316/go-auth-service/pkg/http/user/command"
+	db "github.com/oligarch316/go-auth-service/pkg/store/command"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
 )
 
@@ -16,16 +17,25 @@ func main() {
 		secretCmd = secret.New("secret", ns)
 		tokenCmd  = token.New("token", ns)
 		userCmd   = user.New("user", ns)
+		dbCmd     = db.New("db")
+
+		keysCmd    = token.NewKeys("keys", ns)
+		migrateCmd = db.NewMigrate("migrate", ns)
+		statusCmd  = db.NewStatus("status", ns)
 	)
 
 	ns.SetFlags(authCmd.Flags())
 	ns.SetFlags(secretCmd.Flags())
 	ns.SetFlags(tokenCmd.Flags())
 	ns.SetFlags(userCmd.Flags())
+	ns.SetFlags(keysCmd.Flags())
+	ns.SetFlags(migrateCmd.Flags())
+	ns.SetFlags(statusCmd.Flags())
 
 	secretCmd.AddCommand(secret.NewConfig("config", ns), secret.NewVersion("version"))
-	tokenCmd.AddCommand(token.NewConfig("config", ns), token.NewVersion("version"))
+	tokenCmd.AddCommand(token.NewConfig("config", ns), token.NewVersion("version"), keysCmd)
 	userCmd.AddCommand(user.NewConfig("config", ns), user.NewVersion("version"))
+	dbCmd.AddCommand(db.NewConfig("config", ns), migrateCmd, statusCmd)
 
 	authCmd.AddCommand(
 		command.NewConfig("config", ns),
@@ -33,6 +43,7 @@ func main() {
 		secretCmd,
 		tokenCmd,
 		userCmd,
+		dbCmd,
 	)
 
 	authCmd.Execute()
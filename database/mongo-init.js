@@ -0,0 +1,19 @@
+// Replica set (required for multi-document transactions)
+try {
+    rs.status();
+} catch (e) {
+    rs.initiate();
+    while (!db.isMaster().ismaster) { sleep(100); }
+}
+
+db = db.getSiblingDB('authsvc');
+
+// Users collection
+db.users.createIndex({ name: 1 }, { unique: true });
+
+// Initial user
+db.users.updateOne(
+    { name: 'testuser' },
+    { $setOnInsert: { name: 'testuser', password_hash: '$2a$10$uISdA44MZq7ePA0a/mea5uWb292tY.LRm87u.TmwOU9/51E02pTyG', admin: true } },
+    { upsert: true }
+);
@@ -1,7 +1,7 @@
 -- Users table
 CREATE TABLE users (
     id INTEGER PRIMARY KEY,
-    name TEXT NOT NULL,
+    name TEXT NOT NULL UNIQUE,
     display_name TEXT,
     password_hash TEXT NOT NULL,
     admin INTEGER DEFAULT 0
@@ -13,7 +13,10 @@ require (
 	github.com/mattn/go-sqlite3 v2.0.3+incompatible
 	github.com/oligarch316/go-skeleton v0.0.0-20200712061043-fe0255e39ee0
 	github.com/spf13/cobra v0.0.6
+	go.mongodb.org/mongo-driver v1.3.5
 	go.uber.org/zap v1.14.0
 	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
+	google.golang.org/grpc v1.30.0
 	gopkg.in/alexcesaro/statsd.v2 v2.0.0
 )
@@ -2,6 +2,7 @@ package claims
 
 // Default TODO.
 const (
+	DefaultAudienceNameReset  = "reset"
 	DefaultAudienceNameSignup = "signup"
 	DefaultAudienceNameUser   = "user"
 
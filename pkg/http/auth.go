@@ -0,0 +1,99 @@
+package httpsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap/zapcore"
+)
+
+// RouteAuth TODO
+type RouteAuth struct {
+	// Token audience to validate against, as understood by the Authenticator
+	Audience string `json:"audience"`
+
+	// Optional, when set the subject must equal this url parameter's value
+	SelfParam string `json:"selfParam,omitempty"`
+
+	// Subject must have admin privileges
+	Admin bool `json:"admin,omitempty"`
+}
+
+// MarshalLogObject TODO
+func (ra RouteAuth) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("audience", ra.Audience)
+	enc.AddString("selfParam", ra.SelfParam)
+	enc.AddBool("admin", ra.Admin)
+	return nil
+}
+
+// Authenticator TODO
+type Authenticator interface {
+	Authenticate(r *http.Request, audience string) (subject string, err error)
+	IsAdmin(ctx context.Context, subject string) (bool, error)
+}
+
+type subjectKey struct{}
+
+// Subject TODO
+//
+// Returns the subject authenticated for the request, empty for routes without
+// auth requirements.
+func Subject(ctx context.Context) string {
+	res, _ := ctx.Value(subjectKey{}).(string)
+	return res
+}
+
+// WithAuthenticator TODO
+func (rtr *Router) WithAuthenticator(authn Authenticator) *Router {
+	res := rtr.Child("/")
+	res.authn = authn
+	return res
+}
+
+func (rtr *Router) wrapAuth(auth RouteAuth, handle httprouter.Handle) httprouter.Handle {
+	if rtr.authn == nil {
+		panic(fmt.Sprintf("route requires '%s' auth, but no authenticator is set", auth.Audience))
+	}
+
+	authn := rtr.authn
+
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		// Validate token
+		subject, err := authn.Authenticate(r, auth.Audience)
+		if err != nil {
+			rtr.servelet.HandleErr(w, r, NewError(http.StatusUnauthorized, err, "failed to validate %s token", auth.Audience))
+			return
+		}
+
+		// Confirm id match
+		if auth.SelfParam != "" && subject != params.ByName(auth.SelfParam) {
+			rtr.servelet.HandleErr(w, r, NewError(
+				http.StatusForbidden,
+				fmt.Errorf("subject does not match url parameter '%s'", auth.SelfParam),
+				"failed to confirm subject",
+			))
+			return
+		}
+
+		// Assert admin privilages
+		if auth.Admin {
+			admin, err := authn.IsAdmin(r.Context(), subject)
+			if err != nil {
+				rtr.servelet.HandleErr(w, r, StoreError(err, "failed to confirm admin privilages"))
+				return
+			}
+
+			if !admin {
+				rtr.servelet.HandleErr(w, r, NewError(http.StatusForbidden, errors.New("subject is not an admin"), "failed to confirm admin privilages"))
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), subjectKey{}, subject)
+		handle(w, r.WithContext(ctx), params)
+	}
+}
@@ -0,0 +1,185 @@
+package httpsvc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const testAudience = "test"
+
+// fakeAuthenticator accepts bearer tokens of the form "<audience>:<subject>"
+type fakeAuthenticator struct {
+	admins   map[string]bool
+	adminErr error
+}
+
+func (fa fakeAuthenticator) Authenticate(r *http.Request, audience string) (string, error) {
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	parts := strings.SplitN(bearer, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", errors.New("missing or malformed token")
+	}
+
+	if parts[0] != audience {
+		return "", errors.New("invalid audience")
+	}
+
+	return parts[1], nil
+}
+
+func (fa fakeAuthenticator) IsAdmin(_ context.Context, subject string) (bool, error) {
+	return fa.admins[subject], fa.adminErr
+}
+
+// Handle writing the authenticated subject
+func handleSubject(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	w.Write([]byte(Subject(r.Context())))
+}
+
+func testRoute(name, method string, handle httprouter.Handle, auth *RouteAuth) Route {
+	return Route{
+		RouteInfo: RouteInfo{Name: name, Method: method, MetricTag: name},
+		Handle:    handle,
+		Auth:      auth,
+	}
+}
+
+func serveRouter(router *Router, method, urlPath, bearer string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, urlPath, nil)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouteAuth(t *testing.T) {
+	newRouter := func(authn fakeAuthenticator) *Router {
+		res := NewRouter(testServelet(t), "/").WithAuthenticator(authn)
+
+		res.Add(testRoute("open", http.MethodGet, handleSubject, nil), "/open")
+		res.Add(testRoute("user", http.MethodGet, handleSubject, &RouteAuth{Audience: testAudience}), "/user")
+		res.Add(testRoute("self", http.MethodGet, handleSubject, &RouteAuth{Audience: testAudience, SelfParam: "id"}), "/self/%P", "id")
+		res.Add(testRoute("admin", http.MethodGet, handleSubject, &RouteAuth{Audience: testAudience, Admin: true}), "/admin")
+
+		return res
+	}
+
+	tests := []struct {
+		name          string
+		authn         fakeAuthenticator
+		path, bearer  string
+		expectStatus  int
+		expectCode    string
+		expectSubject string
+	}{
+		{name: "no auth required", path: "/open", expectStatus: http.StatusOK},
+		{name: "no auth required with token", path: "/open", bearer: "test:alice", expectStatus: http.StatusOK},
+
+		{name: "valid token", path: "/user", bearer: "test:alice", expectStatus: http.StatusOK, expectSubject: "alice"},
+		{name: "missing token", path: "/user", expectStatus: http.StatusUnauthorized, expectCode: CodeUnauthenticated},
+		{name: "wrong audience", path: "/user", bearer: "other:alice", expectStatus: http.StatusUnauthorized, expectCode: CodeUnauthenticated},
+
+		{name: "self match", path: "/self/alice", bearer: "test:alice", expectStatus: http.StatusOK, expectSubject: "alice"},
+		{name: "self mismatch", path: "/self/bob", bearer: "test:alice", expectStatus: http.StatusForbidden, expectCode: CodeForbidden},
+		{name: "self without token", path: "/self/alice", expectStatus: http.StatusUnauthorized, expectCode: CodeUnauthenticated},
+
+		{
+			name:          "admin",
+			authn:         fakeAuthenticator{admins: map[string]bool{"alice": true}},
+			path:          "/admin",
+			bearer:        "test:alice",
+			expectStatus:  http.StatusOK,
+			expectSubject: "alice",
+		},
+		{
+			name:         "non admin",
+			authn:        fakeAuthenticator{admins: map[string]bool{"alice": true}},
+			path:         "/admin",
+			bearer:       "test:bob",
+			expectStatus: http.StatusForbidden,
+			expectCode:   CodeForbidden,
+		},
+		{
+			name:         "admin lookup failure",
+			authn:        fakeAuthenticator{admins: map[string]bool{"alice": true}, adminErr: errors.New("store unavailable")},
+			path:         "/admin",
+			bearer:       "test:alice",
+			expectStatus: http.StatusInternalServerError,
+			expectCode:   CodeInternal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serveRouter(newRouter(test.authn), http.MethodGet, test.path, test.bearer)
+
+			if rec.Code != test.expectStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.expectStatus, rec.Code, rec.Body.String())
+			}
+
+			if test.expectCode != "" {
+				if body := decodeErrBody(t, rec); body["code"] != test.expectCode {
+					t.Errorf("expected code '%s', got '%v'", test.expectCode, body["code"])
+				}
+				return
+			}
+
+			if actual := rec.Body.String(); actual != test.expectSubject {
+				t.Errorf("expected subject '%s', got '%s'", test.expectSubject, actual)
+			}
+		})
+	}
+}
+
+func TestRouteAuthRouteData(t *testing.T) {
+	router := NewRouter(testServelet(t), "/").WithAuthenticator(fakeAuthenticator{})
+
+	auth := &RouteAuth{Audience: testAudience, Admin: true}
+	router.Add(testRoute("admin", http.MethodGet, handleSubject, auth), "/admin")
+	router.Add(testRoute("open", http.MethodGet, handleSubject, nil), "/open")
+
+	if actual := router.dataMap["admin"].Auth; actual == nil || *actual != *auth {
+		t.Errorf("expected auth %+v recorded, got %+v", auth, actual)
+	}
+
+	if actual := router.dataMap["open"].Auth; actual != nil {
+		t.Errorf("expected no auth recorded, got %+v", actual)
+	}
+}
+
+func TestWithAuthenticator(t *testing.T) {
+	var (
+		root   = NewRouter(testServelet(t), "/")
+		authed = root.WithAuthenticator(fakeAuthenticator{})
+		child  = authed.Child("/child")
+	)
+
+	// Children inherit the authenticator
+	child.Add(testRoute("child", http.MethodGet, handleSubject, &RouteAuth{Audience: testAudience}), "/user")
+
+	rec := serveRouter(root, http.MethodGet, "/child/user", "test:alice")
+	if rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+		t.Errorf("expected child route authenticated as 'alice', got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// ... but parents do not
+	t.Run("missing authenticator", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic adding an auth route without an authenticator")
+			}
+		}()
+
+		root.Add(testRoute("root", http.MethodGet, handleSubject, &RouteAuth{Audience: testAudience}), "/user")
+	})
+}
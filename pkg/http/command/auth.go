@@ -2,11 +2,11 @@ package command
 
 import (
 	"log"
-	"net/http"
 	"os"
 
 	"github.com/oligarch316/go-auth-service/pkg/http"
 	secret "github.com/oligarch316/go-auth-service/pkg/http/secret/command"
+	httptoken "github.com/oligarch316/go-auth-service/pkg/http/token"
 	token "github.com/oligarch316/go-auth-service/pkg/http/token/command"
 	user "github.com/oligarch316/go-auth-service/pkg/http/user/command"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
@@ -48,14 +48,27 @@ func run(ns *namespace.NS) int {
 	root.Logger.Info("loaded configuration", zap.Strings("sources", srcRecord))
 	root.Logger.Info("created observability core", zap.Object("config", cfg.Observ))
 
+	// ----- Passwords
+	passwords, err := cfg.Password.Build()
+	if err != nil {
+		root.Logger.Error("failed to create password hashers", zap.Error(err))
+		return 1
+	}
+
+	root.Logger.Info("created password hashers", zap.Object("config", cfg.Password))
+
 	// ----- Database
-	db, err := cfg.DB.Build(observCore.Named("database"))
+	db, err := cfg.DB.Build(passwords, observCore.Named("database"))
 	if err != nil {
 		root.Logger.Error("failed to create database", zap.Error(err))
 		return 1
 	}
 
-	defer db.Close()
+	defer func() {
+		if err := db.Close(); err != nil {
+			root.Logger.Error("failed to close database", zap.Error(err))
+		}
+	}()
 
 	root.Logger.Info("created database", zap.Object("config", cfg.DB))
 
@@ -65,43 +78,88 @@ func run(ns *namespace.NS) int {
 		router   = httpsvc.NewRouter(servelet, "/")
 	)
 
+	if cfg.SecretSvc.Discovery.Issuer == "" {
+		cfg.SecretSvc.Discovery.Issuer = cfg.TokenSvc.IssuerName
+	}
+
+	if cfg.UserSvc.RevocationTTL.Duration == 0 {
+		cfg.UserSvc.RevocationTTL = cfg.TokenSvc.MaxTTL
+	}
+
+	cfg.SecretSvc.Discovery.ResolveURLs(
+		cfg.TLS.Scheme(),
+		cfg.Address,
+		cfg.TLS.Scheme()+"://"+cfg.Address+httptoken.UserPath(),
+	)
+
 	secretSvr, err := secret.NewServer(cfg.SecretSvc, servelet.Named("secret"))
 	if err != nil {
 		root.Logger.Error("failed to create secret server", zap.Error(err))
 		return 1
 	}
 
-	tokenSvr, err := token.NewServer(cfg.TokenSvc, servelet.Named("token"), db)
+	loadConfig := func() (secret.Config, error) {
+		next := defaultCmdConfig()
+		_, err := ns.LoadAndRecord(&next)
+		return next.SecretSvc, err
+	}
+
+	stopWatch := secret.WatchKeySet(secretSvr.Set, loadConfig, cfg.SecretSvc.ReloadInterval.Duration, observCore.Named("keyset"))
+	defer stopWatch()
+
+	tokenSvr, err := token.NewServer(cfg.TokenSvc, servelet.Named("token"), db, passwords)
 	if err != nil {
 		root.Logger.Error("failed to create token server", zap.Error(err))
 		return 1
 	}
 
-	userSvr, err := user.NewServer(cfg.UserSvc, servelet.Named("user"), db)
+	defer tokenSvr.Revocations.Close()
+
+	stopPrune := tokenSvr.Prune()
+	defer stopPrune()
+
+	// NOTE: Keys are read from the in-process secret server, an http round trip
+	// to our own listener would need to follow its TLS configuration
+	userSvr, err := user.NewLocalServer(cfg.UserSvc, servelet.Named("user"), db, passwords, secretSvr.Set)
 	if err != nil {
 		root.Logger.Error("failed to create user server", zap.Error(err))
 		return 1
 	}
 
+	defer userSvr.Close()
+
 	secretSvr.AddRoutes(router)
 	tokenSvr.AddRoutes(router)
 	userSvr.AddRoutes(router)
 	router.AddMetaRoutes()
 
+	// ----- TLS
+	serverTLS, err := httpsvc.NewServerTLS(cfg.TLS, observCore.Named("tls"))
+	if err != nil {
+		root.Logger.Error("failed to create tls configuration", zap.Error(err))
+		return 1
+	}
+
+	defer serverTLS.Close()
+
+	if serverTLS == nil {
+		root.Logger.Warn("tls disabled, serving plaintext http")
+	}
+
 	// ----- Run
 	root.Logger.Info(
 		"starting server",
 		zap.String("address", cfg.Address),
+		zap.Object("http", cfg.HTTP),
+		zap.Object("tls", cfg.TLS),
 		zap.Array("routes", router),
 	)
 
-	// TODO: Signal handlers
-	// TODO: TLS only
-
-	if err := http.ListenAndServe(cfg.Address, router); err != http.ErrServerClosed {
+	if err := cfg.HTTP.ListenAndServe(cfg.Address, router, serverTLS.Config(), root.Logger); err != nil {
 		root.Logger.Error("server failure", zap.Error(err))
 		return 1
 	}
 
+	root.Logger.Info("server stopped")
 	return 0
 }
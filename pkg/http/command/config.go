@@ -1,9 +1,11 @@
 package command
 
 import (
+	"github.com/oligarch316/go-auth-service/pkg/http"
 	secret "github.com/oligarch316/go-auth-service/pkg/http/secret/command"
 	token "github.com/oligarch316/go-auth-service/pkg/http/token/command"
 	user "github.com/oligarch316/go-auth-service/pkg/http/user/command"
+	"github.com/oligarch316/go-auth-service/pkg/password"
 	"github.com/oligarch316/go-auth-service/pkg/store"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
 	"github.com/oligarch316/go-skeleton/pkg/observ"
@@ -13,23 +15,34 @@ import (
 const defaultAddress = "localhost:8000"
 
 type cmdConfig struct {
-	Address   string        `json:"address"`
-	DB        store.Config  `json:"db"`
-	Observ    observ.Config `json:"observ"`
-	SecretSvc secret.Config `json:"secretsvc"`
-	TokenSvc  token.Config  `json:"tokensvc"`
-	UserSvc   user.Config   `json:"usersvc"`
+	Address   string               `json:"address"`
+	HTTP      httpsvc.ConfigServer `json:"http"`
+	TLS       httpsvc.ConfigTLS    `json:"tls"`
+	DB        store.Config         `json:"db"`
+	Password  password.Config      `json:"password"`
+	Observ    observ.Config        `json:"observ"`
+	SecretSvc secret.Config        `json:"secretsvc"`
+	TokenSvc  token.Config         `json:"tokensvc"`
+	UserSvc   user.Config          `json:"usersvc"`
 }
 
 func defaultCmdConfig() cmdConfig {
+	// NOTE: Revocation TTL left empty to follow the token service max TTL
 	userSvcConfig := user.DefaultConfig()
-	userSvcConfig.SecretCache.Client.Address = defaultAddress
+	userSvcConfig.RevocationTTL.Duration = 0
+
+	// NOTE: Issuer left empty to follow the token service issuer name
+	secretSvcConfig := secret.DefaultConfig()
+	secretSvcConfig.Discovery.Issuer = ""
 
 	return cmdConfig{
 		Address:   defaultAddress,
+		HTTP:      httpsvc.DefaultServerConfig(),
+		TLS:       httpsvc.DefaultTLSConfig(),
 		DB:        store.DefaultConfig(),
+		Password:  password.DefaultConfig(),
 		Observ:    observ.DefaultConfig(),
-		SecretSvc: secret.DefaultConfig(),
+		SecretSvc: secretSvcConfig,
 		TokenSvc:  token.DefaultConfig(),
 		UserSvc:   userSvcConfig,
 	}
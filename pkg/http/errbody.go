@@ -0,0 +1,35 @@
+package httpsvc
+
+import (
+	"net/http"
+
+	"github.com/golang/gddo/httputil"
+)
+
+const (
+	cTypeJSON        = "application/json"
+	cTypeProblemJSON = "application/problem+json"
+)
+
+type errBody struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"requestID,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// NOTE: RFC 7807, with code, requestID and fields as extension members
+type problemBody struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"requestID,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// NOTE: Problem details only when explicitly preferred, plain json otherwise
+func negotiateErrContentType(r *http.Request) string {
+	return httputil.NegotiateContentType(r, []string{cTypeJSON, cTypeProblemJSON}, cTypeJSON)
+}
@@ -0,0 +1,180 @@
+package httpsvc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+	statsd "gopkg.in/alexcesaro/statsd.v2"
+)
+
+func testServelet(t *testing.T) *Servelet {
+	t.Helper()
+
+	emitter, err := statsd.New(statsd.Mute(true))
+	if err != nil {
+		t.Fatalf("failed to create emitter: %s", err)
+	}
+
+	return &Servelet{Corelet: &observ.Corelet{Logger: zap.NewNop(), Emitter: emitter}}
+}
+
+// Serve a single route failing with the given error
+func serveErr(t *testing.T, herr Error, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var (
+		servelet = testServelet(t)
+		router   = NewRouter(servelet, "/")
+	)
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) { servelet.HandleErr(w, r, herr) }
+	router.Add(Route{RouteInfo: RouteInfo{Name: "fail", Method: http.MethodGet, MetricTag: "fail"}, Handle: handle}, "/fail")
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeErrBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode error body '%s': %s", rec.Body.String(), err)
+	}
+
+	return res
+}
+
+func TestErrBodyShape(t *testing.T) {
+	const secretText = "select * from users where name = 'alice'"
+
+	tests := []struct {
+		name         string
+		herr         Error
+		expectStatus int
+		expectCode   string
+		expectFields map[string]interface{}
+	}{
+		{
+			name:         "client error",
+			herr:         NewError(http.StatusBadRequest, errors.New(secretText), "failed to load request body"),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   CodeBadRequest,
+		},
+		{
+			name:         "store not found",
+			herr:         StoreError(storeerr.NotFound(errors.New(secretText)), "failed to read user"),
+			expectStatus: http.StatusNotFound,
+			expectCode:   CodeNotFound,
+		},
+		{
+			name:         "internal error",
+			herr:         InternalError(errors.New(secretText), "failed to read user"),
+			expectStatus: http.StatusInternalServerError,
+			expectCode:   CodeInternal,
+		},
+		{
+			name:         "custom code with fields",
+			herr:         NewError(http.StatusTooManyRequests, errors.New(secretText), "too many attempts").WithCode("login_throttled").WithField("retryAfter", "30"),
+			expectStatus: http.StatusTooManyRequests,
+			expectCode:   "login_throttled",
+			expectFields: map[string]interface{}{"retryAfter": "30"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, accept := range []string{"", cTypeProblemJSON} {
+				rec := serveErr(t, test.herr, accept)
+
+				if rec.Code != test.expectStatus {
+					t.Errorf("accept '%s': expected status %d, got %d", accept, test.expectStatus, rec.Code)
+				}
+
+				if strings.Contains(rec.Body.String(), secretText) {
+					t.Errorf("accept '%s': wrapped error text leaked in body: %s", accept, rec.Body.String())
+				}
+
+				body := decodeErrBody(t, rec)
+
+				if body["code"] != test.expectCode {
+					t.Errorf("accept '%s': expected code '%s', got '%v'", accept, test.expectCode, body["code"])
+				}
+
+				if _, ok := body["error"]; ok {
+					t.Errorf("accept '%s': unexpected 'error' member in body", accept)
+				}
+
+				if body["requestID"] != rec.Header().Get(HeaderRequestID) || body["requestID"] == "" {
+					t.Errorf("accept '%s': expected requestID '%s', got '%v'", accept, rec.Header().Get(HeaderRequestID), body["requestID"])
+				}
+
+				fields, _ := body["fields"].(map[string]interface{})
+				if len(fields) != len(test.expectFields) {
+					t.Errorf("accept '%s': expected fields %v, got %v", accept, test.expectFields, fields)
+				}
+
+				for k, v := range test.expectFields {
+					if fields[k] != v {
+						t.Errorf("accept '%s': expected field '%s' = '%v', got '%v'", accept, k, v, fields[k])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestErrBodyContentType(t *testing.T) {
+	herr := NewError(http.StatusForbidden, errors.New("forbidden"), "failed to confirm subject")
+
+	t.Run("plain json", func(t *testing.T) {
+		rec := serveErr(t, herr, "application/json")
+
+		if cType := rec.Header().Get("Content-Type"); cType != "application/json; charset=utf-8" {
+			t.Errorf("expected plain json content type, got '%s'", cType)
+		}
+
+		body := decodeErrBody(t, rec)
+		if body["message"] != "failed to confirm subject" {
+			t.Errorf("expected message 'failed to confirm subject', got '%v'", body["message"])
+		}
+	})
+
+	t.Run("problem json", func(t *testing.T) {
+		rec := serveErr(t, herr, cTypeProblemJSON)
+
+		if cType := rec.Header().Get("Content-Type"); cType != "application/problem+json; charset=utf-8" {
+			t.Errorf("expected problem json content type, got '%s'", cType)
+		}
+
+		body := decodeErrBody(t, rec)
+
+		expected := map[string]interface{}{
+			"type":   "about:blank",
+			"title":  "Forbidden",
+			"status": float64(http.StatusForbidden),
+			"detail": "failed to confirm subject",
+			"code":   CodeForbidden,
+		}
+
+		for k, v := range expected {
+			if body[k] != v {
+				t.Errorf("expected '%s' = '%v', got '%v'", k, v, body[k])
+			}
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package httpsvc
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Middleware TODO
+//
+// Wraps next, the handle for route, with additional behavior. Route data is
+// provided so middleware can vary per route (e.g. by name or auth).
+type Middleware func(route Route, next httprouter.Handle) httprouter.Handle
+
+// Use TODO
+//
+// Middleware applies to every route subsequently added to this router or any
+// of its children, in the order given and after that of parent routers.
+func (rtr *Router) Use(mw ...Middleware) {
+	rtr.middleware = append(rtr.middleware, mw...)
+}
+
+func (rtr *Router) middlewareChain() []Middleware {
+	var res []Middleware
+
+	if rtr.parent != nil {
+		res = rtr.parent.middlewareChain()
+	}
+
+	return append(res, rtr.middleware...)
+}
+
+func (rtr *Router) wrapMiddleware(route Route, handle httprouter.Handle) httprouter.Handle {
+	chain := rtr.middlewareChain()
+
+	// NOTE: Wrap in reverse, so the first middleware is outermost
+	for i := len(chain) - 1; i >= 0; i-- {
+		handle = chain[i](route, handle)
+	}
+
+	return handle
+}
+
+// MaxBodySize TODO
+func MaxBodySize(n int64) Middleware {
+	return func(_ Route, next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next(w, r, params)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package httpsvc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// trace records the order in which middleware and handlers run
+type trace []string
+
+func (tr *trace) middleware(name string) Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			*tr = append(*tr, name+":"+route.Name)
+			next(w, r, params)
+		}
+	}
+}
+
+func (tr *trace) handle(name string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		*tr = append(*tr, name)
+	}
+}
+
+func expectTrace(t *testing.T, actual trace, expected ...string) {
+	t.Helper()
+
+	if !reflect.DeepEqual([]string(actual), expected) {
+		t.Errorf("expected trace %v, got %v", expected, actual)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var (
+		tr     trace
+		parent = NewRouter(testServelet(t), "/")
+		child  = parent.Child("/child")
+	)
+
+	parent.Use(tr.middleware("p1"), tr.middleware("p2"))
+	child.Use(tr.middleware("c1"))
+	child.Use(tr.middleware("c2"))
+
+	parent.Add(testRoute("parent", http.MethodGet, tr.handle("parent"), nil), "/route")
+	child.Add(testRoute("child", http.MethodGet, tr.handle("child"), nil), "/route")
+
+	t.Run("parent then child", func(t *testing.T) {
+		tr = nil
+		serveRouter(parent, http.MethodGet, "/child/route", "")
+		expectTrace(t, tr, "p1:child", "p2:child", "c1:child", "c2:child", "child")
+	})
+
+	t.Run("child middleware excluded from parent", func(t *testing.T) {
+		tr = nil
+		serveRouter(parent, http.MethodGet, "/route", "")
+		expectTrace(t, tr, "p1:parent", "p2:parent", "parent")
+	})
+}
+
+func TestMiddlewareSubsequentRoutes(t *testing.T) {
+	var (
+		tr     trace
+		router = NewRouter(testServelet(t), "/")
+	)
+
+	router.Add(testRoute("before", http.MethodGet, tr.handle("before"), nil), "/before")
+	router.Use(tr.middleware("mw"))
+	router.Add(testRoute("after", http.MethodGet, tr.handle("after"), nil), "/after")
+
+	serveRouter(router, http.MethodGet, "/before", "")
+	expectTrace(t, tr, "before")
+
+	tr = nil
+	serveRouter(router, http.MethodGet, "/after", "")
+	expectTrace(t, tr, "mw:after", "after")
+}
+
+func TestMiddlewareOutsideAuth(t *testing.T) {
+	var (
+		tr     trace
+		router = NewRouter(testServelet(t), "/").WithAuthenticator(fakeAuthenticator{})
+	)
+
+	router.Use(tr.middleware("mw"))
+	router.Add(testRoute("user", http.MethodGet, tr.handle("user"), &RouteAuth{Audience: testAudience}), "/user")
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		tr = nil
+
+		rec := serveRouter(router, http.MethodGet, "/user", "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+
+		// Middleware still runs for rejected requests
+		expectTrace(t, tr, "mw:user")
+	})
+
+	t.Run("authenticated", func(t *testing.T) {
+		tr = nil
+
+		rec := serveRouter(router, http.MethodGet, "/user", "test:alice")
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		expectTrace(t, tr, "mw:user", "user")
+	})
+}
+
+func TestMaxBodySize(t *testing.T) {
+	router := NewRouter(testServelet(t), "/")
+	router.Use(MaxBodySize(8))
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+
+	router.Add(testRoute("body", http.MethodPost, handle, nil), "/body")
+
+	tests := []struct {
+		name         string
+		body         string
+		expectStatus int
+	}{
+		{name: "within limit", body: "12345678", expectStatus: http.StatusOK},
+		{name: "over limit", body: "123456789", expectStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				req = httptest.NewRequest(http.MethodPost, "/body", strings.NewReader(test.body))
+				rec = httptest.NewRecorder()
+			)
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectStatus {
+				t.Errorf("expected status %d, got %d", test.expectStatus, rec.Code)
+			}
+		})
+	}
+}
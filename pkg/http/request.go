@@ -0,0 +1,88 @@
+package httpsvc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+)
+
+// HeaderRequestID TODO
+const HeaderRequestID = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestID TODO
+func RequestID(ctx context.Context) string {
+	res, _ := ctx.Value(requestIDKey{}).(string)
+	return res
+}
+
+// NOTE: Client supplied ids are echoed into headers, logs and error bodies,
+// so only accept a conservative character set
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := r.Header.Get(HeaderRequestID)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	w.Header().Set(HeaderRequestID, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+}
+
+// RequestLogger TODO
+func (s *Servelet) RequestLogger(r *http.Request) *zap.Logger {
+	return s.Logger.With(zap.String("requestID", RequestID(r.Context())))
+}
+
+func (s *Servelet) wrapRecover(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		defer func() {
+			v := recover()
+
+			switch {
+			case v == nil:
+				return
+			case v == http.ErrAbortHandler:
+				// Deliberate abort => let net/http handle it
+				panic(v)
+			}
+
+			s.RequestLogger(r).Error("recovered from panic", zap.Any("panic", v), zap.Stack("stack"))
+			s.HandleErr(w, r, InternalError(fmt.Errorf("panic: %v", v), "unexpected failure"))
+		}()
+
+		handle(w, r, params)
+	}
+}
@@ -0,0 +1,120 @@
+package httpsvc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var generatedRequestID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func TestRecoverPanic(t *testing.T) {
+	const panicText = "nil map write in handler"
+
+	router := NewRouter(testServelet(t), "/")
+	router.Add(testRoute("panic", http.MethodGet, func(http.ResponseWriter, *http.Request, httprouter.Params) { panic(panicText) }, nil), "/panic")
+	router.Add(testRoute("abort", http.MethodGet, func(http.ResponseWriter, *http.Request, httprouter.Params) { panic(http.ErrAbortHandler) }, nil), "/abort")
+
+	t.Run("panic", func(t *testing.T) {
+		rec := serveRouter(router, http.MethodGet, "/panic", "")
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+		}
+
+		if strings.Contains(rec.Body.String(), panicText) {
+			t.Errorf("panic value leaked in body: %s", rec.Body.String())
+		}
+
+		body := decodeErrBody(t, rec)
+
+		if body["code"] != CodeInternal {
+			t.Errorf("expected code '%s', got '%v'", CodeInternal, body["code"])
+		}
+
+		if id := rec.Header().Get(HeaderRequestID); id == "" || body["requestID"] != id {
+			t.Errorf("expected requestID '%s', got '%v'", id, body["requestID"])
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("expected abort panic to propagate, got: %v", v)
+			}
+		}()
+
+		serveRouter(router, http.MethodGet, "/abort", "")
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	var (
+		router   = NewRouter(testServelet(t), "/")
+		seen     string
+		handleID = func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) { seen = RequestID(r.Context()) }
+	)
+
+	router.Add(testRoute("id", http.MethodGet, handleID, nil), "/id")
+
+	tests := []struct {
+		name     string
+		clientID string
+		echoed   bool
+	}{
+		{name: "none", clientID: "", echoed: false},
+		{name: "valid", clientID: "client-id_1.2:3", echoed: true},
+		{name: "max length", clientID: strings.Repeat("a", maxRequestIDLen), echoed: true},
+		{name: "too long", clientID: strings.Repeat("a", maxRequestIDLen+1), echoed: false},
+		{name: "invalid characters", clientID: "id\r\nSet-Cookie: x", echoed: false},
+		{name: "space", clientID: "client id", echoed: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seen = ""
+
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			if test.clientID != "" {
+				req.Header.Set(HeaderRequestID, test.clientID)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(HeaderRequestID)
+
+			switch {
+			case test.echoed && id != test.clientID:
+				t.Errorf("expected client id '%s' echoed, got '%s'", test.clientID, id)
+			case !test.echoed && !generatedRequestID.MatchString(id):
+				t.Errorf("expected generated id, got '%s'", id)
+			}
+
+			if seen != id {
+				t.Errorf("expected handler to see id '%s', got '%s'", id, seen)
+			}
+		})
+	}
+
+	t.Run("unique", func(t *testing.T) {
+		first := serveRouter(router, http.MethodGet, "/id", "").Header().Get(HeaderRequestID)
+		second := serveRouter(router, http.MethodGet, "/id", "").Header().Get(HeaderRequestID)
+
+		if first == second {
+			t.Errorf("expected distinct generated ids, got '%s' twice", first)
+		}
+	})
+
+	t.Run("unrouted", func(t *testing.T) {
+		rec := serveRouter(router, http.MethodGet, "/missing", "")
+
+		if !generatedRequestID.MatchString(rec.Header().Get(HeaderRequestID)) {
+			t.Errorf("expected generated id on unrouted request, got '%s'", rec.Header().Get(HeaderRequestID))
+		}
+	})
+}
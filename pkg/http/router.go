@@ -58,11 +58,15 @@ func (ri RouteInfo) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 
 type routeData struct {
 	RouteInfo
-	Path string `json:"path"`
+	Path string     `json:"path"`
+	Auth *RouteAuth `json:"auth,omitempty"`
 }
 
 func (rd routeData) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("path", rd.Path)
+	if rd.Auth != nil {
+		enc.AddObject("auth", rd.Auth)
+	}
 	return rd.RouteInfo.MarshalLogObject(enc)
 }
 
@@ -70,6 +74,9 @@ func (rd routeData) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 type Route struct {
 	RouteInfo
 	Handle httprouter.Handle
+
+	// Optional, nil means no authentication
+	Auth *RouteAuth
 }
 
 // Router TODO
@@ -77,6 +84,10 @@ type Router struct {
 	prefix   string
 	servelet *Servelet
 	router   *httprouter.Router
+	authn    Authenticator
+
+	parent     *Router
+	middleware []Middleware
 
 	dataMap map[string]routeData
 }
@@ -97,6 +108,8 @@ func (rtr *Router) Child(format string, a ...interface{}) *Router {
 		prefix:   path.Join(rtr.prefix, formatPath(format, a)),
 		servelet: rtr.servelet,
 		router:   rtr.router,
+		authn:    rtr.authn,
+		parent:   rtr,
 		dataMap:  rtr.dataMap,
 	}
 }
@@ -110,18 +123,33 @@ func (rtr *Router) Add(route Route, format string, a ...interface{}) {
 	rtr.dataMap[route.Name] = routeData{
 		RouteInfo: route.RouteInfo,
 		Path:      pathStr,
+		Auth:      route.Auth,
+	}
+
+	// Wrap handler for authentication
+	handle := route.Handle
+	if route.Auth != nil {
+		handle = rtr.wrapAuth(*route.Auth, handle)
 	}
 
+	// Wrap handler with middleware, outside of authentication
+	handle = rtr.wrapMiddleware(route, handle)
+
+	// Wrap handler for panic recovery
+	handle = rtr.servelet.wrapRecover(handle)
+
 	// Wrap handler for metric emission
 	routeEmitter := rtr.servelet.Emitter.Clone(statsd.Tags("route", route.MetricTag))
-	metricHandle := WrapMetrics(routeEmitter, route.Handle)
+	metricHandle := WrapMetrics(routeEmitter, handle)
 
 	// Add to httprouter
 	rtr.router.Handle(route.Method, pathStr, metricHandle)
 }
 
 // ServeHTTP TODO
-func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) { rtr.router.ServeHTTP(w, r) }
+func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rtr.router.ServeHTTP(w, withRequestID(w, r))
+}
 
 // MarshalLogArray TODO
 func (rtr Router) MarshalLogArray(enc zapcore.ArrayEncoder) error {
@@ -2,24 +2,84 @@ package secret
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/goware/urlx"
 	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/oligarch316/go-auth-service/pkg/http"
 	"go.uber.org/zap/zapcore"
 )
 
-const defaultURLScheme = "http"
+const (
+	defaultURLScheme    = "http"
+	defaultTLSURLScheme = "https"
+)
+
+// ErrNotFound TODO.
+var ErrNotFound = errors.New("not found")
+
+// ConfigRetry TODO.
+type ConfigRetry struct {
+	MaxAttempts    int           `json:"maxAttempts"`
+	InitialBackoff time.Duration `json:"initialBackoff"`
+	MaxBackoff     time.Duration `json:"maxBackoff"`
+}
+
+// MarshalLogObject TODO.
+func (cr ConfigRetry) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("maxAttempts", cr.MaxAttempts)
+	enc.AddDuration("initialBackoff", cr.InitialBackoff)
+	enc.AddDuration("maxBackoff", cr.MaxBackoff)
+	return nil
+}
+
+// NOTE: The package level source is unseeded before Go 1.20, every process
+// would share one jitter sequence. A rand.Rand is not safe for concurrent use.
+var jitter = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func jitterInt63n(n int64) int64 {
+	jitter.Lock()
+	defer jitter.Unlock()
+
+	return jitter.Int63n(n)
+}
+
+// NOTE: "Equal jitter", half the backoff is fixed and half is random
+func (cr ConfigRetry) wait(ctx context.Context, backoff time.Duration) error {
+	if half := int64(backoff / 2); half > 0 {
+		backoff = time.Duration(half + jitterInt63n(half))
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
 // ConfigClient TODO.
 type ConfigClient struct {
-	Address string        `json:"address"`
-	Timeout time.Duration `json:"timeout"`
+	Address string                  `json:"address"`
+	Timeout time.Duration           `json:"timeout"`
+	TLS     httpsvc.ConfigClientTLS `json:"tls"`
+	Retry   ConfigRetry             `json:"retry"`
 }
 
 // DefaultClientConfig TODO.
@@ -27,6 +87,11 @@ func DefaultClientConfig() ConfigClient {
 	return ConfigClient{
 		Address: DefaultAddress,
 		Timeout: 10 * time.Second,
+		Retry: ConfigRetry{
+			MaxAttempts:    3,
+			InitialBackoff: 100 * time.Millisecond,
+			MaxBackoff:     2 * time.Second,
+		},
 	}
 }
 
@@ -34,32 +99,77 @@ func DefaultClientConfig() ConfigClient {
 func (cc ConfigClient) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("address", cc.Address)
 	enc.AddDuration("timeout", cc.Timeout)
-	return nil
+	enc.AddObject("tls", cc.TLS)
+	return enc.AddObject("retry", cc.Retry)
 }
 
 // Client TODO.
 type Client struct {
 	client         *http.Client
+	retry          ConfigRetry
 	urlSet, urlKey string
 }
 
 // NewClient TODO.
 func NewClient(cfg ConfigClient) (*Client, error) {
-	base, err := urlx.ParseWithDefaultScheme(cfg.Address, defaultURLScheme)
+	tlsConfig, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tls configuration: %w", err)
+	}
+
+	scheme := defaultURLScheme
+	if tlsConfig != nil {
+		scheme = defaultTLSURLScheme
+	}
+
+	base, err := urlx.ParseWithDefaultScheme(cfg.Address, scheme)
 	if err != nil {
 		return nil, fmt.Errorf("invalid address: %w", err)
 	}
 
+	if tlsConfig != nil && base.Scheme != defaultTLSURLScheme {
+		return nil, fmt.Errorf("tls enabled for non-https address '%s'", cfg.Address)
+	}
+
 	base.Path = urlPathJoin(APIVersion, pathBase)
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
 	return &Client{
-		client: &http.Client{Timeout: cfg.Timeout},
+		client: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		retry:  cfg.Retry,
 		urlSet: urlPathJoin(base.String(), pathSet),
 		urlKey: urlPathJoin(base.String(), pathKey),
 	}, nil
 }
 
 func (c *Client) do(ctx context.Context, urlStr string) ([]byte, error) {
+	backoff := c.retry.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		res, err := c.attempt(ctx, urlStr)
+
+		var tErr transientError
+		if err == nil || !errors.As(err, &tErr) || attempt >= c.retry.MaxAttempts {
+			return res, err
+		}
+
+		if c.retry.wait(ctx, backoff) != nil {
+			return nil, err
+		}
+
+		if backoff *= 2; backoff > c.retry.MaxBackoff {
+			backoff = c.retry.MaxBackoff
+		}
+	}
+}
+
+type transientError struct{ error }
+
+func (te transientError) Unwrap() error { return te.error }
+
+func (c *Client) attempt(ctx context.Context, urlStr string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -69,18 +179,44 @@ func (c *Client) do(ctx context.Context, urlStr string) ([]byte, error) {
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %w", err)
+		err = fmt.Errorf("failed to perform request: %w", err)
+
+		// Caller gave up, or the server failed TLS verification => don't retry
+		var uErr *url.Error
+		if ctx.Err() != nil || (errors.As(err, &uErr) && isTLSVerifyErr(uErr.Err)) {
+			return nil, err
+		}
+
+		return nil, transientError{err}
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= http.StatusInternalServerError:
+		return nil, transientError{fmt.Errorf("non-200 response code: %d", resp.StatusCode)}
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("non-200 response code: %d: %w", resp.StatusCode, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
 		return nil, fmt.Errorf("non-200 response code: %d", resp.StatusCode)
 	}
 
 	return ioutil.ReadAll(resp.Body)
 }
 
+func isTLSVerifyErr(err error) bool {
+	var (
+		hostErr    x509.HostnameError
+		authErr    x509.UnknownAuthorityError
+		invalidErr x509.CertificateInvalidError
+	)
+
+	return errors.As(err, &hostErr) ||
+		errors.As(err, &authErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.Is(err, httpsvc.ErrPinnedKeyMismatch)
+}
+
 // Set TODO.
 func (c *Client) Set(ctx context.Context) (*jwk.Set, error) {
 	respBytes, err := c.do(ctx, c.urlSet)
@@ -1,30 +1,49 @@
 package command
 
 import (
+	"time"
+
+	"github.com/oligarch316/go-auth-service/internal/pkg/claims"
+	"github.com/oligarch316/go-auth-service/pkg/http"
 	httpsecret "github.com/oligarch316/go-auth-service/pkg/http/secret"
 	"github.com/oligarch316/go-auth-service/pkg/secret"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
 	"github.com/oligarch316/go-skeleton/pkg/observ"
 	"github.com/spf13/cobra"
 )
 
 // Config TODO.
 type Config struct {
-	Keys []secret.Config `json:"keys"`
+	Discovery      httpsecret.ConfigDiscovery `json:"discovery"`
+	Keys           secret.ConfigKeyring       `json:"keys"`
+	ReloadInterval ctype.Duration             `json:"reloadInterval"`
 }
 
 // DefaultConfig TODO.
-func DefaultConfig() Config { return Config{} }
+func DefaultConfig() Config {
+	discovery := httpsecret.DefaultDiscoveryConfig()
+	discovery.Issuer = claims.DefaultIssuerName
+
+	return Config{
+		Discovery:      discovery,
+		ReloadInterval: ctype.Duration{Duration: 30 * time.Second},
+	}
+}
 
 type cmdConfig struct {
-	Address   string        `json:"address"`
-	Observ    observ.Config `json:"observ"`
-	SecretSvc Config        `json:"secretsvc"`
+	Address   string               `json:"address"`
+	HTTP      httpsvc.ConfigServer `json:"http"`
+	TLS       httpsvc.ConfigTLS    `json:"tls"`
+	Observ    observ.Config        `json:"observ"`
+	SecretSvc Config               `json:"secretsvc"`
 }
 
 func defaultCmdConfig() cmdConfig {
 	return cmdConfig{
 		Address:   httpsecret.DefaultAddress,
+		HTTP:      httpsvc.DefaultServerConfig(),
+		TLS:       httpsvc.DefaultTLSConfig(),
 		Observ:    observ.DefaultConfig(),
 		SecretSvc: DefaultConfig(),
 	}
@@ -0,0 +1,94 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	httpsecret "github.com/oligarch316/go-auth-service/pkg/http/secret"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+)
+
+// WatchKeySet TODO.
+//
+// On SIGHUP, reloads the configuration via loadConfig and rebuilds the key
+// set from it. Also reloads whenever a file sourced key changes on disk as
+// observed by polling at the given interval (zero disables polling). A failed
+// reload keeps the current key set.
+func WatchKeySet(set *httpsecret.KeySet, loadConfig func() (Config, error), interval time.Duration, corelet *observ.Corelet) (stop func()) {
+	var (
+		done   = make(chan struct{})
+		hup    = make(chan os.Signal, 1)
+		poll   <-chan time.Time
+		ticker *time.Ticker
+	)
+
+	signal.Notify(hup, syscall.SIGHUP)
+
+	// NOTE: Poll even without file sourced keys, a rebuild may introduce some
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		poll = ticker.C
+	}
+
+	reload := func(trigger string, load func() (*jwk.Set, error)) {
+		current, err := load()
+		if err != nil {
+			corelet.Logger.Error("failed to reload key set", zap.String("trigger", trigger), zap.Error(err))
+			corelet.Emitter.Count("reload_failures", 1)
+			return
+		}
+
+		var keyIDs []string
+		for _, key := range current.Keys {
+			keyIDs = append(keyIDs, key.KeyID())
+		}
+
+		corelet.Logger.Info("reloaded key set", zap.String("trigger", trigger), zap.Strings("keyIDs", keyIDs))
+		corelet.Emitter.Count("reloads", 1)
+		corelet.Emitter.Gauge("keys", len(keyIDs))
+	}
+
+	// NOTE: Stamp before returning, changes made right after must be observed
+	stamp := set.Stamp()
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				stamp = set.Stamp()
+				reload("signal", func() (*jwk.Set, error) { return rebuild(set, loadConfig) })
+			case <-poll:
+				if next := set.Stamp(); next != stamp {
+					stamp = next
+					reload("file", set.Reload)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(hup)
+
+		if ticker != nil {
+			ticker.Stop()
+		}
+
+		close(done)
+	}
+}
+
+func rebuild(set *httpsecret.KeySet, loadConfig func() (Config, error)) (*jwk.Set, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return set.Rebuild(cfg.Keys)
+}
@@ -0,0 +1,153 @@
+package command
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/knq/pemutil"
+	"github.com/lestrrat-go/jwx/jwa"
+	httpsecret "github.com/oligarch316/go-auth-service/pkg/http/secret"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+	statsd "gopkg.in/alexcesaro/statsd.v2"
+)
+
+const testWatchTimeout = 5 * time.Second
+
+func testCorelet(t *testing.T) *observ.Corelet {
+	t.Helper()
+
+	emitter, err := statsd.New(statsd.Mute(true))
+	if err != nil {
+		t.Fatalf("failed to create emitter: %s", err)
+	}
+
+	return &observ.Corelet{Logger: zap.NewNop(), Emitter: emitter}
+}
+
+func writeKeyFile(t *testing.T, filePath string) {
+	t.Helper()
+
+	store, err := pemutil.GenerateECKeySet(elliptic.P256())
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	if err := store.WriteFile(filePath); err != nil {
+		t.Fatalf("failed to write key file: %s", err)
+	}
+}
+
+func testKey(source secret.Source) secret.ConfigKey {
+	return secret.ConfigKey{
+		Config: secret.Config{Algorithm: jwa.ES256, Source: source},
+		State:  secret.KeyStateActive,
+	}
+}
+
+func firstKeyID(set *httpsecret.KeySet) string { return set.Load().Keys[0].KeyID() }
+
+// Wait for the first key of set to differ from prev
+func awaitKeyChange(t *testing.T, set *httpsecret.KeySet, prev string) {
+	t.Helper()
+
+	deadline := time.Now().Add(testWatchTimeout)
+
+	for firstKeyID(set) == prev {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for key '%s' to be replaced", prev)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchKeySetPoll(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "key.pem")
+	writeKeyFile(t, filePath)
+
+	set, err := httpsecret.NewKeySet(secret.ConfigKeyring{testKey(secret.File(filePath))})
+	if err != nil {
+		t.Fatalf("failed to create key set: %s", err)
+	}
+
+	noConfig := func() (Config, error) { return Config{}, errors.New("unexpected configuration load") }
+
+	stop := WatchKeySet(set, noConfig, 10*time.Millisecond, testCorelet(t))
+	defer stop()
+
+	prev := firstKeyID(set)
+	writeKeyFile(t, filePath)
+
+	// Ensure the stamp moves even on coarse file system timestamps
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filePath, later, later); err != nil {
+		t.Fatalf("failed to touch key file: %s", err)
+	}
+
+	awaitKeyChange(t, set, prev)
+}
+
+func TestWatchKeySetSignal(t *testing.T) {
+	set, err := httpsecret.NewKeySet(secret.ConfigKeyring{testKey(secret.Generate(jwa.EC))})
+	if err != nil {
+		t.Fatalf("failed to create key set: %s", err)
+	}
+
+	// NOTE: Unchanged generated keys are carried across rebuilds, so rotate in a file
+	filePath := filepath.Join(t.TempDir(), "key.pem")
+	writeKeyFile(t, filePath)
+
+	var (
+		loads    = make(chan struct{}, 2)
+		failNext = true
+		nextCfg  = Config{Keys: secret.ConfigKeyring{testKey(secret.File(filePath))}}
+	)
+
+	loadConfig := func() (Config, error) {
+		defer func() { loads <- struct{}{} }()
+
+		if failNext {
+			failNext = false
+			return Config{}, errors.New("invalid configuration")
+		}
+
+		return nextCfg, nil
+	}
+
+	stop := WatchKeySet(set, loadConfig, 0, testCorelet(t))
+	defer stop()
+
+	prev := firstKeyID(set)
+
+	hangup := func() {
+		t.Helper()
+
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatalf("failed to signal: %s", err)
+		}
+
+		select {
+		case <-loads:
+		case <-time.After(testWatchTimeout):
+			t.Fatal("timed out waiting for configuration load")
+		}
+	}
+
+	// Failed configuration load => current set kept
+	hangup()
+
+	if actual := firstKeyID(set); actual != prev {
+		t.Errorf("expected key '%s' kept after failed reload, got '%s'", prev, actual)
+	}
+
+	// Successful load => rebuilt from the new configuration
+	hangup()
+	awaitKeyChange(t, set, prev)
+}
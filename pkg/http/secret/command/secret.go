@@ -3,13 +3,11 @@ package command
 import (
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
-	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/oligarch316/go-auth-service/pkg/http"
 	httpsecret "github.com/oligarch316/go-auth-service/pkg/http/secret"
-	"github.com/oligarch316/go-auth-service/pkg/secret"
+	httptoken "github.com/oligarch316/go-auth-service/pkg/http/token"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
 	"github.com/oligarch316/go-skeleton/pkg/observ"
 	"github.com/spf13/cobra"
@@ -28,29 +26,19 @@ func New(name string, ns *namespace.NS) *cobra.Command {
 
 // NewServer TODO.
 func NewServer(cfg Config, srvlet *httpsvc.Servelet) (*httpsecret.Server, error) {
-	var pubKeys []jwk.Key
-
-	for _, item := range cfg.Keys {
-		pubKey, err := item.PublicKey()
-		if err != nil {
-			return nil, fmt.Errorf("failed to load public key: %w", err)
-		}
-
-		// Double check
-		if !secret.IsPublic(pubKey) {
-			return nil, fmt.Errorf("non-public key detected of type %s", pubKey.KeyType().String())
-		}
-
-		pubKeys = append(pubKeys, pubKey)
+	set, err := httpsecret.NewKeySet(cfg.Keys)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(pubKeys) < 1 {
+	if len(set.Load().Keys) < 1 {
 		return nil, fmt.Errorf("no configured public keys found")
 	}
 
 	return &httpsecret.Server{
-		Servelet: srvlet,
-		Set:      &jwk.Set{Keys: pubKeys},
+		Servelet:  srvlet,
+		Set:       set,
+		Discovery: cfg.Discovery,
 	}, nil
 }
 
@@ -83,29 +71,57 @@ func run(ns *namespace.NS) int {
 		router   = httpsvc.NewRouter(servelet, "/")
 	)
 
+	cfg.SecretSvc.Discovery.ResolveURLs(
+		cfg.TLS.Scheme(),
+		cfg.Address,
+		cfg.TLS.Scheme()+"://"+httptoken.DefaultAddress+httptoken.UserPath(),
+	)
+
 	server, err := NewServer(cfg.SecretSvc, servelet.Named("secret"))
 	if err != nil {
 		root.Logger.Error("failed to create server", zap.Error(err))
 		return 1
 	}
 
+	loadConfig := func() (Config, error) {
+		next := defaultCmdConfig()
+		_, err := ns.LoadAndRecord(&next)
+		return next.SecretSvc, err
+	}
+
+	stopWatch := WatchKeySet(server.Set, loadConfig, cfg.SecretSvc.ReloadInterval.Duration, observCore.Named("keyset"))
+	defer stopWatch()
+
 	server.AddRoutes(router)
 	router.AddMetaRoutes()
 
+	// ----- TLS
+	serverTLS, err := httpsvc.NewServerTLS(cfg.TLS, observCore.Named("tls"))
+	if err != nil {
+		root.Logger.Error("failed to create tls configuration", zap.Error(err))
+		return 1
+	}
+
+	defer serverTLS.Close()
+
+	if serverTLS == nil {
+		root.Logger.Warn("tls disabled, serving plaintext http")
+	}
+
 	// ----- Run
 	root.Logger.Info(
 		"starting server",
 		zap.String("address", cfg.Address),
+		zap.Object("http", cfg.HTTP),
+		zap.Object("tls", cfg.TLS),
 		zap.Array("routes", router),
 	)
 
-	// TODO: Signal handlers
-	// TODO: TLS only
-
-	if err := http.ListenAndServe(cfg.Address, router); err != http.ErrServerClosed {
+	if err := cfg.HTTP.ListenAndServe(cfg.Address, router, serverTLS.Config(), root.Logger); err != nil {
 		root.Logger.Error("server failure", zap.Error(err))
 		return 1
 	}
 
+	root.Logger.Info("server stopped")
 	return 0
 }
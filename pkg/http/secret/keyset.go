@@ -0,0 +1,215 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+)
+
+type keySetEntry struct {
+	secret.ConfigKey
+	key jwk.Key
+}
+
+func (kse keySetEntry) path() (string, bool) {
+	switch t := kse.Source.(type) {
+	case secret.File:
+		return string(t), true
+	case *secret.File:
+		return string(*t), true
+	}
+	return "", false
+}
+
+func (kse keySetEntry) load() (jwk.Key, error) {
+	key, err := kse.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Double check
+	if !secret.IsPublic(key) {
+		return nil, fmt.Errorf("non-public key detected of type %s", key.KeyType().String())
+	}
+
+	return key, nil
+}
+
+func (kse keySetEntry) carry(prev []keySetEntry) (jwk.Key, bool) {
+	if _, ok := kse.path(); ok {
+		return nil, false
+	}
+
+	cfgBytes, err := json.Marshal(kse.Config)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, item := range prev {
+		if prevBytes, err := json.Marshal(item.Config); err == nil && bytes.Equal(cfgBytes, prevBytes) {
+			return item.key, true
+		}
+	}
+
+	return nil, false
+}
+
+// KeySet TODO
+type KeySet struct {
+	current atomic.Value
+
+	mu      sync.Mutex
+	entries []keySetEntry
+}
+
+// NewKeySet TODO
+func NewKeySet(cfg secret.ConfigKeyring) (*KeySet, error) {
+	entries, err := newKeySetEntries(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &KeySet{entries: entries}
+	res.store()
+	return res, nil
+}
+
+// NOTE: Keys not sourced from files are carried over from prev when their
+// configuration is unchanged, rebuilding would silently replace generated keys
+func newKeySetEntries(cfg secret.ConfigKeyring, prev []keySetEntry) ([]keySetEntry, error) {
+	var res []keySetEntry
+
+	for _, item := range cfg {
+		if item.State == secret.KeyStateRetired {
+			continue
+		}
+
+		entry := keySetEntry{ConfigKey: item}
+
+		if key, ok := entry.carry(prev); ok {
+			entry.key = key
+			res = append(res, entry)
+			continue
+		}
+
+		key, err := entry.load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key: %w", err)
+		}
+
+		entry.key = key
+		res = append(res, entry)
+	}
+
+	return res, nil
+}
+
+func (ks *KeySet) store() {
+	set := &jwk.Set{Keys: make([]jwk.Key, len(ks.entries))}
+	for i, entry := range ks.entries {
+		set.Keys[i] = entry.key
+	}
+
+	ks.current.Store(set)
+}
+
+// Load TODO
+func (ks *KeySet) Load() *jwk.Set { return ks.current.Load().(*jwk.Set) }
+
+// Files TODO
+func (ks *KeySet) Files() []string {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	var res []string
+
+	for _, entry := range ks.entries {
+		if path, ok := entry.path(); ok {
+			res = append(res, path)
+		}
+	}
+
+	return res
+}
+
+// Stamp TODO
+func (ks *KeySet) Stamp() string {
+	var items []string
+
+	for _, path := range ks.Files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			items = append(items, path+":"+err.Error())
+			continue
+		}
+
+		items = append(items, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+	}
+
+	sort.Strings(items)
+	return strings.Join(items, "|")
+}
+
+// Reload TODO
+//
+// Re-reads every file sourced key. Keys from other sources are kept as is,
+// reloading a generated key would silently replace it. Either every file
+// loads and the new set is swapped in, or the current set is left untouched.
+func (ks *KeySet) Reload() (*jwk.Set, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	entries := make([]keySetEntry, len(ks.entries))
+	copy(entries, ks.entries)
+
+	for i, entry := range entries {
+		path, ok := entry.path()
+		if !ok {
+			continue
+		}
+
+		key, err := entry.load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key from '%s': %w", path, err)
+		}
+
+		entries[i].key = key
+	}
+
+	ks.entries = entries
+	ks.store()
+
+	return ks.Load(), nil
+}
+
+// Rebuild TODO
+//
+// Replaces the set with one built from cfg, as after a configuration reload.
+// On any failure the current set is left untouched.
+func (ks *KeySet) Rebuild(cfg secret.ConfigKeyring) (*jwk.Set, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	entries, err := newKeySetEntries(cfg, ks.entries)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) < 1 {
+		return nil, errors.New("no configured public keys found")
+	}
+
+	ks.entries = entries
+	ks.store()
+
+	return ks.Load(), nil
+}
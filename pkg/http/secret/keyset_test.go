@@ -0,0 +1,347 @@
+package secret
+
+import (
+	"crypto/elliptic"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/knq/pemutil"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+	statsd "gopkg.in/alexcesaro/statsd.v2"
+)
+
+func testServelet(t *testing.T) *httpsvc.Servelet {
+	t.Helper()
+
+	emitter, err := statsd.New(statsd.Mute(true))
+	if err != nil {
+		t.Fatalf("failed to create emitter: %s", err)
+	}
+
+	return &httpsvc.Servelet{Corelet: &observ.Corelet{Logger: zap.NewNop(), Emitter: emitter}}
+}
+
+// Serve the given key set, as the secret service would
+func newTestRouter(t *testing.T, set *KeySet, discovery ConfigDiscovery) *httpsvc.Router {
+	t.Helper()
+
+	var (
+		servelet = testServelet(t)
+		router   = httpsvc.NewRouter(servelet, "/")
+	)
+
+	(&Server{Servelet: servelet, Set: set, Discovery: discovery}).AddRoutes(router)
+	return router
+}
+
+func serve(router *httpsvc.Router, urlPath string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// Write a freshly generated key pair to filePath
+func writeKeyFile(t *testing.T, filePath string) {
+	t.Helper()
+
+	store, err := pemutil.GenerateECKeySet(elliptic.P256())
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	if err := store.WriteFile(filePath); err != nil {
+		t.Fatalf("failed to write key file: %s", err)
+	}
+}
+
+func fileKey(filePath string, state secret.KeyState) secret.ConfigKey {
+	return secret.ConfigKey{
+		Config: secret.Config{Algorithm: jwa.ES256, Source: secret.File(filePath)},
+		State:  state,
+	}
+}
+
+func generatedKey(state secret.KeyState) secret.ConfigKey {
+	return secret.ConfigKey{
+		Config: secret.Config{Algorithm: jwa.ES256, Source: secret.Generate(jwa.EC)},
+		State:  state,
+	}
+}
+
+func newTestKeySet(t *testing.T, cfg secret.ConfigKeyring) *KeySet {
+	t.Helper()
+
+	res, err := NewKeySet(cfg)
+	if err != nil {
+		t.Fatalf("failed to create key set: %s", err)
+	}
+
+	return res
+}
+
+func keyIDs(set *KeySet) []string {
+	var res []string
+	for _, key := range set.Load().Keys {
+		res = append(res, key.KeyID())
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+func listKeyIDs(t *testing.T, router *httpsvc.Router) []string {
+	t.Helper()
+
+	rec := serve(router, path.Join("/", APIVersion, pathBase, pathKey), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp keyListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response body: %s", err)
+	}
+
+	sort.Strings(resp.KeyIDs)
+	return resp.KeyIDs
+}
+
+func keyReadStatus(router *httpsvc.Router, keyID string) int {
+	return serve(router, path.Join("/", APIVersion, pathBase, pathKey, keyID), nil).Code
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestKeySetStates(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"active.pem", "verify.pem", "retired.pem"} {
+		writeKeyFile(t, filepath.Join(dir, name))
+	}
+
+	var (
+		full = newTestKeySet(t, secret.ConfigKeyring{
+			fileKey(filepath.Join(dir, "active.pem"), secret.KeyStateActive),
+			fileKey(filepath.Join(dir, "verify.pem"), secret.KeyStateVerify),
+			fileKey(filepath.Join(dir, "retired.pem"), secret.KeyStateActive),
+		})
+
+		set = newTestKeySet(t, secret.ConfigKeyring{
+			fileKey(filepath.Join(dir, "active.pem"), secret.KeyStateActive),
+			fileKey(filepath.Join(dir, "verify.pem"), secret.KeyStateVerify),
+			fileKey(filepath.Join(dir, "retired.pem"), secret.KeyStateRetired),
+		})
+
+		router = newTestRouter(t, set, DefaultDiscoveryConfig())
+	)
+
+	if len(full.Load().Keys) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(full.Load().Keys))
+	}
+
+	var (
+		activeID  = full.Load().Keys[0].KeyID()
+		verifyID  = full.Load().Keys[1].KeyID()
+		retiredID = full.Load().Keys[2].KeyID()
+	)
+
+	if actual := listKeyIDs(t, router); !equalStrings(actual, keyIDs(set)) || len(actual) != 2 {
+		t.Errorf("expected active and verify key ids, got %v", actual)
+	}
+
+	tests := []struct {
+		name         string
+		keyID        string
+		expectStatus int
+	}{
+		{name: "active", keyID: activeID, expectStatus: http.StatusOK},
+		{name: "verify", keyID: verifyID, expectStatus: http.StatusOK},
+		{name: "retired", keyID: retiredID, expectStatus: http.StatusNotFound},
+		{name: "unknown", keyID: "unknown", expectStatus: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := keyReadStatus(router, test.keyID); actual != test.expectStatus {
+				t.Errorf("expected status %d, got %d", test.expectStatus, actual)
+			}
+		})
+	}
+}
+
+func TestKeySetReload(t *testing.T) {
+	t.Run("replaced file", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "key.pem")
+		writeKeyFile(t, filePath)
+
+		var (
+			set    = newTestKeySet(t, secret.ConfigKeyring{fileKey(filePath, secret.KeyStateActive), generatedKey(secret.KeyStateVerify)})
+			router = newTestRouter(t, set, DefaultDiscoveryConfig())
+
+			prevFileID      = set.Load().Keys[0].KeyID()
+			prevGeneratedID = set.Load().Keys[1].KeyID()
+		)
+
+		writeKeyFile(t, filePath)
+
+		if _, err := set.Reload(); err != nil {
+			t.Fatalf("failed to reload: %s", err)
+		}
+
+		nextFileID := set.Load().Keys[0].KeyID()
+
+		if nextFileID == prevFileID {
+			t.Fatal("expected file key to be replaced")
+		}
+
+		if actual := set.Load().Keys[1].KeyID(); actual != prevGeneratedID {
+			t.Errorf("expected generated key '%s' kept, got '%s'", prevGeneratedID, actual)
+		}
+
+		// Routes serve the reloaded set without re-registration
+		if actual := keyReadStatus(router, prevFileID); actual != http.StatusNotFound {
+			t.Errorf("expected status %d for replaced key, got %d", http.StatusNotFound, actual)
+		}
+
+		if actual := keyReadStatus(router, nextFileID); actual != http.StatusOK {
+			t.Errorf("expected status %d for reloaded key, got %d", http.StatusOK, actual)
+		}
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		var (
+			dir       = t.TempDir()
+			goodPath  = filepath.Join(dir, "good.pem")
+			otherPath = filepath.Join(dir, "other.pem")
+		)
+
+		writeKeyFile(t, goodPath)
+		writeKeyFile(t, otherPath)
+
+		set := newTestKeySet(t, secret.ConfigKeyring{fileKey(goodPath, secret.KeyStateActive), fileKey(otherPath, secret.KeyStateVerify)})
+		prev := keyIDs(set)
+
+		// Replace one file validly, corrupt the other => all or nothing
+		writeKeyFile(t, goodPath)
+
+		if err := ioutil.WriteFile(otherPath, []byte("not a pem file"), 0600); err != nil {
+			t.Fatalf("failed to corrupt key file: %s", err)
+		}
+
+		if _, err := set.Reload(); err == nil {
+			t.Error("expected error for corrupt key file")
+		}
+
+		if actual := keyIDs(set); !equalStrings(actual, prev) {
+			t.Errorf("expected key set %v kept, got %v", prev, actual)
+		}
+	})
+
+	t.Run("stamp", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "key.pem")
+		writeKeyFile(t, filePath)
+
+		var (
+			set  = newTestKeySet(t, secret.ConfigKeyring{fileKey(filePath, secret.KeyStateActive)})
+			prev = set.Stamp()
+		)
+
+		if actual := set.Files(); len(actual) != 1 || actual[0] != filePath {
+			t.Errorf("expected files [%s], got %v", filePath, actual)
+		}
+
+		if err := ioutil.WriteFile(filePath, []byte("changed"), 0600); err != nil {
+			t.Fatalf("failed to change key file: %s", err)
+		}
+
+		if set.Stamp() == prev {
+			t.Error("expected stamp to change along with key file")
+		}
+	})
+}
+
+func TestKeySetRebuild(t *testing.T) {
+	dir := t.TempDir()
+	writeKeyFile(t, filepath.Join(dir, "a.pem"))
+	writeKeyFile(t, filepath.Join(dir, "b.pem"))
+
+	var (
+		generated = generatedKey(secret.KeyStateActive)
+		keyA      = fileKey(filepath.Join(dir, "a.pem"), secret.KeyStateVerify)
+		keyB      = fileKey(filepath.Join(dir, "b.pem"), secret.KeyStateVerify)
+	)
+
+	t.Run("carries unchanged keys", func(t *testing.T) {
+		set := newTestKeySet(t, secret.ConfigKeyring{generated, keyA})
+		generatedID := set.Load().Keys[0].KeyID()
+
+		if _, err := set.Rebuild(secret.ConfigKeyring{generated, keyB}); err != nil {
+			t.Fatalf("failed to rebuild: %s", err)
+		}
+
+		keys := set.Load().Keys
+
+		if len(keys) != 2 {
+			t.Fatalf("expected 2 keys, got %d", len(keys))
+		}
+
+		if keys[0].KeyID() != generatedID {
+			t.Errorf("expected generated key '%s' carried, got '%s'", generatedID, keys[0].KeyID())
+		}
+
+		if set.Files()[0] != filepath.Join(dir, "b.pem") {
+			t.Errorf("expected file key b, got %v", set.Files())
+		}
+	})
+
+	tests := []struct {
+		name string
+		cfg  secret.ConfigKeyring
+	}{
+		{name: "no keys", cfg: secret.ConfigKeyring{}},
+		{name: "only retired keys", cfg: secret.ConfigKeyring{fileKey(filepath.Join(dir, "a.pem"), secret.KeyStateRetired)}},
+		{name: "missing file", cfg: secret.ConfigKeyring{fileKey(filepath.Join(dir, "missing.pem"), secret.KeyStateActive)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set := newTestKeySet(t, secret.ConfigKeyring{generated, keyA})
+			prev := keyIDs(set)
+
+			if _, err := set.Rebuild(test.cfg); err == nil {
+				t.Error("expected error")
+			}
+
+			if actual := keyIDs(set); !equalStrings(actual, prev) {
+				t.Errorf("expected key set %v kept, got %v", prev, actual)
+			}
+		})
+	}
+}
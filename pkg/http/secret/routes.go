@@ -19,6 +19,16 @@ const (
 	paramKeyID = "keyID"
 )
 
+const (
+	pathWellKnown = "/.well-known"
+
+	pathWellKnownJWKS      = pathWellKnown + "/jwks.json"
+	pathWellKnownDiscovery = pathWellKnown + "/openid-configuration"
+)
+
+// JWKSPath TODO
+func JWKSPath() string { return pathWellKnownJWKS }
+
 // AddRoutes TODO
 func (s *Server) AddRoutes(r *httpsvc.Router) {
 	child := r.Child("/%s/%s", APIVersion, pathBase)
@@ -26,4 +36,7 @@ func (s *Server) AddRoutes(r *httpsvc.Router) {
 	child.Add(s.HandleSetRead(), pathSet)
 	child.Add(s.HandleKeyList(), pathKey)
 	child.Add(s.HandleKeyRead(paramKeyID), "/%s/%P", pathKey, paramKeyID)
+
+	r.Add(s.HandleWellKnownJWKS(), pathWellKnownJWKS)
+	r.Add(s.HandleWellKnownDiscovery(), pathWellKnownDiscovery)
 }
@@ -7,14 +7,14 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/knq/pemutil"
-	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/oligarch316/go-auth-service/pkg/http"
 )
 
 // Server TODO
 type Server struct {
-	Servelet *httpsvc.Servelet
-	Set      *jwk.Set
+	Servelet  *httpsvc.Servelet
+	Set       *KeySet
+	Discovery ConfigDiscovery
 }
 
 type keyListResponse struct {
@@ -31,7 +31,7 @@ func (s *Server) HandleSetRead() httpsvc.Route {
 	}
 
 	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		bytes, err := json.Marshal(s.Set)
+		bytes, err := json.Marshal(s.Set.Load())
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
 			return
@@ -53,13 +53,13 @@ func (s *Server) HandleKeyList() httpsvc.Route {
 		MetricTag:   "secret_key_list",
 	}
 
-	var resp keyListResponse
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var resp keyListResponse
 
-	for _, key := range s.Set.Keys {
-		resp.KeyIDs = append(resp.KeyIDs, key.KeyID())
-	}
+		for _, key := range s.Set.Load().Keys {
+			resp.KeyIDs = append(resp.KeyIDs, key.KeyID())
+		}
 
-	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		bytes, err := json.Marshal(resp)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
@@ -95,7 +95,7 @@ func (s *Server) HandleKeyRead(kidParamName string) httpsvc.Route {
 			return
 		}
 
-		keys := s.Set.LookupKeyID(kid)
+		keys := s.Set.Load().LookupKeyID(kid)
 		if len(keys) < 1 {
 			err := fmt.Errorf("no such key '%s'", kid)
 			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusNotFound, err, "failed to load key"))
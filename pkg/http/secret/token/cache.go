@@ -7,19 +7,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/karlseguin/ccache/v2"
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/oligarch316/go-auth-service/pkg/http/secret"
 	"github.com/oligarch316/go-auth-service/pkg/secret/token"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
-	compactSep = '.'
-	defaultTTL = 24 * time.Hour
+	compactSep         = '.'
+	defaultTTL         = 24 * time.Hour
+	defaultNegativeTTL = 30 * time.Second
 )
 
+// ErrFetchLimited TODO.
+var ErrFetchLimited = errors.New("secret service fetch rate limit exceeded")
+
 type cache struct {
 	*ccache.Configuration
 	*ccache.Cache
@@ -41,11 +47,24 @@ func (c *client) init() (err error) {
 
 // Cache TODO.
 type Cache struct {
-	cache  cache
-	client client
+	cache, negative cache
+	client          client
+
+	flight     singleflight.Group
+	limiter    *limiter
+	fetchRate  float64
+	fetchBurst int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
 
-	ttl           time.Duration
-	onHit, onMiss func()
+	mu    sync.Mutex
+	known map[string]struct{}
+
+	ttl, negativeTTL, refreshInterval, staleGrace time.Duration
+	onHit, onMiss, onRefresh                      func()
+	onError                                       func(error)
 }
 
 // NewCache TODO.
@@ -53,19 +72,39 @@ func NewCache(opts ...CacheOption) (*Cache, error) {
 	noop := func() {}
 
 	res := &Cache{
-		cache:  cache{Configuration: ccache.Configure()},
-		client: client{ConfigClient: secret.DefaultClientConfig()},
-		ttl:    defaultTTL,
-		onHit:  noop,
-		onMiss: noop,
+		cache:       cache{Configuration: ccache.Configure()},
+		negative:    cache{Configuration: ccache.Configure()},
+		client:      client{ConfigClient: secret.DefaultClientConfig()},
+		done:        make(chan struct{}),
+		known:       make(map[string]struct{}),
+		ttl:         defaultTTL,
+		negativeTTL: defaultNegativeTTL,
+		onHit:       noop,
+		onMiss:      noop,
+		onRefresh:   noop,
+		onError:     func(error) {},
 	}
 
 	for _, opt := range opts {
 		opt(res)
 	}
 
+	if err := res.client.init(); err != nil {
+		return nil, err
+	}
+
 	res.cache.init()
-	return res, res.client.init()
+	res.negative.init()
+	res.limiter = newLimiter(res.fetchRate, res.fetchBurst)
+	res.ctx, res.cancel = context.WithCancel(context.Background())
+
+	if res.refreshInterval > 0 {
+		go res.poll()
+	} else {
+		close(res.done)
+	}
+
+	return res, nil
 }
 
 func (c *Cache) addKey(key jwk.Key) (*token.Validater, error) {
@@ -79,20 +118,46 @@ func (c *Cache) addKey(key jwk.Key) (*token.Validater, error) {
 		return nil, err
 	}
 
+	c.mu.Lock()
+	c.known[keyID] = struct{}{}
+	c.mu.Unlock()
+
+	c.negative.Delete(keyID)
 	c.cache.Set(keyID, validater, c.ttl)
 	return validater, nil
 }
 
-func (c *Cache) lookupKey(keyID string) (*token.Validater, bool) {
-	if item := c.cache.Get(keyID); item != nil && !item.Expired() {
+func (c *Cache) evictKeys(keep map[string]struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for keyID := range c.known {
+		if _, ok := keep[keyID]; !ok {
+			c.cache.Delete(keyID)
+			delete(c.known, keyID)
+		}
+	}
+}
+
+// NOTE: Expired entries are kept, rather than deleted, so they remain
+// available as stale fallbacks within the grace period
+func (c *Cache) lookupKey(keyID string) (validater *token.Validater, fresh bool) {
+	item := c.cache.Get(keyID)
+
+	switch {
+	case item == nil:
+		return nil, false
+	case !item.Expired():
 		return item.Value().(*token.Validater), true
+	case time.Since(item.Expires()) < c.staleGrace:
+		return item.Value().(*token.Validater), false
 	}
 
 	return nil, false
 }
 
 func (c *Cache) fetchKey(keyID string) (jwk.Key, error) {
-	key, err := c.client.Key(context.TODO(), keyID)
+	key, err := c.client.Key(c.ctx, keyID)
 	if err != nil {
 		return nil, err
 	}
@@ -104,9 +169,88 @@ func (c *Cache) fetchKey(keyID string) (jwk.Key, error) {
 	return key, nil
 }
 
+func (c *Cache) acquireKey(keyID string) (*token.Validater, error) {
+	// Recently reported missing upstream => fail without a fetch
+	if item := c.negative.Get(keyID); item != nil && !item.Expired() {
+		return nil, fmt.Errorf("key id '%s': %w", keyID, secret.ErrNotFound)
+	}
+
+	// NOTE: Concurrent misses on one key id share a single upstream fetch
+	res, err, _ := c.flight.Do(keyID, func() (interface{}, error) {
+		if !c.limiter.allow() {
+			return nil, ErrFetchLimited
+		}
+
+		key, err := c.fetchKey(keyID)
+		if err != nil {
+			if errors.Is(err, secret.ErrNotFound) {
+				c.negative.Set(keyID, struct{}{}, c.negativeTTL)
+			}
+			return nil, err
+		}
+
+		return c.addKey(key)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*token.Validater), nil
+}
+
+func (c *Cache) refresh(ctx context.Context) error {
+	set, err := c.client.Set(ctx)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]struct{}, len(set.Keys))
+
+	for _, key := range set.Keys {
+		if _, err := c.addKey(key); err != nil {
+			return err
+		}
+
+		keep[key.KeyID()] = struct{}{}
+	}
+
+	// Keys no longer served upstream => evict
+	c.evictKeys(keep)
+	return nil
+}
+
+func (c *Cache) poll() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		switch err := c.refresh(c.ctx); {
+		case c.ctx.Err() != nil:
+			return
+		case err != nil:
+			c.onError(err)
+		default:
+			c.onRefresh()
+		}
+	}
+}
+
 // Close TODO.
 func (c *Cache) Close() error {
+	c.cancel()
+	<-c.done
+
 	c.cache.Stop()
+	c.negative.Stop()
 	return nil
 }
 
@@ -122,21 +266,24 @@ func (c *Cache) Validate(token string, claims interface{}) error {
 	}
 
 	// Attempt cache lookup
-	validater, found := c.lookupKey(keyID)
+	validater, fresh := c.lookupKey(keyID)
 
-	if found {
+	if fresh {
 		// Found in cache => continue
 		c.onHit()
 	} else {
-		// Not found in cache => acquire via client
+		// Not found in cache, or stale => acquire via client
 		c.onMiss()
 
-		key, err := c.fetchKey(keyID)
-		if err != nil {
-			return err
-		}
+		acquired, err := c.acquireKey(keyID)
 
-		if validater, err = c.addKey(key); err != nil {
+		switch {
+		case err == nil:
+			validater = acquired
+		case validater != nil && !errors.Is(err, secret.ErrNotFound):
+			// Secret service unavailable, stale within grace period => continue
+			c.onError(err)
+		default:
 			return err
 		}
 	}
@@ -146,20 +293,7 @@ func (c *Cache) Validate(token string, claims interface{}) error {
 }
 
 // Warm TODO.
-func (c *Cache) Warm(ctx context.Context) error {
-	set, err := c.client.Set(ctx)
-	if err != nil {
-		return err
-	}
-
-	for _, key := range set.Keys {
-		if _, err := c.addKey(key); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
+func (c *Cache) Warm(ctx context.Context) error { return c.refresh(ctx) }
 
 func extractKID(token string) (string, error) {
 	/*
@@ -0,0 +1,435 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	httpsecret "github.com/oligarch316/go-auth-service/pkg/http/secret"
+	"github.com/oligarch316/go-auth-service/pkg/secret/token"
+)
+
+// fakeSecretService serves public keys as the secret service would, counting
+// key requests and failing everything while down
+type fakeSecretService struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	keys []jwk.Key
+
+	down    int32
+	fetches int32
+	block   chan struct{}
+}
+
+func newFakeSecretService(t *testing.T, keys ...jwk.Key) *fakeSecretService {
+	t.Helper()
+
+	res := &fakeSecretService{keys: keys}
+	res.Server = httptest.NewServer(http.HandlerFunc(res.serve))
+
+	t.Cleanup(res.Close)
+	return res
+}
+
+func (fss *fakeSecretService) setKeys(keys ...jwk.Key) {
+	fss.mu.Lock()
+	defer fss.mu.Unlock()
+
+	fss.keys = keys
+}
+
+func (fss *fakeSecretService) setDown(down bool) {
+	var val int32
+	if down {
+		val = 1
+	}
+
+	atomic.StoreInt32(&fss.down, val)
+}
+
+func (fss *fakeSecretService) fetchCount() int { return int(atomic.LoadInt32(&fss.fetches)) }
+
+func (fss *fakeSecretService) serve(w http.ResponseWriter, r *http.Request) {
+	// NOTE: Client urls carry doubled separators, cleaned by the real router
+	var (
+		urlPath = path.Clean(r.URL.Path)
+		setPath = "/" + httpsecret.APIVersion + "/secret/set"
+		keyPath = "/" + httpsecret.APIVersion + "/secret/key/"
+		body    interface{}
+	)
+
+	if strings.HasPrefix(urlPath, keyPath) {
+		atomic.AddInt32(&fss.fetches, 1)
+
+		if fss.block != nil {
+			<-fss.block
+		}
+	}
+
+	if atomic.LoadInt32(&fss.down) == 1 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	fss.mu.Lock()
+	keys := fss.keys
+	fss.mu.Unlock()
+
+	switch {
+	case urlPath == setPath:
+		body = &jwk.Set{Keys: keys}
+	case strings.HasPrefix(urlPath, keyPath):
+		keyID := strings.TrimPrefix(urlPath, keyPath)
+		for _, key := range keys {
+			if key.KeyID() == keyID {
+				body = key
+			}
+		}
+	}
+
+	if body == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func newTestCache(t *testing.T, fss *fakeSecretService, opts ...CacheOption) *Cache {
+	t.Helper()
+
+	cfg := httpsecret.DefaultClientConfig()
+	cfg.Address = fss.URL
+	cfg.Retry.MaxAttempts = 1
+
+	res, err := NewCache(append([]CacheOption{WithClientConfig(cfg), WithFetchRateLimit(0, 0)}, opts...)...)
+	if err != nil {
+		t.Fatalf("failed to create cache: %s", err)
+	}
+
+	t.Cleanup(func() { res.Close() })
+	return res
+}
+
+func signTestToken(t *testing.T, signer *token.Signer) string {
+	t.Helper()
+
+	res, err := signer.Sign(StandardClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err)
+	}
+
+	return res
+}
+
+func validate(c *Cache, tokenStr string) error {
+	var claims StandardClaims
+	return c.Validate(tokenStr, &claims)
+}
+
+func TestCacheHit(t *testing.T) {
+	var (
+		signer, public = testKeyPair(t)
+		fss            = newFakeSecretService(t, public)
+		hits, misses   int
+		cache          = newTestCache(t, fss, WithOnHitHook(func() { hits++ }), WithOnMissHook(func() { misses++ }))
+		tokenStr       = signTestToken(t, signer)
+	)
+
+	for i := 0; i < 3; i++ {
+		if err := validate(cache, tokenStr); err != nil {
+			t.Fatalf("failed to validate: %s", err)
+		}
+	}
+
+	if fss.fetchCount() != 1 {
+		t.Errorf("expected 1 fetch, got %d", fss.fetchCount())
+	}
+
+	if hits != 2 || misses != 1 {
+		t.Errorf("expected 2 hits and 1 miss, got %d and %d", hits, misses)
+	}
+}
+
+func TestCacheStale(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+
+	tests := []struct {
+		name         string
+		staleGrace   time.Duration
+		upstream     func(fss *fakeSecretService)
+		expectValid  bool
+		expectErrors int
+	}{
+		{
+			name:         "upstream down within grace",
+			staleGrace:   time.Hour,
+			upstream:     func(fss *fakeSecretService) { fss.setDown(true) },
+			expectValid:  true,
+			expectErrors: 1,
+		},
+		{
+			name:        "upstream down past grace",
+			staleGrace:  0,
+			upstream:    func(fss *fakeSecretService) { fss.setDown(true) },
+			expectValid: false,
+		},
+		{
+			name:        "key removed upstream within grace",
+			staleGrace:  time.Hour,
+			upstream:    func(fss *fakeSecretService) { fss.setKeys() },
+			expectValid: false,
+		},
+		{
+			name:        "upstream up within grace",
+			staleGrace:  time.Hour,
+			upstream:    func(fss *fakeSecretService) {},
+			expectValid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				signer, public = testKeyPair(t)
+				fss            = newFakeSecretService(t, public)
+				errs           []error
+				cache          = newTestCache(t, fss, WithDefaultTTL(ttl), WithStaleGrace(test.staleGrace), WithOnErrorHook(func(err error) { errs = append(errs, err) }))
+				tokenStr       = signTestToken(t, signer)
+			)
+
+			if err := validate(cache, tokenStr); err != nil {
+				t.Fatalf("failed to validate: %s", err)
+			}
+
+			time.Sleep(2 * ttl)
+			test.upstream(fss)
+
+			err := validate(cache, tokenStr)
+
+			switch {
+			case test.expectValid && err != nil:
+				t.Errorf("expected stale key to validate, got: %s", err)
+			case !test.expectValid && err == nil:
+				t.Error("expected error")
+			}
+
+			// Stale key refetched on every use past its ttl
+			if fss.fetchCount() != 2 {
+				t.Errorf("expected 2 fetches, got %d", fss.fetchCount())
+			}
+
+			// Serving stale is reported, rather than silent
+			if len(errs) != test.expectErrors {
+				t.Errorf("expected %d reported errors, got %v", test.expectErrors, errs)
+			}
+		})
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	const negativeTTL = 20 * time.Millisecond
+
+	var (
+		signer, public = testKeyPair(t)
+		fss            = newFakeSecretService(t)
+		cache          = newTestCache(t, fss, WithNegativeTTL(negativeTTL))
+		tokenStr       = signTestToken(t, signer)
+	)
+
+	for i := 0; i < 3; i++ {
+		if err := validate(cache, tokenStr); !errors.Is(err, httpsecret.ErrNotFound) {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	}
+
+	if fss.fetchCount() != 1 {
+		t.Errorf("expected 1 fetch while negatively cached, got %d", fss.fetchCount())
+	}
+
+	// Key published upstream, negative entry expires => refetched
+	fss.setKeys(public)
+	time.Sleep(2 * negativeTTL)
+
+	if err := validate(cache, tokenStr); err != nil {
+		t.Errorf("failed to validate after negative entry expired: %s", err)
+	}
+
+	if fss.fetchCount() != 2 {
+		t.Errorf("expected 2 fetches, got %d", fss.fetchCount())
+	}
+
+	t.Run("not cached when upstream down", func(t *testing.T) {
+		var (
+			signer, _ = testKeyPair(t)
+			fss       = newFakeSecretService(t)
+			cache     = newTestCache(t, fss)
+			tokenStr  = signTestToken(t, signer)
+		)
+
+		fss.setDown(true)
+
+		for i := 0; i < 2; i++ {
+			if err := validate(cache, tokenStr); err == nil || errors.Is(err, httpsecret.ErrNotFound) {
+				t.Fatalf("expected upstream error, got: %v", err)
+			}
+		}
+
+		if fss.fetchCount() != 2 {
+			t.Errorf("expected 2 fetches, got %d", fss.fetchCount())
+		}
+	})
+}
+
+func TestCacheSingleFlight(t *testing.T) {
+	const callers = 10
+
+	var (
+		signer, public = testKeyPair(t)
+		fss            = newFakeSecretService(t, public)
+		cache          = newTestCache(t, fss)
+		tokenStr       = signTestToken(t, signer)
+		wg             sync.WaitGroup
+		errs           = make(chan error, callers)
+	)
+
+	fss.block = make(chan struct{})
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			errs <- validate(cache, tokenStr)
+		}()
+	}
+
+	// Hold the first fetch open while the remaining callers miss
+	for fss.fetchCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(fss.block)
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("failed to validate: %s", err)
+		}
+	}
+
+	if fss.fetchCount() != 1 {
+		t.Errorf("expected 1 shared fetch, got %d", fss.fetchCount())
+	}
+}
+
+func TestCacheFetchRateLimit(t *testing.T) {
+	var (
+		fss   = newFakeSecretService(t)
+		cache = newTestCache(t, fss, WithFetchRateLimit(0.001, 2))
+	)
+
+	for i := 0; i < 2; i++ {
+		signer, _ := testKeyPair(t)
+
+		if err := validate(cache, signTestToken(t, signer)); !errors.Is(err, httpsecret.ErrNotFound) {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	}
+
+	signer, _ := testKeyPair(t)
+
+	if err := validate(cache, signTestToken(t, signer)); !errors.Is(err, ErrFetchLimited) {
+		t.Errorf("expected fetch limited error, got: %v", err)
+	}
+
+	if fss.fetchCount() != 2 {
+		t.Errorf("expected 2 fetches, got %d", fss.fetchCount())
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	var (
+		signerA, publicA = testKeyPair(t)
+		signerB, publicB = testKeyPair(t)
+		fss              = newFakeSecretService(t, publicA, publicB)
+		cache            = newTestCache(t, fss)
+	)
+
+	if err := cache.Warm(context.Background()); err != nil {
+		t.Fatalf("failed to warm cache: %s", err)
+	}
+
+	if cache.ItemCount() != 2 {
+		t.Errorf("expected 2 cached keys, got %d", cache.ItemCount())
+	}
+
+	// Warmed keys validate without a fetch
+	for _, signer := range []*token.Signer{signerA, signerB} {
+		if err := validate(cache, signTestToken(t, signer)); err != nil {
+			t.Errorf("failed to validate: %s", err)
+		}
+	}
+
+	if fss.fetchCount() != 0 {
+		t.Errorf("expected no fetches, got %d", fss.fetchCount())
+	}
+
+	// Key A no longer served => evicted on refresh
+	fss.setKeys(publicB)
+
+	if err := cache.Warm(context.Background()); err != nil {
+		t.Fatalf("failed to refresh cache: %s", err)
+	}
+
+	if cache.ItemCount() != 1 {
+		t.Errorf("expected 1 cached key, got %d", cache.ItemCount())
+	}
+
+	if err := validate(cache, signTestToken(t, signerA)); !errors.Is(err, httpsecret.ErrNotFound) {
+		t.Errorf("expected not found error for evicted key, got: %v", err)
+	}
+
+	t.Run("background", func(t *testing.T) {
+		var (
+			fss       = newFakeSecretService(t, publicA)
+			refreshed = make(chan struct{}, 1)
+			hook      = func() {
+				select {
+				case refreshed <- struct{}{}:
+				default:
+				}
+			}
+		)
+
+		cache := newTestCache(t, fss, WithRefreshInterval(10*time.Millisecond), WithOnRefreshHook(hook))
+
+		select {
+		case <-refreshed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for background refresh")
+		}
+
+		if err := validate(cache, signTestToken(t, signerA)); err != nil {
+			t.Errorf("failed to validate: %s", err)
+		}
+
+		if fss.fetchCount() != 0 {
+			t.Errorf("expected no fetches after background refresh, got %d", fss.fetchCount())
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/oligarch316/go-auth-service/pkg/http/secret"
+	"github.com/oligarch316/go-auth-service/pkg/secret/token"
+)
+
+// KeySetValidater validates tokens directly against an in-process key set,
+// for servers running alongside the secret service.
+type KeySetValidater struct {
+	Keys interface{ Load() *jwk.Set }
+}
+
+// Validate TODO.
+func (ksv KeySetValidater) Validate(tokenStr string, claims interface{}) error {
+	// Extract key id
+	keyID, err := extractKID(tokenStr)
+	if err != nil {
+		return err
+	}
+
+	// Lookup current key
+	keys := ksv.Keys.Load().LookupKeyID(keyID)
+	if len(keys) < 1 {
+		return fmt.Errorf("key id '%s': %w", keyID, secret.ErrNotFound)
+	}
+
+	validater, err := token.NewValidater(keys[0])
+	if err != nil {
+		return err
+	}
+
+	// Perform validation
+	return validater.Validate(tokenStr, claims)
+}
@@ -0,0 +1,83 @@
+package token
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	httpsecret "github.com/oligarch316/go-auth-service/pkg/http/secret"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	"github.com/oligarch316/go-auth-service/pkg/secret/token"
+)
+
+type staticKeys []jwk.Key
+
+func (sk staticKeys) Load() *jwk.Set { return &jwk.Set{Keys: sk} }
+
+func testKeyPair(t *testing.T) (*token.Signer, jwk.Key) {
+	t.Helper()
+
+	store, err := secret.Generate(jwa.EC).Store()
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	private, err := secret.NewPrivate(jwa.ES256, store)
+	if err != nil {
+		t.Fatalf("failed to create private key: %s", err)
+	}
+
+	public, err := secret.NewPublic(jwa.ES256, store)
+	if err != nil {
+		t.Fatalf("failed to create public key: %s", err)
+	}
+
+	signer, err := token.NewSigner(private)
+	if err != nil {
+		t.Fatalf("failed to create signer: %s", err)
+	}
+
+	return signer, public
+}
+
+func TestKeySetValidater(t *testing.T) {
+	var (
+		signer, public = testKeyPair(t)
+		_, other       = testKeyPair(t)
+	)
+
+	tokenStr, err := signer.Sign(StandardClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err)
+	}
+
+	t.Run("known key", func(t *testing.T) {
+		var claims StandardClaims
+
+		if err := (KeySetValidater{Keys: staticKeys{other, public}}).Validate(tokenStr, &claims); err != nil {
+			t.Fatalf("failed to validate: %s", err)
+		}
+
+		if claims.Subject != "alice" {
+			t.Errorf("expected subject 'alice', got '%s'", claims.Subject)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		var claims StandardClaims
+
+		err := (KeySetValidater{Keys: staticKeys{other}}).Validate(tokenStr, &claims)
+		if !errors.Is(err, httpsecret.ErrNotFound) {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		var claims StandardClaims
+
+		if err := (KeySetValidater{Keys: staticKeys{public}}).Validate("not.a.token", &claims); err == nil {
+			t.Error("expected error for malformed token")
+		}
+	})
+}
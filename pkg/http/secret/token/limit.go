@@ -0,0 +1,48 @@
+package token
+
+import (
+	"sync"
+	"time"
+)
+
+// NOTE: Minimal token bucket, refilled lazily on each call
+type limiter struct {
+	mu sync.Mutex
+
+	rate, burst, tokens float64
+	last                time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (l *limiter) allow() bool {
+	// Non-positive rate => unlimited
+	if l == nil || l.rate <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if l.tokens += now.Sub(l.last).Seconds() * l.rate; l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
@@ -31,6 +31,23 @@ func WithMaxSize(maxSize int64) CacheOption {
 	return func(c *Cache) { c.cache.MaxSize(maxSize) }
 }
 
+// WithNegativeTTL TODO.
+func WithNegativeTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) { c.negativeTTL = ttl }
+}
+
+// WithNegativeMaxSize TODO.
+func WithNegativeMaxSize(maxSize int64) CacheOption {
+	return func(c *Cache) { c.negative.MaxSize(maxSize) }
+}
+
+// WithFetchRateLimit TODO.
+//
+// A non-positive rate disables the limit.
+func WithFetchRateLimit(perSecond float64, burst int) CacheOption {
+	return func(c *Cache) { c.fetchRate, c.fetchBurst = perSecond, burst }
+}
+
 // WithOnHitHook TODO.
 func WithOnHitHook(hook func()) CacheOption {
 	return func(c *Cache) { c.onHit = hook }
@@ -41,19 +58,53 @@ func WithOnMissHook(hook func()) CacheOption {
 	return func(c *Cache) { c.onMiss = hook }
 }
 
+// WithOnRefreshHook TODO.
+func WithOnRefreshHook(hook func()) CacheOption {
+	return func(c *Cache) { c.onRefresh = hook }
+}
+
+// WithOnErrorHook TODO.
+func WithOnErrorHook(hook func(error)) CacheOption {
+	return func(c *Cache) { c.onError = hook }
+}
+
+// WithRefreshInterval TODO.
+//
+// A non-positive interval disables background refresh.
+func WithRefreshInterval(interval time.Duration) CacheOption {
+	return func(c *Cache) { c.refreshInterval = interval }
+}
+
+// WithStaleGrace TODO.
+func WithStaleGrace(grace time.Duration) CacheOption {
+	return func(c *Cache) { c.staleGrace = grace }
+}
+
 // ConfigCache TODO.
 type ConfigCache struct {
-	Client     secret.ConfigClient `json:"client"`
-	DefaultTTL ctype.Duration      `json:"defaultTTL"`
-	MaxSize    int64               `json:"maxSize"`
+	Client          secret.ConfigClient `json:"client"`
+	DefaultTTL      ctype.Duration      `json:"defaultTTL"`
+	MaxSize         int64               `json:"maxSize"`
+	RefreshInterval ctype.Duration      `json:"refreshInterval"`
+	StaleGrace      ctype.Duration      `json:"staleGrace"`
+	NegativeTTL     ctype.Duration      `json:"negativeTTL"`
+	NegativeMaxSize int64               `json:"negativeMaxSize"`
+	FetchRate       float64             `json:"fetchRate"`
+	FetchBurst      int                 `json:"fetchBurst"`
 }
 
 // DefaultCacheConfig TODO.
 func DefaultCacheConfig() ConfigCache {
 	return ConfigCache{
-		Client:     secret.DefaultClientConfig(),
-		DefaultTTL: ctype.Duration{Duration: 24 * time.Hour},
-		MaxSize:    10,
+		Client:          secret.DefaultClientConfig(),
+		DefaultTTL:      ctype.Duration{Duration: 24 * time.Hour},
+		MaxSize:         10,
+		RefreshInterval: ctype.Duration{Duration: 5 * time.Minute},
+		StaleGrace:      ctype.Duration{Duration: time.Hour},
+		NegativeTTL:     ctype.Duration{Duration: 30 * time.Second},
+		NegativeMaxSize: 1000,
+		FetchRate:       5,
+		FetchBurst:      10,
 	}
 }
 
@@ -63,6 +114,11 @@ func (cc ConfigCache) Options() []CacheOption {
 		WithClientConfig(cc.Client),
 		WithDefaultTTL(cc.DefaultTTL.Duration),
 		WithMaxSize(cc.MaxSize),
+		WithRefreshInterval(cc.RefreshInterval.Duration),
+		WithStaleGrace(cc.StaleGrace.Duration),
+		WithNegativeTTL(cc.NegativeTTL.Duration),
+		WithNegativeMaxSize(cc.NegativeMaxSize),
+		WithFetchRateLimit(cc.FetchRate, cc.FetchBurst),
 	}
 }
 
@@ -70,5 +126,11 @@ func (cc ConfigCache) Options() []CacheOption {
 func (cc ConfigCache) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddDuration("defaultTTL", cc.DefaultTTL.Duration)
 	enc.AddInt64("maxSize", cc.MaxSize)
+	enc.AddDuration("refreshInterval", cc.RefreshInterval.Duration)
+	enc.AddDuration("staleGrace", cc.StaleGrace.Duration)
+	enc.AddDuration("negativeTTL", cc.NegativeTTL.Duration)
+	enc.AddInt64("negativeMaxSize", cc.NegativeMaxSize)
+	enc.AddFloat64("fetchRate", cc.FetchRate)
+	enc.AddInt("fetchBurst", cc.FetchBurst)
 	return enc.AddObject("client", cc.Client)
 }
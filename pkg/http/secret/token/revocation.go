@@ -0,0 +1,223 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Revocation TODO
+//
+// Revokes the single token identified by TokenID when set, otherwise every
+// token for Subject issued before IssuedBefore. Token issue times carry whole
+// seconds only, so IssuedBefore is compared at that precision too.
+type Revocation struct {
+	TokenID      string       `json:"jti,omitempty"`
+	Subject      string       `json:"sub,omitempty"`
+	IssuedBefore *NumericDate `json:"iatBefore,omitempty"`
+	Expiration   *NumericDate `json:"exp"`
+}
+
+// RevocationSource TODO.
+type RevocationSource interface {
+	Revocations(ctx context.Context) ([]Revocation, error)
+}
+
+// RevocationSourceFunc TODO.
+type RevocationSourceFunc func(context.Context) ([]Revocation, error)
+
+// Revocations TODO.
+func (rsf RevocationSourceFunc) Revocations(ctx context.Context) ([]Revocation, error) {
+	return rsf(ctx)
+}
+
+// ConfigRevocationList TODO.
+type ConfigRevocationList struct {
+	PollInterval ctype.Duration `json:"pollInterval"`
+}
+
+// DefaultRevocationListConfig TODO.
+func DefaultRevocationListConfig() ConfigRevocationList {
+	return ConfigRevocationList{
+		PollInterval: ctype.Duration{Duration: 30 * time.Second},
+	}
+}
+
+// MarshalLogObject TODO.
+func (crl ConfigRevocationList) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddDuration("pollInterval", crl.PollInterval.Duration)
+	return nil
+}
+
+type subjectRevocation struct{ issuedBefore, expiration time.Time }
+
+// RevocationList TODO.
+type RevocationList struct {
+	mu       sync.RWMutex
+	tokenIDs map[string]time.Time
+	subjects map[string]subjectRevocation
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRevocationList TODO.
+func NewRevocationList() *RevocationList {
+	return &RevocationList{
+		tokenIDs: make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (rl *RevocationList) add(item Revocation) {
+	if item.Expiration == nil {
+		return
+	}
+
+	if item.TokenID != "" {
+		rl.tokenIDs[item.TokenID] = item.Expiration.Time
+		return
+	}
+
+	if item.Subject == "" || item.IssuedBefore == nil {
+		return
+	}
+
+	// Keep the broader of two revocations for the same subject
+	entry := rl.subjects[item.Subject]
+
+	if issuedBefore := item.IssuedBefore.Truncate(time.Second); issuedBefore.After(entry.issuedBefore) {
+		entry.issuedBefore = issuedBefore
+	}
+
+	if item.Expiration.After(entry.expiration) {
+		entry.expiration = item.Expiration.Time
+	}
+
+	rl.subjects[item.Subject] = entry
+}
+
+// Add TODO.
+func (rl *RevocationList) Add(items ...Revocation) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for _, item := range items {
+		rl.add(item)
+	}
+}
+
+// Replace TODO.
+func (rl *RevocationList) Replace(items []Revocation) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.tokenIDs = make(map[string]time.Time, len(items))
+	rl.subjects = make(map[string]subjectRevocation)
+
+	for _, item := range items {
+		rl.add(item)
+	}
+}
+
+// Len TODO.
+func (rl *RevocationList) Len() int {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	return len(rl.tokenIDs) + len(rl.subjects)
+}
+
+// Revoked TODO.
+func (rl *RevocationList) Revoked(claims StandardClaims) bool {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	now := time.Now()
+
+	if claims.TokenID != "" {
+		if exp, ok := rl.tokenIDs[claims.TokenID]; ok && now.Before(exp) {
+			return true
+		}
+	}
+
+	entry, ok := rl.subjects[claims.Subject]
+	if !ok || !now.Before(entry.expiration) {
+		return false
+	}
+
+	// NOTE: Tokens lacking an "iat" can't prove they postdate the revocation
+	return claims.IssuedAt == nil || claims.IssuedAt.Before(entry.issuedBefore)
+}
+
+// Refresh TODO.
+func (rl *RevocationList) Refresh(ctx context.Context, source RevocationSource) error {
+	items, err := source.Revocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	rl.Replace(items)
+	return nil
+}
+
+// Poll TODO.
+func (rl *RevocationList) Poll(source RevocationSource, interval time.Duration, onError func(error)) {
+	refresh := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+
+		if err := rl.Refresh(ctx, source); err != nil {
+			onError(err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-rl.stop:
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
+
+// LoadRevocationList TODO.
+//
+// Performs an initial refresh, failing if it does, then polls source at the
+// configured interval until closed.
+func LoadRevocationList(cfg ConfigRevocationList, source RevocationSource, corelet *observ.Corelet) (*RevocationList, error) {
+	res := NewRevocationList()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PollInterval.Duration)
+	defer cancel()
+
+	if err := res.Refresh(ctx, source); err != nil {
+		return nil, err
+	}
+
+	onError := func(err error) {
+		corelet.Logger.Warn("failed to refresh revocation list", zap.Error(err))
+		corelet.Emitter.Count("errors", 1)
+	}
+
+	res.Poll(source, cfg.PollInterval.Duration, onError)
+	return res, nil
+}
+
+// Close TODO.
+func (rl *RevocationList) Close() error {
+	rl.stopOnce.Do(func() { close(rl.stop) })
+	return nil
+}
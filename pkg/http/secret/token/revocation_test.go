@@ -0,0 +1,130 @@
+package token
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func numericDate(t time.Time) *NumericDate { return &NumericDate{Time: t} }
+
+// Claims as a validater sees them, i.e. after a JSON round trip
+func issuedClaims(t *testing.T, subject, tokenID string, issuedAt time.Time) StandardClaims {
+	t.Helper()
+
+	data, err := json.Marshal(StandardClaims{Subject: subject, TokenID: tokenID, IssuedAt: numericDate(issuedAt)})
+	if err != nil {
+		t.Fatalf("failed to marshal claims: %s", err)
+	}
+
+	var res StandardClaims
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatalf("failed to unmarshal claims: %s", err)
+	}
+
+	return res
+}
+
+func TestRevocationListSubject(t *testing.T) {
+	var (
+		// Mid second, so sub-second precision would matter
+		revokedAt  = time.Now().Truncate(time.Second).Add(700 * time.Millisecond)
+		expiration = revokedAt.Add(time.Hour)
+	)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		expected bool
+	}{
+		{name: "previous second", issuedAt: revokedAt.Add(-time.Second), expected: true},
+		{name: "long before", issuedAt: revokedAt.Add(-30 * time.Minute), expected: true},
+		{name: "same second after revocation", issuedAt: revokedAt.Add(100 * time.Millisecond), expected: false},
+		{name: "next second", issuedAt: revokedAt.Add(time.Second), expected: false},
+	}
+
+	list := NewRevocationList()
+	list.Add(Revocation{Subject: "alice", IssuedBefore: numericDate(revokedAt), Expiration: numericDate(expiration)})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := issuedClaims(t, "alice", "", test.issuedAt)
+
+			if actual := list.Revoked(claims); actual != test.expected {
+				t.Errorf("expected revoked %t, got %t", test.expected, actual)
+			}
+		})
+	}
+
+	if list.Revoked(issuedClaims(t, "bob", "", revokedAt.Add(-time.Second))) {
+		t.Error("revocation applied to another subject")
+	}
+
+	if !list.Revoked(StandardClaims{Subject: "alice"}) {
+		t.Error("token lacking iat not revoked")
+	}
+}
+
+func TestRevocationListRevokeThenIssue(t *testing.T) {
+	list := NewRevocationList()
+
+	// Revoke, then issue straight away as a password change followed by a
+	// login would
+	now := time.Now()
+	list.Add(Revocation{Subject: "alice", IssuedBefore: numericDate(now.Truncate(time.Second)), Expiration: numericDate(now.Add(time.Hour))})
+
+	if claims := issuedClaims(t, "alice", "", time.Now()); list.Revoked(claims) {
+		t.Error("token issued right after revocation is revoked")
+	}
+}
+
+func TestRevocationListTokenID(t *testing.T) {
+	var (
+		now  = time.Now()
+		list = NewRevocationList()
+	)
+
+	list.Add(
+		Revocation{TokenID: "active", Expiration: numericDate(now.Add(time.Hour))},
+		Revocation{TokenID: "expired", Expiration: numericDate(now.Add(-time.Second))},
+		Revocation{TokenID: "no expiration"},
+	)
+
+	tests := []struct {
+		tokenID  string
+		expected bool
+	}{
+		{tokenID: "active", expected: true},
+		{tokenID: "expired", expected: false},
+		{tokenID: "no expiration", expected: false},
+		{tokenID: "other", expected: false},
+	}
+
+	for _, test := range tests {
+		if actual := list.Revoked(issuedClaims(t, "alice", test.tokenID, now)); actual != test.expected {
+			t.Errorf("token '%s': expected revoked %t, got %t", test.tokenID, test.expected, actual)
+		}
+	}
+}
+
+func TestRevocationListReplace(t *testing.T) {
+	var (
+		now  = time.Now()
+		list = NewRevocationList()
+	)
+
+	list.Add(Revocation{TokenID: "old", Expiration: numericDate(now.Add(time.Hour))})
+	list.Replace([]Revocation{{TokenID: "new", Expiration: numericDate(now.Add(time.Hour))}})
+
+	if list.Len() != 1 {
+		t.Errorf("expected 1 revocation, got %d", list.Len())
+	}
+
+	if list.Revoked(issuedClaims(t, "alice", "old", now)) {
+		t.Error("replaced revocation still applies")
+	}
+
+	if !list.Revoked(issuedClaims(t, "alice", "new", now)) {
+		t.Error("replacement revocation does not apply")
+	}
+}
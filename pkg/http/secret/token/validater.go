@@ -29,51 +29,81 @@ type Validater struct {
 	Secret interface {
 		Validate(token string, claims interface{}) error
 	}
+
+	// Optional, nil disables revocation checks
+	Revocations interface {
+		Revoked(claims StandardClaims) bool
+	}
 }
 
 // Validate TODO.
 func (v Validater) Validate(r *http.Request) (string, error) {
-	tokenStr, err := loadTokenString(r)
+	claims, err := v.ValidateClaims(r)
 	if err != nil {
 		return "", err
 	}
 
+	return claims.Subject, nil
+}
+
+// ValidateClaims TODO.
+func (v Validater) ValidateClaims(r *http.Request) (StandardClaims, error) {
+	tokenStr, err := ParseAuthorization(r.Header.Get("Authorization"))
+	if err != nil {
+		return StandardClaims{}, err
+	}
+
+	return v.ValidateToken(tokenStr)
+}
+
+// ValidateToken TODO.
+func (v Validater) ValidateToken(tokenStr string) (StandardClaims, error) {
 	var claims StandardClaims
+
 	if err := v.Secret.Validate(tokenStr, &claims); err != nil {
-		return "", err
+		return claims, err
 	}
 
+	return claims, v.CheckClaims(claims)
+}
+
+// CheckClaims TODO.
+func (v Validater) CheckClaims(claims StandardClaims) error {
 	now := time.Now()
 
 	switch {
 	// ---- Required claim names
 	case claims.Subject == "":
-		return "", errors.New("sub: missing subject")
+		return errors.New("sub: missing subject")
 	case claims.Issuer == "":
-		return "", errors.New("iss: missing issuer")
+		return errors.New("iss: missing issuer")
 	case claims.Audience == nil:
-		return "", errors.New("aud: missing audience")
+		return errors.New("aud: missing audience")
 	case claims.Expiration == nil:
-		return "", errors.New("exp: missing expiration")
+		return errors.New("exp: missing expiration")
 
 		// ----- Issuer/Audience allowances
 	case !v.Claims.AllowedIssuers.Contains(claims.Issuer):
-		return "", errors.New("iss: not from an allowed issuer")
+		return errors.New("iss: not from an allowed issuer")
 	case !claims.Audience.Contains(v.Claims.AudienceName):
-		return "", errors.New("aud: not for this audience")
+		return errors.New("aud: not for this audience")
 
 		// ----- Time requirements
 	case now.After(claims.Expiration.Time):
-		return "", errors.New("exp: expired")
+		return errors.New("exp: expired")
 	case claims.NotBefore != nil && now.Before(claims.NotBefore.Time):
-		return "", errors.New("nbf: not yet valid")
+		return errors.New("nbf: not yet valid")
+
+		// ----- Revocation
+	case v.Revocations != nil && v.Revocations.Revoked(claims):
+		return errors.New("jti: revoked")
 	}
 
-	return claims.Subject, nil
+	return nil
 }
 
-func loadTokenString(r *http.Request) (string, error) {
-	hdrVal := r.Header.Get("Authorization")
+// ParseAuthorization TODO.
+func ParseAuthorization(hdrVal string) (string, error) {
 	if hdrVal == "" {
 		return "", errors.New("empty authorization header")
 	}
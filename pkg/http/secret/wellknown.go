@@ -0,0 +1,156 @@
+package secret
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"go.uber.org/zap/zapcore"
+)
+
+// ConfigDiscovery TODO
+type ConfigDiscovery struct {
+	Issuer        string         `json:"issuer"`
+	JWKSURI       string         `json:"jwksURI"`
+	TokenEndpoint string         `json:"tokenEndpoint"`
+	MaxAge        ctype.Duration `json:"maxAge"`
+}
+
+// DefaultDiscoveryConfig TODO
+func DefaultDiscoveryConfig() ConfigDiscovery {
+	return ConfigDiscovery{
+		MaxAge: ctype.Duration{Duration: 5 * time.Minute},
+	}
+}
+
+// ResolveURLs TODO
+//
+// Fills in URLs left unconfigured once the scheme being served is known.
+func (cd *ConfigDiscovery) ResolveURLs(scheme, address, tokenEndpoint string) {
+	if cd.JWKSURI == "" {
+		cd.JWKSURI = scheme + "://" + address + pathWellKnownJWKS
+	}
+
+	if cd.TokenEndpoint == "" {
+		cd.TokenEndpoint = tokenEndpoint
+	}
+}
+
+// MarshalLogObject TODO
+func (cd ConfigDiscovery) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("issuer", cd.Issuer)
+	enc.AddString("jwksURI", cd.JWKSURI)
+	enc.AddString("tokenEndpoint", cd.TokenEndpoint)
+	enc.AddDuration("maxAge", cd.MaxAge.Duration)
+	return nil
+}
+
+type discoveryResponse struct {
+	Issuer            string   `json:"issuer"`
+	JWKSURI           string   `json:"jwks_uri"`
+	TokenEndpoint     string   `json:"token_endpoint,omitempty"`
+	ResponseTypes     []string `json:"response_types_supported"`
+	SubjectTypes      []string `json:"subject_types_supported"`
+	SigningAlgorithms []string `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported   []string `json:"claims_supported"`
+}
+
+func (s *Server) writeCacheable(w http.ResponseWriter, r *http.Request, bytes []byte) {
+	var (
+		sum  = sha256.Sum256(bytes)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	)
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.Discovery.MaxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	cTypeJSON.writeRespHeader(w)
+	w.Write(bytes)
+}
+
+func etagMatch(header, etag string) bool {
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimPrefix(strings.TrimSpace(item), "W/")
+		if item == etag || item == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleWellKnownJWKS TODO
+func (s *Server) HandleWellKnownJWKS() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "wellknownjwks",
+		Description: "JSON web key (jwk) set at its well-known location",
+		Method:      http.MethodGet,
+		MetricTag:   "well_known_jwks",
+	}
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		bytes, err := json.Marshal(s.Set.Load())
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
+			return
+		}
+
+		s.writeCacheable(w, r, bytes)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Handle: handle}
+}
+
+// HandleWellKnownDiscovery TODO
+func (s *Server) HandleWellKnownDiscovery() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "wellknowndiscovery",
+		Description: "OpenID Connect discovery document",
+		Method:      http.MethodGet,
+		MetricTag:   "well_known_discovery",
+	}
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var (
+			algSeen = make(map[string]bool)
+			resp    = discoveryResponse{
+				Issuer:            s.Discovery.Issuer,
+				JWKSURI:           s.Discovery.JWKSURI,
+				TokenEndpoint:     s.Discovery.TokenEndpoint,
+				ResponseTypes:     []string{"token"},
+				SubjectTypes:      []string{"public"},
+				SigningAlgorithms: []string{},
+				ClaimsSupported:   []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"},
+			}
+		)
+
+		// Signing algorithms follow the current key set
+		for _, key := range s.Set.Load().Keys {
+			if alg := key.Algorithm(); alg != "" && !algSeen[alg] {
+				algSeen[alg] = true
+				resp.SigningAlgorithms = append(resp.SigningAlgorithms, alg)
+			}
+		}
+
+		bytes, err := json.Marshal(resp)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
+			return
+		}
+
+		s.writeCacheable(w, r, bytes)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Handle: handle}
+}
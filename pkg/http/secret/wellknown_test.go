@@ -0,0 +1,187 @@
+package secret
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+)
+
+func TestWellKnownJWKS(t *testing.T) {
+	var (
+		set       = newTestKeySet(t, secret.ConfigKeyring{generatedKey(secret.KeyStateActive), generatedKey(secret.KeyStateVerify)})
+		discovery = ConfigDiscovery{MaxAge: ctype.Duration{Duration: 90 * time.Second}}
+		router    = newTestRouter(t, set, discovery)
+	)
+
+	rec := serve(router, JWKSPath(), nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	if actual := rec.Header().Get("Cache-Control"); actual != "public, max-age=90" {
+		t.Errorf("expected cache control 'public, max-age=90', got '%s'", actual)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an etag")
+	}
+
+	body, err := jwk.ParseBytes(rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("failed to parse jwks body: %s", err)
+	}
+
+	var actualIDs []string
+	for _, key := range body.Keys {
+		if secret.IsPrivate(key) {
+			t.Errorf("private key '%s' served", key.KeyID())
+		}
+
+		actualIDs = append(actualIDs, key.KeyID())
+	}
+
+	sort.Strings(actualIDs)
+
+	if !equalStrings(actualIDs, keyIDs(set)) {
+		t.Errorf("expected key ids %v, got %v", keyIDs(set), actualIDs)
+	}
+
+	tests := []struct {
+		name         string
+		ifNoneMatch  string
+		expectStatus int
+	}{
+		{name: "matching etag", ifNoneMatch: etag, expectStatus: http.StatusNotModified},
+		{name: "weak matching etag", ifNoneMatch: "W/" + etag, expectStatus: http.StatusNotModified},
+		{name: "etag in list", ifNoneMatch: `"other", ` + etag, expectStatus: http.StatusNotModified},
+		{name: "wildcard", ifNoneMatch: "*", expectStatus: http.StatusNotModified},
+		{name: "stale etag", ifNoneMatch: `"other"`, expectStatus: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(router, JWKSPath(), http.Header{"If-None-Match": {test.ifNoneMatch}})
+
+			if rec.Code != test.expectStatus {
+				t.Errorf("expected status %d, got %d", test.expectStatus, rec.Code)
+			}
+
+			if test.expectStatus == http.StatusNotModified && rec.Body.Len() > 0 {
+				t.Errorf("expected empty body, got: %s", rec.Body.String())
+			}
+
+			if actual := rec.Header().Get("ETag"); actual != etag {
+				t.Errorf("expected etag '%s', got '%s'", etag, actual)
+			}
+		})
+	}
+
+	t.Run("etag follows key set", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "key.pem")
+		writeKeyFile(t, filePath)
+
+		if _, err := set.Rebuild(secret.ConfigKeyring{fileKey(filePath, secret.KeyStateActive)}); err != nil {
+			t.Fatalf("failed to rebuild: %s", err)
+		}
+
+		rec := serve(router, JWKSPath(), http.Header{"If-None-Match": {etag}})
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		if rec.Header().Get("ETag") == etag {
+			t.Error("expected etag to change along with the key set")
+		}
+	})
+}
+
+func TestWellKnownDiscovery(t *testing.T) {
+	rsaKey := secret.ConfigKey{Config: secret.DefaultConfig(), State: secret.KeyStateActive}
+
+	set := newTestKeySet(t, secret.ConfigKeyring{rsaKey, generatedKey(secret.KeyStateVerify), generatedKey(secret.KeyStateVerify)})
+
+	discovery := DefaultDiscoveryConfig()
+	discovery.Issuer = "test-issuer"
+	discovery.ResolveURLs("https", "auth.example.com:8001", "https://auth.example.com:8000/v1/token/user")
+
+	rec := serve(newTestRouter(t, set, discovery), pathWellKnownDiscovery, nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	if rec.Header().Get("ETag") == "" {
+		t.Error("expected an etag")
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"issuer":         "test-issuer",
+		"jwks_uri":       "https://auth.example.com:8001/.well-known/jwks.json",
+		"token_endpoint": "https://auth.example.com:8000/v1/token/user",
+	}
+
+	for k, v := range expected {
+		if body[k] != v {
+			t.Errorf("expected '%s' = '%v', got '%v'", k, v, body[k])
+		}
+	}
+
+	var resp discoveryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response body: %s", err)
+	}
+
+	// Signing algorithms follow the key set, deduplicated
+	expectedAlgs := []string{jwa.RS256.String(), jwa.ES256.String()}
+	if !equalStrings(resp.SigningAlgorithms, expectedAlgs) {
+		t.Errorf("expected signing algorithms %v, got %v", expectedAlgs, resp.SigningAlgorithms)
+	}
+
+	if !equalStrings(resp.ResponseTypes, []string{"token"}) || !equalStrings(resp.SubjectTypes, []string{"public"}) {
+		t.Errorf("unexpected response or subject types: %v, %v", resp.ResponseTypes, resp.SubjectTypes)
+	}
+
+	if len(resp.ClaimsSupported) == 0 {
+		t.Error("expected supported claims")
+	}
+}
+
+func TestResolveURLs(t *testing.T) {
+	t.Run("unconfigured", func(t *testing.T) {
+		var cd ConfigDiscovery
+		cd.ResolveURLs("http", "localhost:8001", "http://localhost:8000/v1/token/user")
+
+		if cd.JWKSURI != "http://localhost:8001/.well-known/jwks.json" {
+			t.Errorf("unexpected jwks uri '%s'", cd.JWKSURI)
+		}
+
+		if cd.TokenEndpoint != "http://localhost:8000/v1/token/user" {
+			t.Errorf("unexpected token endpoint '%s'", cd.TokenEndpoint)
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		cd := ConfigDiscovery{JWKSURI: "https://keys.example.com/jwks.json", TokenEndpoint: "https://auth.example.com/token"}
+		cd.ResolveURLs("http", "localhost:8001", "http://localhost:8000/v1/token/user")
+
+		if cd.JWKSURI != "https://keys.example.com/jwks.json" || cd.TokenEndpoint != "https://auth.example.com/token" {
+			t.Errorf("expected configured urls kept, got '%s' and '%s'", cd.JWKSURI, cd.TokenEndpoint)
+		}
+	})
+}
@@ -1,27 +1,92 @@
 package httpsvc
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
 	"github.com/oligarch316/go-skeleton/pkg/observ"
 	"go.uber.org/zap"
 )
 
+// Error codes TODO
+const (
+	CodeBadRequest      = "bad_request"
+	CodeUnauthenticated = "unauthenticated"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeInvalidID       = "invalid_id"
+	CodeTooManyRequests = "too_many_requests"
+	CodeInternal        = "internal"
+	CodePasswordPolicy  = "password_policy"
+)
+
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	}
+
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
 // Error TODO
 type Error struct {
 	Status  int
+	Code    string
 	Message string
+
+	// Optional, per field details (e.g. request body validation)
+	Fields map[string]string
+
 	error
 }
 
 // Unwrap TODO
 func (e Error) Unwrap() error { return e.error }
 
+// WithCode TODO
+func (e Error) WithCode(code string) Error {
+	e.Code = code
+	return e
+}
+
+// WithField TODO
+func (e Error) WithField(name, detail string) Error {
+	fields := make(map[string]string, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+
+	fields[name] = detail
+	e.Fields = fields
+	return e
+}
+
 // NewError TODO
 func NewError(status int, err error, format string, a ...interface{}) Error {
 	return Error{
 		Status:  status,
+		Code:    statusCode(status),
 		Message: fmt.Sprintf(format, a...),
 		error:   err,
 	}
@@ -46,12 +111,32 @@ func URLParamError(paramName string) Error {
 func StoreError(err error, format string, a ...interface{}) Error {
 	var status int
 
-	// TODO: Determine appropriate status code
-	status = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, storeerr.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, storeerr.ErrConflict), errors.Is(err, storeerr.ErrConstraint):
+		status = http.StatusConflict
+	case errors.Is(err, storeerr.ErrInvalidID):
+		return NewError(http.StatusBadRequest, err, format, a...).WithCode(CodeInvalidID)
+	default:
+		status = http.StatusInternalServerError
+	}
 
 	return NewError(status, err, format, a...)
 }
 
+// PasswordPolicyError TODO
+func PasswordPolicyError(err error) Error {
+	res := NewError(http.StatusBadRequest, err, "password does not meet policy").WithCode(CodePasswordPolicy)
+
+	var policyErr password.PolicyError
+	if errors.As(err, &policyErr) {
+		res = res.WithField("violations", strings.Join(policyErr.Violations, "; "))
+	}
+
+	return res
+}
+
 // Servelet TODO
 type Servelet struct{ *observ.Corelet }
 
@@ -63,15 +148,19 @@ func (s *Servelet) Named(name string) *Servelet {
 // HandleErr TODO
 func (s *Servelet) HandleErr(w http.ResponseWriter, r *http.Request, err Error) {
 	s.logErr(r, err)
-	s.writeErr(w, err)
+	s.writeErr(w, r, err)
 }
 
 func (s *Servelet) logErr(r *http.Request, err Error) {
-	var logFunc func(string, ...zap.Field)
+	var (
+		logger  = s.RequestLogger(r)
+		logFunc func(string, ...zap.Field)
+	)
+
 	if err.Status >= http.StatusInternalServerError {
-		logFunc = s.Logger.Error
+		logFunc = logger.Error
 	} else {
-		logFunc = s.Logger.Debug
+		logFunc = logger.Debug
 	}
 
 	logFunc(err.Message,
@@ -83,8 +172,43 @@ func (s *Servelet) logErr(r *http.Request, err Error) {
 	)
 }
 
-func (s *Servelet) writeErr(w http.ResponseWriter, err Error) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+func (s *Servelet) writeErr(w http.ResponseWriter, r *http.Request, err Error) {
+	var (
+		body      interface{}
+		cType     = negotiateErrContentType(r)
+		requestID = RequestID(r.Context())
+	)
+
+	// NOTE: Wrapped error text is kept in logs only, clients see the curated
+	// message, code and fields
+
+	if cType == cTypeProblemJSON {
+		body = problemBody{
+			Type:      "about:blank",
+			Title:     http.StatusText(err.Status),
+			Status:    err.Status,
+			Detail:    err.Message,
+			Code:      err.Code,
+			RequestID: requestID,
+			Fields:    err.Fields,
+		}
+	} else {
+		body = errBody{
+			Code:      err.Code,
+			Message:   err.Message,
+			RequestID: requestID,
+			Fields:    err.Fields,
+		}
+	}
+
+	bytes, mErr := json.Marshal(body)
+	if mErr != nil {
+		// NOTE: Body fields are all strings, so this should never happen
+		s.RequestLogger(r).Error("failed to encode error response", zap.Error(mErr))
+		bytes = []byte(`{"code":"` + CodeInternal + `"}`)
+	}
+
+	w.Header().Set("Content-Type", cType+"; charset=utf-8")
 	w.WriteHeader(err.Status)
-	fmt.Fprintf(w, `{ "message": "%s", "error": "%s" }`, err.Message, err.Error())
+	w.Write(bytes)
 }
@@ -0,0 +1,451 @@
+package httpsvc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NOTE: TLS 1.3 suites are not configurable, these apply to TLS 1.2 only.
+// Only forward secret AEAD suites are permitted, listed in preference order.
+var tlsCipherSuites = []struct {
+	name string
+	id   uint16
+}{
+	{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+	{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+	{"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256", tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305},
+	{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256", tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305},
+}
+
+func lookupCipherSuite(name string) (uint16, bool) {
+	for _, item := range tlsCipherSuites {
+		if item.name == name {
+			return item.id, true
+		}
+	}
+	return 0, false
+}
+
+var tlsClientAuthModes = map[string]tls.ClientAuthType{
+	"none":    tls.NoClientCert,
+	"request": tls.VerifyClientCertIfGiven,
+	"require": tls.RequireAndVerifyClientCert,
+}
+
+// ConfigTLS TODO
+type ConfigTLS struct {
+	Enabled bool `json:"enabled"`
+
+	Certificate secret.SourceConfig `json:"certificate"`
+	Key         secret.SourceConfig `json:"key"`
+
+	MinVersion   string   `json:"minVersion"`
+	CipherSuites []string `json:"cipherSuites"`
+
+	ClientAuth string              `json:"clientAuth"`
+	ClientCA   secret.SourceConfig `json:"clientCA"`
+
+	ReloadInterval ctype.Duration `json:"reloadInterval"`
+}
+
+// DefaultTLSConfig TODO
+func DefaultTLSConfig() ConfigTLS {
+	return ConfigTLS{
+		Certificate:    secret.SourceConfig{Source: secret.Generate("EC")},
+		MinVersion:     "1.2",
+		ClientAuth:     "none",
+		ReloadInterval: ctype.Duration{Duration: time.Minute},
+	}
+}
+
+// Scheme TODO
+func (ct ConfigTLS) Scheme() string {
+	if ct.Enabled {
+		return "https"
+	}
+	return "http"
+}
+
+// MarshalLogObject TODO
+func (ct ConfigTLS) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddBool("enabled", ct.Enabled)
+	enc.AddString("minVersion", ct.MinVersion)
+	enc.AddString("clientAuth", ct.ClientAuth)
+	enc.AddDuration("reloadInterval", ct.ReloadInterval.Duration)
+	return enc.AddArray("cipherSuites", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, item := range ct.CipherSuites {
+			arr.AppendString(item)
+		}
+		return nil
+	}))
+}
+
+func (ct ConfigTLS) base() (*tls.Config, error) {
+	res := &tls.Config{
+		NextProtos:               []string{"h2", "http/1.1"},
+		PreferServerCipherSuites: true,
+	}
+
+	var ok bool
+
+	if res.MinVersion, ok = tlsVersions[ct.MinVersion]; !ok {
+		return nil, fmt.Errorf("unsupported minimum version '%s'", ct.MinVersion)
+	}
+
+	if res.ClientAuth, ok = tlsClientAuthModes[ct.ClientAuth]; !ok {
+		return nil, fmt.Errorf("unknown client auth mode '%s'", ct.ClientAuth)
+	}
+
+	for _, name := range ct.CipherSuites {
+		id, ok := lookupCipherSuite(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite '%s'", name)
+		}
+		res.CipherSuites = append(res.CipherSuites, id)
+	}
+
+	if len(res.CipherSuites) < 1 {
+		for _, item := range tlsCipherSuites {
+			res.CipherSuites = append(res.CipherSuites, item.id)
+		}
+	}
+
+	if res.ClientAuth != tls.NoClientCert && ct.ClientCA.Source == nil {
+		return nil, errors.New("client auth requires a client CA")
+	}
+
+	return res, nil
+}
+
+func (ct ConfigTLS) generated() bool {
+	switch ct.Certificate.Source.(type) {
+	case secret.Generate, *secret.Generate:
+		return true
+	}
+	return false
+}
+
+type tlsMaterial struct{ cert, key, clientCA []byte }
+
+func (tm tlsMaterial) equal(other tlsMaterial) bool {
+	return bytes.Equal(tm.cert, other.cert) && bytes.Equal(tm.key, other.key) && bytes.Equal(tm.clientCA, other.clientCA)
+}
+
+func (ct ConfigTLS) material() (res tlsMaterial, err error) {
+	if ct.generated() {
+		if res.cert, res.key, err = generateCertificate(); err != nil {
+			return
+		}
+	} else {
+		if res.cert, err = ct.Certificate.PEM(); err != nil {
+			return res, fmt.Errorf("certificate: %w", err)
+		}
+
+		if res.key, err = ct.Key.PEM(); err != nil {
+			return res, fmt.Errorf("key: %w", err)
+		}
+	}
+
+	if ct.ClientCA.Source != nil {
+		if res.clientCA, err = ct.ClientCA.PEM(); err != nil {
+			return res, fmt.Errorf("client CA: %w", err)
+		}
+	}
+
+	return
+}
+
+// ServerTLS TODO
+type ServerTLS struct {
+	*observ.Corelet
+
+	cfg     ConfigTLS
+	base    *tls.Config
+	current atomic.Value
+	loaded  tlsMaterial
+	stop    chan struct{}
+}
+
+// NewServerTLS TODO
+//
+// Returns nil when TLS is disabled. Certificate, key and client CA are
+// periodically re-read, and picked up by new connections when they change.
+func NewServerTLS(cfg ConfigTLS, corelet *observ.Corelet) (*ServerTLS, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	base, err := cfg.base()
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+
+	res := &ServerTLS{
+		Corelet: corelet,
+		cfg:     cfg,
+		base:    base,
+		stop:    make(chan struct{}),
+	}
+
+	if err := res.reload(); err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+
+	// Generated certificates are ephemeral, nothing to reload
+	if !cfg.generated() && cfg.ReloadInterval.Duration > 0 {
+		go res.watch(cfg.ReloadInterval.Duration)
+	}
+
+	return res, nil
+}
+
+func (st *ServerTLS) reload() error {
+	material, err := st.cfg.material()
+	if err != nil {
+		return err
+	}
+
+	if st.current.Load() != nil && material.equal(st.loaded) {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(material.cert, material.key)
+	if err != nil {
+		return err
+	}
+
+	next := st.base.Clone()
+	next.Certificates = []tls.Certificate{cert}
+
+	if material.clientCA != nil {
+		next.ClientCAs = x509.NewCertPool()
+		if !next.ClientCAs.AppendCertsFromPEM(material.clientCA) {
+			return errors.New("client CA: no certificates found")
+		}
+	}
+
+	st.loaded = material
+	st.current.Store(next)
+	return nil
+}
+
+func (st *ServerTLS) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-st.stop:
+			return
+		case <-ticker.C:
+			prev := st.current.Load()
+
+			if err := st.reload(); err != nil {
+				st.Logger.Error("failed to reload tls certificate", zap.Error(err))
+				st.Emitter.Count("reload_failures", 1)
+				continue
+			}
+
+			if st.current.Load() != prev {
+				st.Logger.Info("reloaded tls certificate")
+				st.Emitter.Count("reloads", 1)
+			}
+		}
+	}
+}
+
+// Config TODO
+func (st *ServerTLS) Config() *tls.Config {
+	if st == nil {
+		return nil
+	}
+
+	current := func() *tls.Config { return st.current.Load().(*tls.Config) }
+
+	res := st.base.Clone()
+	res.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return &current().Certificates[0], nil
+	}
+	res.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return current(), nil
+	}
+
+	return res
+}
+
+// Close TODO
+func (st *ServerTLS) Close() error {
+	if st != nil {
+		close(st.stop)
+	}
+	return nil
+}
+
+func generateCertificate() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// ErrPinnedKeyMismatch TODO
+var ErrPinnedKeyMismatch = errors.New("server public key does not match any pinned key")
+
+// ConfigClientTLS TODO
+type ConfigClientTLS struct {
+	Enabled bool `json:"enabled"`
+
+	// Optional, system roots are used when unset
+	CA secret.SourceConfig `json:"ca"`
+
+	// Optional, presented for mutual TLS when set
+	Certificate secret.SourceConfig `json:"certificate"`
+	Key         secret.SourceConfig `json:"key"`
+
+	ServerName string `json:"serverName"`
+
+	// Base64 encoded SHA-256 digests of accepted server public keys (SPKI)
+	PinnedKeys []string `json:"pinnedKeys"`
+}
+
+// MarshalLogObject TODO
+func (cct ConfigClientTLS) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddBool("enabled", cct.Enabled)
+	enc.AddBool("customCA", cct.CA.Source != nil)
+	enc.AddBool("clientCertificate", cct.Certificate.Source != nil)
+	enc.AddString("serverName", cct.ServerName)
+	enc.AddInt("pinnedKeys", len(cct.PinnedKeys))
+	return nil
+}
+
+// Build TODO
+//
+// Returns nil when TLS is disabled.
+func (cct ConfigClientTLS) Build() (*tls.Config, error) {
+	if !cct.Enabled {
+		return nil, nil
+	}
+
+	res := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cct.ServerName,
+	}
+
+	if cct.CA.Source != nil {
+		caPEM, err := cct.CA.PEM()
+		if err != nil {
+			return nil, fmt.Errorf("ca: %w", err)
+		}
+
+		res.RootCAs = x509.NewCertPool()
+		if !res.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("ca: no certificates found")
+		}
+	}
+
+	if cct.Certificate.Source != nil {
+		certPEM, err := cct.Certificate.PEM()
+		if err != nil {
+			return nil, fmt.Errorf("certificate: %w", err)
+		}
+
+		keyPEM, err := cct.Key.PEM()
+		if err != nil {
+			return nil, fmt.Errorf("key: %w", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(cct.PinnedKeys) > 0 {
+		pins := make(map[string]bool, len(cct.PinnedKeys))
+		for _, item := range cct.PinnedKeys {
+			pins[item] = true
+		}
+
+		res.VerifyPeerCertificate = verifyPinnedKeys(pins)
+	}
+
+	return res, nil
+}
+
+// NOTE: Runs after standard chain verification. Only certificates within a
+// verified chain count, the raw presented certificates may include extras
+// that chain to nothing
+func verifyPinnedKeys(pins map[string]bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if pins[base64.StdEncoding.EncodeToString(sum[:])] {
+					return nil
+				}
+			}
+		}
+
+		return ErrPinnedKeyMismatch
+	}
+}
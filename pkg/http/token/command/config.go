@@ -1,7 +1,9 @@
 package command
 
 import (
+	"github.com/oligarch316/go-auth-service/pkg/http"
 	"github.com/oligarch316/go-auth-service/pkg/http/token"
+	"github.com/oligarch316/go-auth-service/pkg/password"
 	"github.com/oligarch316/go-auth-service/pkg/secret"
 	"github.com/oligarch316/go-auth-service/pkg/store"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
@@ -12,28 +14,34 @@ import (
 // Config TODO.
 type Config struct {
 	token.ConfigServer
-	Secret secret.Config `json:"secret"`
+	Keys secret.ConfigKeyring `json:"keys"`
 }
 
 // DefaultConfig TODO.
 func DefaultConfig() Config {
 	return Config{
 		ConfigServer: token.DefaultServerConfig(),
-		Secret:       secret.DefaultConfig(),
+		Keys:         secret.DefaultKeyringConfig(),
 	}
 }
 
 type cmdConfig struct {
-	Address  string        `json:"address"`
-	DB       store.Config  `json:"db"`
-	Observ   observ.Config `json:"observ"`
-	TokenSvc Config        `json:"tokensvc"`
+	Address  string               `json:"address"`
+	HTTP     httpsvc.ConfigServer `json:"http"`
+	TLS      httpsvc.ConfigTLS    `json:"tls"`
+	DB       store.Config         `json:"db"`
+	Password password.Config      `json:"password"`
+	Observ   observ.Config        `json:"observ"`
+	TokenSvc Config               `json:"tokensvc"`
 }
 
 func defaultCmdConfig() cmdConfig {
 	return cmdConfig{
 		Address:  token.DefaultAddress,
+		HTTP:     httpsvc.DefaultServerConfig(),
+		TLS:      httpsvc.DefaultTLSConfig(),
 		DB:       store.DefaultConfig(),
+		Password: password.DefaultConfig(),
 		Observ:   observ.DefaultConfig(),
 		TokenSvc: DefaultConfig(),
 	}
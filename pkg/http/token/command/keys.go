@@ -0,0 +1,47 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
+	"github.com/spf13/cobra"
+)
+
+// NewKeys TODO.
+func NewKeys(name string, ns *namespace.NS) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: "Show configured signing keys and their states",
+		Long:  "Show configured signing keys and their states",
+		Run:   func(_ *cobra.Command, _ []string) { os.Exit(runKeys(ns)) },
+	}
+}
+
+func runKeys(ns *namespace.NS) int {
+	cfg := defaultCmdConfig()
+
+	if _, err := ns.LoadAndRecord(&cfg); err != nil {
+		log.Printf("[bootstrap] failed to load configuration: %s\n", err)
+		return 1
+	}
+
+	for _, item := range cfg.TokenSvc.Keys {
+		key, err := item.PublicKey()
+		if err != nil {
+			log.Printf("[keys] failed to load public key: %s\n", err)
+			return 1
+		}
+
+		fmt.Printf("%-8s %-6s %s\n", item.State, key.Algorithm(), key.KeyID())
+	}
+
+	// Confirm the keyring is usable for signing
+	if _, _, err := cfg.TokenSvc.Keys.PrivateKeys(); err != nil {
+		log.Printf("[keys] %s\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -3,11 +3,12 @@ package command
 import (
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
 	"github.com/oligarch316/go-auth-service/pkg/http"
+	secrettoken "github.com/oligarch316/go-auth-service/pkg/http/secret/token"
 	httptoken "github.com/oligarch316/go-auth-service/pkg/http/token"
+	"github.com/oligarch316/go-auth-service/pkg/password"
 	"github.com/oligarch316/go-auth-service/pkg/secret/token"
 	"github.com/oligarch316/go-auth-service/pkg/store"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
@@ -27,22 +28,48 @@ func New(name string, ns *namespace.NS) *cobra.Command {
 }
 
 // NewServer TODO.
-func NewServer(cfg Config, srvlet *httpsvc.Servelet, db store.Backend) (*httptoken.Server, error) {
-	secretKey, err := cfg.Secret.PrivateKey()
+func NewServer(cfg Config, srvlet *httpsvc.Servelet, db store.Backend, passwords *password.Registry) (*httptoken.Server, error) {
+	activeKey, verifyKeys, err := cfg.Keys.PrivateKeys()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load secret key: %w", err)
+		return nil, fmt.Errorf("failed to load secret keys: %w", err)
 	}
 
-	signer, err := token.NewSigner(secretKey)
+	keyring, err := token.NewKeyring(activeKey, verifyKeys...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create token signer: %w", err)
+		return nil, fmt.Errorf("failed to create token keyring: %w", err)
+	}
+
+	srvlet.Logger.Info(
+		"created token keyring",
+		zap.String("activeKeyID", keyring.KeyID()),
+		zap.Strings("keyIDs", keyring.KeyIDs()),
+	)
+
+	revocations, err := secrettoken.LoadRevocationList(cfg.Revocations, httptoken.StoreRevocations(db), srvlet.Corelet.Named("revocations"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load token revocations: %w", err)
+	}
+
+	notifier, err := cfg.Reset.Notifier.Build(srvlet.Corelet.Named("notifier").Logger)
+	if err != nil {
+		revocations.Close()
+		return nil, fmt.Errorf("failed to create reset notifier: %w", err)
+	}
+
+	if notifier == nil {
+		srvlet.Logger.Warn("no reset notifier configured, password reset disabled")
+	} else {
+		srvlet.Logger.Info("created reset notifier", zap.Object("config", cfg.Reset.Notifier))
 	}
 
 	return &httptoken.Server{
 		ConfigServer: cfg.ConfigServer,
 		Servelet:     srvlet,
-		Secret:       signer,
+		Secret:       keyring,
 		Store:        db,
+		Revocations:  revocations,
+		Passwords:    passwords,
+		Notifier:     notifier,
 	}, nil
 }
 
@@ -69,14 +96,27 @@ func run(ns *namespace.NS) int {
 	root.Logger.Info("loaded configuration", zap.Strings("sources", srcRecord))
 	root.Logger.Info("created observability core", zap.Object("config", cfg.Observ))
 
+	// ----- Passwords
+	passwords, err := cfg.Password.Build()
+	if err != nil {
+		root.Logger.Error("failed to create password hashers", zap.Error(err))
+		return 1
+	}
+
+	root.Logger.Info("created password hashers", zap.Object("config", cfg.Password))
+
 	// ----- Database
-	db, err := cfg.DB.Build(observCore.Named("database"))
+	db, err := cfg.DB.Build(passwords, observCore.Named("database"))
 	if err != nil {
 		root.Logger.Error("failed to create database", zap.Error(err))
 		return 1
 	}
 
-	defer db.Close()
+	defer func() {
+		if err := db.Close(); err != nil {
+			root.Logger.Error("failed to close database", zap.Error(err))
+		}
+	}()
 
 	root.Logger.Info("created database", zap.Object("config", cfg.DB))
 
@@ -86,29 +126,47 @@ func run(ns *namespace.NS) int {
 		router   = httpsvc.NewRouter(servelet, "/")
 	)
 
-	server, err := NewServer(cfg.TokenSvc, servelet.Named("token"), db)
+	server, err := NewServer(cfg.TokenSvc, servelet.Named("token"), db, passwords)
 	if err != nil {
 		root.Logger.Error("failed to create server", zap.Error(err))
 		return 1
 	}
 
+	defer server.Revocations.Close()
+
+	stopPrune := server.Prune()
+	defer stopPrune()
+
 	server.AddRoutes(router)
 	router.AddMetaRoutes()
 
+	// ----- TLS
+	serverTLS, err := httpsvc.NewServerTLS(cfg.TLS, observCore.Named("tls"))
+	if err != nil {
+		root.Logger.Error("failed to create tls configuration", zap.Error(err))
+		return 1
+	}
+
+	defer serverTLS.Close()
+
+	if serverTLS == nil {
+		root.Logger.Warn("tls disabled, serving plaintext http")
+	}
+
 	// ----- Run
 	root.Logger.Info(
 		"starting server",
 		zap.String("address", cfg.Address),
+		zap.Object("http", cfg.HTTP),
+		zap.Object("tls", cfg.TLS),
 		zap.Array("routes", router),
 	)
 
-	// TODO: Signal handlers
-	// TODO: TLS only
-
-	if err := http.ListenAndServe(cfg.Address, router); err != http.ErrServerClosed {
+	if err := cfg.HTTP.ListenAndServe(cfg.Address, router, serverTLS.Config(), root.Logger); err != nil {
 		root.Logger.Error("server failure", zap.Error(err))
 		return 1
 	}
 
+	root.Logger.Info("server stopped")
 	return 0
 }
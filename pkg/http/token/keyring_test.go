@@ -0,0 +1,146 @@
+package token
+
+import (
+	"crypto/elliptic"
+	"net/http"
+	"testing"
+
+	"github.com/knq/pemutil"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	secrettoken "github.com/oligarch316/go-auth-service/pkg/secret/token"
+)
+
+// Inline key config, so every deployment step sees the same key material
+func testKeyConfig(t *testing.T, state secret.KeyState) secret.ConfigKey {
+	t.Helper()
+
+	store, err := pemutil.GenerateECKeySet(elliptic.P256())
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	data, err := store.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode key: %s", err)
+	}
+
+	return secret.ConfigKey{
+		Config: secret.Config{Algorithm: jwa.ES256, Source: secret.Inline(data)},
+		State:  state,
+	}
+}
+
+func withState(key secret.ConfigKey, state secret.KeyState) secret.ConfigKey {
+	key.State = state
+	return key
+}
+
+// deploy swaps in the keyring a token server would build from cfg
+func (te *testEnv) deploy(t *testing.T, cfg secret.ConfigKeyring) *secrettoken.Keyring {
+	t.Helper()
+
+	active, verify, err := cfg.PrivateKeys()
+	if err != nil {
+		t.Fatalf("failed to load keyring: %s", err)
+	}
+
+	res, err := secrettoken.NewKeyring(active, verify...)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %s", err)
+	}
+
+	te.Secret = res
+
+	// Handlers capture their validaters on registration, as across a restart
+	te.router = httpsvc.NewRouter(te.Servelet, "/")
+	te.AddRoutes(te.router)
+
+	return res
+}
+
+func tokenKeyID(t *testing.T, tokenStr string) string {
+	t.Helper()
+
+	res, err := secrettoken.KeyID(tokenStr)
+	if err != nil {
+		t.Fatalf("failed to read token key id: %s", err)
+	}
+
+	return res
+}
+
+func TestKeyRotation(t *testing.T) {
+	var (
+		te   = newTestEnv(t)
+		prev = testKeyConfig(t, secret.KeyStateActive)
+		next = testKeyConfig(t, secret.KeyStateVerify)
+	)
+
+	te.createUser(t, "alice", "alice password")
+
+	// Before => signed by the previous key
+	prevRing := te.deploy(t, secret.ConfigKeyring{prev})
+	prevToken := te.login(t, "alice", "alice password").Token
+
+	if actual := tokenKeyID(t, prevToken); actual != prevRing.KeyID() {
+		t.Fatalf("expected key id '%s', got '%s'", prevRing.KeyID(), actual)
+	}
+
+	// Step 1, next key known for verification only => still signed by previous
+	te.deploy(t, secret.ConfigKeyring{prev, next})
+
+	if actual := tokenKeyID(t, te.login(t, "alice", "alice password").Token); actual != prevRing.KeyID() {
+		t.Errorf("expected previous key id '%s' while next is verify only, got '%s'", prevRing.KeyID(), actual)
+	}
+
+	// Step 2, promote next and demote previous => tokens of either validate
+	nextRing := te.deploy(t, secret.ConfigKeyring{withState(prev, secret.KeyStateVerify), withState(next, secret.KeyStateActive)})
+	nextToken := te.login(t, "alice", "alice password").Token
+
+	if actual := tokenKeyID(t, nextToken); actual != nextRing.KeyID() {
+		t.Errorf("expected next key id '%s', got '%s'", nextRing.KeyID(), actual)
+	}
+
+	expectStatus(t, te.do(t, http.MethodGet, UserPath(), nil, prevToken), http.StatusOK)
+	expectStatus(t, te.do(t, http.MethodGet, UserPath(), nil, nextToken), http.StatusOK)
+
+	// Step 3, retire previous => its tokens no longer validate
+	te.deploy(t, secret.ConfigKeyring{withState(prev, secret.KeyStateRetired), withState(next, secret.KeyStateActive)})
+
+	expectStatus(t, te.do(t, http.MethodGet, UserPath(), nil, prevToken), http.StatusUnauthorized)
+	expectStatus(t, te.do(t, http.MethodGet, UserPath(), nil, nextToken), http.StatusOK)
+}
+
+func TestKeyringConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  secret.ConfigKeyring
+	}{
+		{name: "no active key", cfg: secret.ConfigKeyring{testKeyConfig(t, secret.KeyStateVerify)}},
+		{name: "only retired keys", cfg: secret.ConfigKeyring{testKeyConfig(t, secret.KeyStateRetired)}},
+		{name: "multiple active keys", cfg: secret.ConfigKeyring{testKeyConfig(t, secret.KeyStateActive), testKeyConfig(t, secret.KeyStateActive)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := test.cfg.PrivateKeys(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	t.Run("duplicate key", func(t *testing.T) {
+		key := testKeyConfig(t, secret.KeyStateActive)
+
+		active, verify, err := secret.ConfigKeyring{key, withState(key, secret.KeyStateVerify)}.PrivateKeys()
+		if err != nil {
+			t.Fatalf("failed to load keyring: %s", err)
+		}
+
+		if _, err := secrettoken.NewKeyring(active, verify...); err == nil {
+			t.Error("expected error for duplicate key id")
+		}
+	})
+}
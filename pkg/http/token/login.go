@@ -0,0 +1,395 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	codeLoginThrottled = "login_throttled"
+	codeAccountLocked  = "account_locked"
+)
+
+// ConfigLoginThrottle TODO.
+type ConfigLoginThrottle struct {
+	// Failures before lockout, zero disables throttling entirely
+	MaxFailures int            `json:"maxFailures"`
+	Lockout     ctype.Duration `json:"lockout"`
+
+	// Wait enforced after each failure below MaxFailures, doubling per failure
+	BaseDelay ctype.Duration `json:"baseDelay"`
+	MaxDelay  ctype.Duration `json:"maxDelay"`
+
+	// Failures are forgotten once the latest is this old
+	ResetAfter ctype.Duration `json:"resetAfter"`
+}
+
+// MarshalLogObject TODO.
+func (clt ConfigLoginThrottle) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("maxFailures", clt.MaxFailures)
+	enc.AddDuration("lockout", clt.Lockout.Duration)
+	enc.AddDuration("baseDelay", clt.BaseDelay.Duration)
+	enc.AddDuration("maxDelay", clt.MaxDelay.Duration)
+	enc.AddDuration("resetAfter", clt.ResetAfter.Duration)
+	return nil
+}
+
+func (clt ConfigLoginThrottle) enabled() bool { return clt.MaxFailures > 0 }
+
+func (clt ConfigLoginThrottle) locked(attempt model.LoginAttempt) bool {
+	return attempt.Failures >= clt.MaxFailures
+}
+
+func (clt ConfigLoginThrottle) delay(failures int) time.Duration {
+	if clt.BaseDelay.Duration <= 0 || failures < 1 {
+		return 0
+	}
+
+	res := float64(clt.BaseDelay.Duration) * math.Pow(2, float64(failures-1))
+	if max := float64(clt.MaxDelay.Duration); res > max {
+		return clt.MaxDelay.Duration
+	}
+
+	return time.Duration(res)
+}
+
+// blockedFor reports how much longer attempts are refused after the given
+// failure history, zero when attempts are allowed.
+func (clt ConfigLoginThrottle) blockedFor(attempt model.LoginAttempt, now time.Time) time.Duration {
+	if !clt.enabled() || now.Sub(attempt.LastFailure) >= clt.ResetAfter.Duration {
+		return 0
+	}
+
+	wait := clt.delay(attempt.Failures)
+	if clt.locked(attempt) {
+		wait = clt.Lockout.Duration
+	}
+
+	if res := attempt.LastFailure.Add(wait).Sub(now); res > 0 {
+		return res
+	}
+
+	return 0
+}
+
+// ConfigLogin TODO.
+type ConfigLogin struct {
+	User ConfigLoginThrottle `json:"user"`
+	Addr ConfigLoginThrottle `json:"addr"`
+
+	// Interval between deletions of forgotten login attempts and expired
+	// revocations, zero disables
+	PruneInterval ctype.Duration `json:"pruneInterval"`
+}
+
+// DefaultLoginConfig TODO.
+func DefaultLoginConfig() ConfigLogin {
+	return ConfigLogin{
+		User: ConfigLoginThrottle{
+			MaxFailures: 5,
+			Lockout:     ctype.Duration{Duration: 15 * time.Minute},
+			BaseDelay:   ctype.Duration{Duration: time.Second},
+			MaxDelay:    ctype.Duration{Duration: 30 * time.Second},
+			ResetAfter:  ctype.Duration{Duration: time.Hour},
+		},
+		Addr: ConfigLoginThrottle{
+			MaxFailures: 50,
+			Lockout:     ctype.Duration{Duration: 15 * time.Minute},
+			ResetAfter:  ctype.Duration{Duration: time.Hour},
+		},
+		PruneInterval: ctype.Duration{Duration: 10 * time.Minute},
+	}
+}
+
+// MarshalLogObject TODO.
+func (cl ConfigLogin) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddDuration("pruneInterval", cl.PruneInterval.Duration)
+	enc.AddObject("user", cl.User)
+	return enc.AddObject("addr", cl.Addr)
+}
+
+type loginKey struct {
+	key    string
+	config ConfigLoginThrottle
+
+	// Clear failures on success, rather than only releasing the reservation
+	reset bool
+}
+
+// NOTE: Only the connection's remote address is used, forwarding headers are
+// trivially spoofed unless a trusted proxy is known to set them
+func remoteAddr(r *http.Request) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return addr
+}
+
+func (s *Server) loginKeys(r *http.Request, name string) []loginKey {
+	return []loginKey{
+		{key: model.LoginUserKey(name), config: s.Login.User, reset: true},
+		{key: model.LoginAddrKey(remoteAddr(r)), config: s.Login.Addr},
+	}
+}
+
+type loginBlockedError struct {
+	key    string
+	wait   time.Duration
+	locked bool
+}
+
+func (lbe loginBlockedError) Error() string {
+	return fmt.Sprintf("'%s' blocked for %s", lbe.key, lbe.wait)
+}
+
+func (lbe loginBlockedError) retryAfter() string {
+	return strconv.Itoa(int(math.Ceil(lbe.wait.Seconds())))
+}
+
+func (lbe loginBlockedError) httpError() httpsvc.Error {
+	code, msg := codeLoginThrottled, "too many failed login attempts, retry later"
+	if lbe.locked {
+		code, msg = codeAccountLocked, "login temporarily locked after too many failed attempts"
+	}
+
+	return httpsvc.NewError(http.StatusTooManyRequests, lbe, msg).WithCode(code).WithField("retryAfter", lbe.retryAfter())
+}
+
+// loginResetBefore reads the failure history of a single key, refusing the
+// attempt while it is blocked. Otherwise returns the point before which
+// recorded failures are forgotten.
+func (s *Server) loginResetBefore(ctx context.Context, item loginKey, now time.Time) (time.Time, error) {
+	res := now.Add(-item.config.ResetAfter.Duration)
+
+	attempt, err := s.Store.ReadLoginAttempt(ctx, item.key)
+
+	switch {
+	case errors.Is(err, storeerr.ErrNotFound):
+		return res, nil
+	case err != nil:
+		return res, err
+	}
+
+	if wait := item.config.blockedFor(attempt, now); wait > 0 {
+		s.Servelet.Emitter.Increment("login.throttled")
+		return res, loginBlockedError{key: item.key, wait: wait, locked: item.config.locked(attempt)}
+	}
+
+	// Lockout served => the failures behind it are forgotten, this attempt
+	// opens a fresh window
+	// NOTE: Concurrent attempts racing past the same lockout reset only once,
+	// the first to record moves last failure past this point
+	if item.config.locked(attempt) {
+		if served := attempt.LastFailure.Add(time.Nanosecond); served.After(res) {
+			res = served
+		}
+	}
+
+	return res, nil
+}
+
+// NOTE: Attempts are counted as failures before the password is verified, so
+// concurrent requests can't all pass on the same stale count. Blocked attempts
+// are refused before being counted, and a successful login then releases its
+// reservation.
+func (s *Server) reserveLogin(ctx context.Context, keys []loginKey) ([]model.LoginAttempt, error) {
+	var (
+		now = time.Now()
+		res = make([]model.LoginAttempt, len(keys))
+	)
+
+	for i, item := range keys {
+		if !item.config.enabled() {
+			continue
+		}
+
+		resetBefore, err := s.loginResetBefore(ctx, item, now)
+		if err != nil {
+			s.unreserveLogin(ctx, keys[:i])
+			return nil, err
+		}
+
+		attempt, err := s.Store.RecordLoginFailure(ctx, item.key, resetBefore)
+		if err != nil {
+			s.unreserveLogin(ctx, keys[:i])
+			return nil, err
+		}
+
+		// Past the threshold => concurrent attempts used up the allowance
+		if attempt.Failures > item.config.MaxFailures {
+			s.unreserveLogin(ctx, keys[:i+1])
+			s.Servelet.Emitter.Increment("login.throttled")
+			return nil, loginBlockedError{key: item.key, wait: item.config.Lockout.Duration, locked: true}
+		}
+
+		res[i] = attempt
+	}
+
+	return res, nil
+}
+
+func (s *Server) recordLoginFailure(keys []loginKey, attempts []model.LoginAttempt) {
+	s.Servelet.Emitter.Increment("login.failures")
+
+	for i, item := range keys {
+		// Reached the threshold with this failure => newly locked
+		if item.config.enabled() && attempts[i].Failures == item.config.MaxFailures {
+			s.Servelet.Emitter.Increment("login.lockouts")
+			s.Servelet.Logger.Warn("login locked out", zap.String("key", item.key), zap.Duration("lockout", item.config.Lockout.Duration))
+		}
+	}
+}
+
+func (s *Server) releaseLogin(ctx context.Context, keys []loginKey) {
+	for _, item := range keys {
+		if !item.config.enabled() {
+			continue
+		}
+
+		release := s.Store.ReleaseLoginAttempt
+		if item.reset {
+			release = s.Store.DeleteLoginAttempt
+		}
+
+		if err := release(ctx, item.key); err != nil && !errors.Is(err, storeerr.ErrNotFound) {
+			s.Servelet.Logger.Error("failed to release login attempt", zap.String("key", item.key), zap.Error(err))
+		}
+	}
+}
+
+// unreserveLogin takes back reservations of an attempt refused before
+// verification, leaving prior failures untouched.
+func (s *Server) unreserveLogin(ctx context.Context, keys []loginKey) {
+	for _, item := range keys {
+		if !item.config.enabled() {
+			continue
+		}
+
+		if err := s.Store.ReleaseLoginAttempt(ctx, item.key); err != nil && !errors.Is(err, storeerr.ErrNotFound) {
+			s.Servelet.Logger.Error("failed to release login attempt", zap.String("key", item.key), zap.Error(err))
+		}
+	}
+}
+
+func (s *Server) handleLoginErr(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	var blockedErr loginBlockedError
+
+	if errors.As(err, &blockedErr) {
+		w.Header().Set("Retry-After", blockedErr.retryAfter())
+		s.Servelet.HandleErr(w, r, blockedErr.httpError())
+		return
+	}
+
+	s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, msg))
+}
+
+// newDummyHash lazily hashes a random password with the preferred hasher.
+// Unknown names verify against it, costing as much as a wrong password.
+func newDummyHash(passwords *password.Registry, logger *zap.Logger) func() model.PasswordHash {
+	var (
+		once sync.Once
+		res  model.PasswordHash
+	)
+
+	return func() model.PasswordHash {
+		once.Do(func() {
+			pw, err := newTokenID()
+			if err == nil {
+				err = res.Set(passwords, pw)
+			}
+
+			if err != nil {
+				logger.Error("failed to create dummy password hash", zap.Error(err))
+			}
+		})
+
+		return res
+	}
+}
+
+// Prune TODO.
+//
+// Deletes login and reset attempts past every ResetAfter, along with expired
+// revocations, at the configured interval. Both are forgotten anyway. Runs
+// until stop is called.
+func (s *Server) Prune() (stop func()) {
+	var (
+		done     = make(chan struct{})
+		interval = s.Login.PruneInterval.Duration
+		retain   = s.Login.User.ResetAfter.Duration
+	)
+
+	if interval <= 0 {
+		return func() {}
+	}
+
+	for _, item := range []ConfigLoginThrottle{s.Login.Addr, s.Reset.User, s.Reset.Addr} {
+		if item.ResetAfter.Duration > retain {
+			retain = item.ResetAfter.Duration
+		}
+	}
+
+	prune := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+
+		if err := s.Store.PruneLoginAttempts(ctx, time.Now().Add(-retain)); err != nil {
+			s.Servelet.Logger.Warn("failed to prune login attempts", zap.Error(err))
+			s.Servelet.Emitter.Increment("login.prune_failures")
+		}
+
+		if err := s.Store.PruneRevocations(ctx, time.Now()); err != nil {
+			s.Servelet.Logger.Warn("failed to prune revocations", zap.Error(err))
+			s.Servelet.Emitter.Increment("revocation.prune_failures")
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				prune()
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func (s *Server) resetLoginFailures(ctx context.Context, name string) {
+	err := s.Store.DeleteLoginAttempt(ctx, model.LoginUserKey(name))
+	if err != nil && !errors.Is(err, storeerr.ErrNotFound) {
+		s.Servelet.Logger.Error("failed to reset login failures", zap.String("name", name), zap.Error(err))
+	}
+}
+
+func (s *Server) rehashPassword(ctx context.Context, id, password string) {
+	if err := s.Store.UpdateUser(ctx, id, model.UserUpdate{Password: &password}); err != nil {
+		s.Servelet.Logger.Error("failed to rehash password", zap.String("id", id), zap.Error(err))
+		return
+	}
+
+	s.Servelet.Emitter.Increment("password.rehashes")
+}
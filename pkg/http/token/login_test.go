@@ -0,0 +1,388 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+	statsd "gopkg.in/alexcesaro/statsd.v2"
+)
+
+func testServelet(t *testing.T) *httpsvc.Servelet {
+	t.Helper()
+
+	emitter, err := statsd.New(statsd.Mute(true))
+	if err != nil {
+		t.Fatalf("failed to create emitter: %s", err)
+	}
+
+	return &httpsvc.Servelet{Corelet: &observ.Corelet{Logger: zap.NewNop(), Emitter: emitter}}
+}
+
+// fakeLoginStore keeps login attempts in memory, every other Backend method
+// is left unimplemented
+type fakeLoginStore struct {
+	store.Backend
+
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttempt
+}
+
+func newFakeLoginStore() *fakeLoginStore {
+	return &fakeLoginStore{attempts: make(map[string]model.LoginAttempt)}
+}
+
+func (fls *fakeLoginStore) ReadLoginAttempt(_ context.Context, key string) (model.LoginAttempt, error) {
+	fls.mu.Lock()
+	defer fls.mu.Unlock()
+
+	res, ok := fls.attempts[key]
+	if !ok {
+		return res, storeerr.NotFound(errors.New(key))
+	}
+
+	return res, nil
+}
+
+func (fls *fakeLoginStore) RecordLoginFailure(_ context.Context, key string, resetBefore time.Time) (model.LoginAttempt, error) {
+	fls.mu.Lock()
+	defer fls.mu.Unlock()
+
+	res, ok := fls.attempts[key]
+	if !ok || res.LastFailure.Before(resetBefore) {
+		res.Failures = 0
+	}
+
+	res.Key, res.Failures, res.LastFailure = key, res.Failures+1, time.Now()
+	fls.attempts[key] = res
+	return res, nil
+}
+
+func (fls *fakeLoginStore) ReleaseLoginAttempt(_ context.Context, key string) error {
+	fls.mu.Lock()
+	defer fls.mu.Unlock()
+
+	res, ok := fls.attempts[key]
+	if !ok || res.Failures < 1 {
+		return storeerr.NotFound(errors.New(key))
+	}
+
+	res.Failures--
+	fls.attempts[key] = res
+	return nil
+}
+
+func (fls *fakeLoginStore) DeleteLoginAttempt(_ context.Context, key string) error {
+	fls.mu.Lock()
+	defer fls.mu.Unlock()
+
+	if _, ok := fls.attempts[key]; !ok {
+		return storeerr.NotFound(errors.New(key))
+	}
+
+	delete(fls.attempts, key)
+	return nil
+}
+
+// rewind moves a key's latest failure into the past, as if time had passed
+func (fls *fakeLoginStore) rewind(key string, d time.Duration) {
+	fls.mu.Lock()
+	defer fls.mu.Unlock()
+
+	res := fls.attempts[key]
+	res.LastFailure = res.LastFailure.Add(-d)
+	fls.attempts[key] = res
+}
+
+func (fls *fakeLoginStore) failures(key string) int {
+	fls.mu.Lock()
+	defer fls.mu.Unlock()
+
+	return fls.attempts[key].Failures
+}
+
+func testLoginConfig() ConfigLogin {
+	return ConfigLogin{
+		User: ConfigLoginThrottle{
+			MaxFailures: 3,
+			Lockout:     ctype.Duration{Duration: time.Minute},
+			ResetAfter:  ctype.Duration{Duration: time.Hour},
+		},
+		Addr: ConfigLoginThrottle{
+			MaxFailures: 5,
+			Lockout:     ctype.Duration{Duration: time.Minute},
+			ResetAfter:  ctype.Duration{Duration: time.Hour},
+		},
+	}
+}
+
+func newTestLoginServer(t *testing.T, db *fakeLoginStore, cfg ConfigLogin) *Server {
+	return &Server{
+		ConfigServer: ConfigServer{Login: cfg},
+		Servelet:     testServelet(t),
+		Store:        db,
+	}
+}
+
+func testLoginKeys(s *Server, name, addr string) []loginKey {
+	return []loginKey{
+		{key: model.LoginUserKey(name), config: s.Login.User, reset: true},
+		{key: model.LoginAddrKey(addr), config: s.Login.Addr},
+	}
+}
+
+// attempt reserves a login and, unless it succeeds, records the failure
+func attemptLogin(s *Server, keys []loginKey, succeed bool) error {
+	ctx := context.Background()
+
+	attempts, err := s.reserveLogin(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	if succeed {
+		s.releaseLogin(ctx, keys)
+	} else {
+		s.recordLoginFailure(keys, attempts)
+	}
+
+	return nil
+}
+
+func expectBlocked(t *testing.T, err error, locked bool) {
+	t.Helper()
+
+	var blockedErr loginBlockedError
+
+	switch {
+	case !errors.As(err, &blockedErr):
+		t.Fatalf("expected blocked attempt, got: %v", err)
+	case blockedErr.locked != locked:
+		t.Errorf("expected locked %t, got %t", locked, blockedErr.locked)
+	}
+}
+
+func TestReserveLogin(t *testing.T) {
+	var (
+		userKey = model.LoginUserKey("alice")
+		addrKey = model.LoginAddrKey("192.0.2.1")
+	)
+
+	tests := []struct {
+		name string
+		run  func(*testing.T, *Server, *fakeLoginStore)
+	}{
+		{
+			name: "lock after max failures",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				keys := testLoginKeys(s, "alice", "192.0.2.1")
+
+				for i := 0; i < 3; i++ {
+					if err := attemptLogin(s, keys, false); err != nil {
+						t.Fatalf("attempt %d: %s", i+1, err)
+					}
+				}
+
+				expectBlocked(t, attemptLogin(s, keys, true), true)
+
+				// Refused attempts are neither counted nor extend the lockout
+				if actual := db.failures(userKey); actual != 3 {
+					t.Errorf("expected 3 user failures, got %d", actual)
+				}
+
+				if actual := db.failures(addrKey); actual != 3 {
+					t.Errorf("expected 3 addr failures, got %d", actual)
+				}
+			},
+		},
+		{
+			name: "lockout expires",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				keys := testLoginKeys(s, "alice", "192.0.2.1")
+
+				for i := 0; i < 3; i++ {
+					attemptLogin(s, keys, false)
+				}
+
+				expectBlocked(t, attemptLogin(s, keys, false), true)
+
+				// Lockout served => a fresh window, not a renewed lockout
+				db.rewind(userKey, time.Minute+time.Second)
+
+				if err := attemptLogin(s, keys, false); err != nil {
+					t.Fatalf("attempt after lockout: %s", err)
+				}
+
+				if actual := db.failures(userKey); actual != 1 {
+					t.Errorf("expected failures to restart at 1, got %d", actual)
+				}
+
+				if err := attemptLogin(s, keys, true); err != nil {
+					t.Errorf("second attempt after lockout: %s", err)
+				}
+			},
+		},
+		{
+			name: "probes during lockout don't extend it",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				keys := testLoginKeys(s, "alice", "192.0.2.1")
+
+				for i := 0; i < 3; i++ {
+					attemptLogin(s, keys, false)
+				}
+
+				db.rewind(userKey, 30*time.Second)
+				expectBlocked(t, attemptLogin(s, keys, false), true)
+
+				db.rewind(userKey, 31*time.Second)
+				if err := attemptLogin(s, keys, true); err != nil {
+					t.Errorf("attempt after lockout: %s", err)
+				}
+			},
+		},
+		{
+			name: "success releases reservation",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				keys := testLoginKeys(s, "alice", "192.0.2.1")
+
+				attemptLogin(s, keys, false)
+				attemptLogin(s, keys, false)
+
+				if err := attemptLogin(s, keys, true); err != nil {
+					t.Fatalf("successful attempt: %s", err)
+				}
+
+				// User failures cleared, address failures only lose the reservation
+				if _, err := db.ReadLoginAttempt(context.Background(), userKey); !errors.Is(err, storeerr.ErrNotFound) {
+					t.Errorf("expected user failures cleared, got: %v", err)
+				}
+
+				if actual := db.failures(addrKey); actual != 2 {
+					t.Errorf("expected 2 addr failures, got %d", actual)
+				}
+			},
+		},
+		{
+			name: "user lock is per name",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				for i := 0; i < 3; i++ {
+					attemptLogin(s, testLoginKeys(s, "alice", "192.0.2.1"), false)
+				}
+
+				expectBlocked(t, attemptLogin(s, testLoginKeys(s, "alice", "192.0.2.2"), true), true)
+
+				if err := attemptLogin(s, testLoginKeys(s, "bob", "192.0.2.1"), true); err != nil {
+					t.Errorf("other name from same address: %s", err)
+				}
+			},
+		},
+		{
+			name: "addr lock spans names",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				for i := 0; i < 5; i++ {
+					names := []string{"a", "b", "c", "d", "e"}
+					if err := attemptLogin(s, testLoginKeys(s, names[i], "192.0.2.1"), false); err != nil {
+						t.Fatalf("attempt %d: %s", i+1, err)
+					}
+				}
+
+				expectBlocked(t, attemptLogin(s, testLoginKeys(s, "f", "192.0.2.1"), true), true)
+
+				// Refused by address => the user reservation is taken back
+				if actual := db.failures(model.LoginUserKey("f")); actual != 0 {
+					t.Errorf("expected no failures for 'f', got %d", actual)
+				}
+
+				if err := attemptLogin(s, testLoginKeys(s, "f", "192.0.2.2"), true); err != nil {
+					t.Errorf("same name from other address: %s", err)
+				}
+			},
+		},
+		{
+			name: "stale failures reset",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				keys := testLoginKeys(s, "alice", "192.0.2.1")
+
+				attemptLogin(s, keys, false)
+				attemptLogin(s, keys, false)
+				db.rewind(userKey, 2*time.Hour)
+
+				if err := attemptLogin(s, keys, false); err != nil {
+					t.Fatalf("attempt: %s", err)
+				}
+
+				if actual := db.failures(userKey); actual != 1 {
+					t.Errorf("expected failures to restart at 1, got %d", actual)
+				}
+			},
+		},
+		{
+			name: "delay between failures",
+			run: func(t *testing.T, s *Server, db *fakeLoginStore) {
+				s.Login.User.BaseDelay = ctype.Duration{Duration: 10 * time.Second}
+				s.Login.User.MaxDelay = ctype.Duration{Duration: time.Minute}
+				keys := testLoginKeys(s, "alice", "192.0.2.1")
+
+				attemptLogin(s, keys, false)
+				expectBlocked(t, attemptLogin(s, keys, true), false)
+
+				db.rewind(userKey, 11*time.Second)
+				if err := attemptLogin(s, keys, true); err != nil {
+					t.Errorf("attempt after delay: %s", err)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newFakeLoginStore()
+			test.run(t, newTestLoginServer(t, db, testLoginConfig()), db)
+		})
+	}
+}
+
+func TestReserveLoginConcurrent(t *testing.T) {
+	const concurrency = 20
+
+	var (
+		db      = newFakeLoginStore()
+		s       = newTestLoginServer(t, db, testLoginConfig())
+		keys    = testLoginKeys(s, "alice", "192.0.2.1")
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := attemptLogin(s, keys, false); err == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if allowed != 3 {
+		t.Errorf("expected exactly 3 attempts allowed, got %d", allowed)
+	}
+
+	if actual := db.failures(model.LoginUserKey("alice")); actual != 3 {
+		t.Errorf("expected 3 recorded failures, got %d", actual)
+	}
+}
@@ -0,0 +1,53 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+)
+
+const (
+	refreshTokenSize = 32
+	familyIDSize     = 16
+	tokenIDSize      = 16
+)
+
+func randomBytes(size int) ([]byte, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	return buf, err
+}
+
+// NOTE: Only a digest of the opaque refresh token is ever persisted
+func refreshTokenID(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRefreshFamilyID() (string, error) {
+	buf, err := randomBytes(familyIDSize)
+	return hex.EncodeToString(buf), err
+}
+
+func newTokenID() (string, error) {
+	buf, err := randomBytes(tokenIDSize)
+	return base64.RawURLEncoding.EncodeToString(buf), err
+}
+
+func newRefreshToken(ttl time.Duration) (string, model.RefreshToken, error) {
+	buf, err := randomBytes(refreshTokenSize)
+	if err != nil {
+		return "", model.RefreshToken{}, err
+	}
+
+	refreshToken := base64.RawURLEncoding.EncodeToString(buf)
+
+	return refreshToken, model.RefreshToken{
+		ID:         refreshTokenID(refreshToken),
+		Expiration: time.Now().Add(ttl),
+	}, nil
+}
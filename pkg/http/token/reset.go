@@ -0,0 +1,279 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/notify"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	codeResetThrottled = "reset_throttled"
+
+	resetSubject  = "Password reset"
+	resetBodyTmpl = `A password reset was requested for your account.
+
+Reset token: %s
+
+The token is valid until %s and can be used once. If you did not request a
+reset, you can ignore this message.
+`
+)
+
+// ConfigReset TODO.
+type ConfigReset struct {
+	TTL            ctype.Duration  `json:"ttl"`
+	PasswordPolicy password.Policy `json:"passwordPolicy"`
+	Notifier       notify.Config   `json:"notifier"`
+
+	// Every request counts against these, whether or not the name exists
+	User ConfigLoginThrottle `json:"user"`
+	Addr ConfigLoginThrottle `json:"addr"`
+}
+
+// DefaultResetConfig TODO.
+func DefaultResetConfig() ConfigReset {
+	return ConfigReset{
+		TTL:            ctype.Duration{Duration: 15 * time.Minute},
+		PasswordPolicy: password.DefaultPolicy(),
+		Notifier:       notify.DefaultConfig(),
+		User: ConfigLoginThrottle{
+			MaxFailures: 3,
+			Lockout:     ctype.Duration{Duration: time.Hour},
+			ResetAfter:  ctype.Duration{Duration: time.Hour},
+		},
+		Addr: ConfigLoginThrottle{
+			MaxFailures: 20,
+			Lockout:     ctype.Duration{Duration: time.Hour},
+			ResetAfter:  ctype.Duration{Duration: time.Hour},
+		},
+	}
+}
+
+// MarshalLogObject TODO.
+func (cr ConfigReset) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddDuration("ttl", cr.TTL.Duration)
+	enc.AddObject("passwordPolicy", cr.PasswordPolicy)
+	enc.AddObject("notifier", cr.Notifier)
+	enc.AddObject("user", cr.User)
+	return enc.AddObject("addr", cr.Addr)
+}
+
+func (s *Server) resetKeys(r *http.Request, name string) []loginKey {
+	return []loginKey{
+		{key: model.ResetUserKey(name), config: s.Reset.User},
+		{key: model.ResetAddrKey(remoteAddr(r)), config: s.Reset.Addr},
+	}
+}
+
+func (s *Server) handleResetErr(w http.ResponseWriter, r *http.Request, err error) {
+	var blockedErr loginBlockedError
+
+	if errors.As(err, &blockedErr) {
+		w.Header().Set("Retry-After", blockedErr.retryAfter())
+		s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusTooManyRequests, blockedErr, "too many reset requests, retry later").
+			WithCode(codeResetThrottled).
+			WithField("retryAfter", blockedErr.retryAfter()))
+		return
+	}
+
+	s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to check reset attempts"))
+}
+
+// sendReset looks up the named user and delivers a fresh reset token. Unknown
+// names are dropped silently.
+func (s *Server) sendReset(ctx context.Context, logger *zap.Logger, name string, genResetClaims func(string, time.Duration) (token.StandardClaims, error)) {
+	// Lookup user data by name
+	data, err := s.Store.LookupUser(ctx, name)
+
+	switch {
+	case errors.Is(err, storeerr.ErrNotFound):
+		return
+	case err != nil:
+		s.Servelet.Emitter.Increment("reset.errors")
+		logger.Error("failed to lookup user", zap.Error(err))
+		return
+	}
+
+	// Create reset claims
+	claims, err := genResetClaims(data.ID, s.Reset.TTL.Duration)
+	if err != nil {
+		s.Servelet.Emitter.Increment("reset.errors")
+		logger.Error("failed to generate reset token claims", zap.String("userID", data.ID), zap.Error(err))
+		return
+	}
+
+	// Build token from claims
+	resetToken, err := s.Secret.Sign(claims)
+	if err != nil {
+		s.Servelet.Emitter.Increment("reset.errors")
+		logger.Error("failed to sign reset token claims", zap.String("userID", data.ID), zap.Error(err))
+		return
+	}
+
+	// Deliver token to the user, out of band
+	msg := notify.Message{
+		To:      data.Name,
+		Subject: resetSubject,
+		Body:    fmt.Sprintf(resetBodyTmpl, resetToken, claims.Expiration.Time.UTC().Format(time.RFC1123)),
+	}
+
+	if err := s.Notifier.Notify(ctx, msg); err != nil {
+		s.Servelet.Emitter.Increment("reset.errors")
+		logger.Error("failed to send reset notification", zap.String("userID", data.ID), zap.Error(err))
+	}
+}
+
+// HandleResetCreate TODO.
+func (s *Server) HandleResetCreate() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "tokenresetcreate",
+		Description: "create a new password reset token and notify its user",
+		Method:      http.MethodPost,
+		MetricTag:   "token_reset_create",
+	}
+
+	type requestBody struct {
+		Name string `json:"name"`
+	}
+
+	genResetClaims := s.claimsGenFactory(s.AudienceNames.Reset)
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var reqBody requestBody
+
+		// Decode request body
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, err, "failed to load request body"))
+			return
+		}
+
+		s.Servelet.Emitter.Increment("reset.requests")
+
+		// Check and count reset attempts
+		keys := s.resetKeys(r, reqBody.Name)
+
+		if _, err := s.reserveLogin(r.Context(), keys); err != nil {
+			s.handleResetErr(w, r, err)
+			return
+		}
+
+		// Send reset notification
+		// NOTE: Detached from the request, so neither the response nor its
+		// timing reveal which names exist
+		logger := s.Servelet.RequestLogger(r)
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), s.Reset.TTL.Duration)
+			defer cancel()
+
+			s.sendReset(ctx, logger, reqBody.Name, genResetClaims)
+		}()
+
+		// Respond
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Handle: handle}
+}
+
+// HandleResetUpdate TODO.
+func (s *Server) HandleResetUpdate() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "tokenresetupdate",
+		Description: "consume attached reset token and set a new password",
+		Method:      http.MethodPut,
+		MetricTag:   "token_reset_update",
+	}
+
+	type requestBody struct {
+		Password string `json:"password"`
+	}
+
+	valResetClaims := s.validaterFactory(s.AudienceNames.Reset)
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		// Validate reset token
+		claims, err := valResetClaims.ValidateClaims(r)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate reset token"))
+			return
+		}
+
+		if claims.TokenID == "" {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, errors.New("missing jti"), "reset token can not be consumed"))
+			return
+		}
+
+		var reqBody requestBody
+
+		// Decode request body
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, err, "failed to load request body"))
+			return
+		}
+
+		// Check password policy
+		if err := s.Reset.PasswordPolicy.Check(reqBody.Password); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.PasswordPolicyError(err))
+			return
+		}
+
+		// Read user data
+		data, err := s.Store.ReadUser(r.Context(), claims.Subject)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
+			return
+		}
+
+		// Consume reset token
+		// NOTE: Consumed before the update, so concurrent requests presenting
+		// the same token can not both succeed
+		consumed := model.Revocation{
+			TokenID:    claims.TokenID,
+			Expiration: claims.Expiration.Time,
+		}
+
+		if err = s.Store.ConsumeToken(r.Context(), consumed); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to consume reset token"))
+			return
+		}
+
+		if s.Revocations != nil {
+			s.Revocations.Add(revocationFromModel(consumed))
+		}
+
+		// Perform update
+		if err = s.Store.UpdateUser(r.Context(), data.ID, model.UserUpdate{Password: &reqBody.Password}); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to update password"))
+			return
+		}
+
+		// Revoke outstanding tokens
+		if err = s.userRevoker().RevokeUser(r.Context(), data.ID); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to revoke user tokens"))
+			return
+		}
+
+		s.resetLoginFailures(r.Context(), data.Name)
+		s.Servelet.Emitter.Increment("reset.completions")
+
+		// Respond
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Handle: handle}
+}
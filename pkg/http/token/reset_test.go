@@ -0,0 +1,194 @@
+package token
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/notify"
+)
+
+var resetTokenPattern = regexp.MustCompile(`Reset token: (\S+)`)
+
+// fakeNotifier hands every message to the test
+type fakeNotifier chan notify.Message
+
+func (fn fakeNotifier) Notify(_ context.Context, msg notify.Message) error {
+	fn <- msg
+	return nil
+}
+
+func (fn fakeNotifier) expectMessage(t *testing.T) notify.Message {
+	t.Helper()
+
+	select {
+	case msg := <-fn:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+
+	return notify.Message{}
+}
+
+func (fn fakeNotifier) expectNone(t *testing.T) {
+	t.Helper()
+
+	select {
+	case msg := <-fn:
+		t.Errorf("unexpected notification to '%s'", msg.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// withNotifier registers the reset routes, which require a notifier
+func (te *testEnv) withNotifier() fakeNotifier {
+	res := make(fakeNotifier, 10)
+	te.Notifier = res
+
+	te.router = httpsvc.NewRouter(te.Servelet, "/")
+	te.AddRoutes(te.router)
+
+	return res
+}
+
+func (te *testEnv) requestReset(t *testing.T, name string) *httptest.ResponseRecorder {
+	t.Helper()
+	return te.do(t, http.MethodPost, resetPath(), map[string]string{"name": name}, "")
+}
+
+func (te *testEnv) completeReset(t *testing.T, resetToken, pass string) *httptest.ResponseRecorder {
+	t.Helper()
+	return te.do(t, http.MethodPut, resetPath(), map[string]string{"password": pass}, resetToken)
+}
+
+func resetPath() string { return path.Join("/", APIVersion, pathBase, pathReset) }
+
+func extractResetToken(t *testing.T, msg notify.Message) string {
+	t.Helper()
+
+	match := resetTokenPattern.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("no reset token in notification body: %s", msg.Body)
+	}
+
+	return match[1]
+}
+
+func TestReset(t *testing.T) {
+	const (
+		oldPass = "old alice password"
+		newPass = "new alice password"
+	)
+
+	t.Run("flow", func(t *testing.T) {
+		te := newTestEnv(t)
+		notifier := te.withNotifier()
+		te.createUser(t, "alice", oldPass)
+
+		session := te.login(t, "alice", oldPass)
+
+		expectStatus(t, te.requestReset(t, "alice"), http.StatusAccepted)
+
+		msg := notifier.expectMessage(t)
+		if msg.To != "alice" {
+			t.Errorf("expected notification to 'alice', got '%s'", msg.To)
+		}
+
+		resetToken := extractResetToken(t, msg)
+		expectStatus(t, te.completeReset(t, resetToken, newPass), http.StatusNoContent)
+
+		// New password in effect
+		te.login(t, "alice", newPass)
+
+		rec := te.do(t, http.MethodPost, UserPath(), map[string]string{"name": "alice", "password": oldPass}, "")
+		expectStatus(t, rec, http.StatusForbidden)
+
+		// Outstanding refresh tokens revoked
+		expectStatus(t, te.refresh(t, session.RefreshToken), http.StatusUnauthorized)
+
+		// Reset tokens are single use
+		expectStatus(t, te.completeReset(t, resetToken, "another alice password"), http.StatusUnauthorized)
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		te := newTestEnv(t)
+		notifier := te.withNotifier()
+
+		// Indistinguishable from a known name
+		expectStatus(t, te.requestReset(t, "bob"), http.StatusAccepted)
+		notifier.expectNone(t)
+	})
+
+	t.Run("policy violation", func(t *testing.T) {
+		te := newTestEnv(t)
+		notifier := te.withNotifier()
+		te.createUser(t, "alice", oldPass)
+
+		expectStatus(t, te.requestReset(t, "alice"), http.StatusAccepted)
+		resetToken := extractResetToken(t, notifier.expectMessage(t))
+
+		rec := te.completeReset(t, resetToken, "short")
+		expectStatus(t, rec, http.StatusBadRequest)
+		expectCode(t, rec, httpsvc.CodePasswordPolicy)
+
+		// Token not consumed by a rejected attempt
+		expectStatus(t, te.completeReset(t, resetToken, newPass), http.StatusNoContent)
+	})
+
+	t.Run("user token", func(t *testing.T) {
+		te := newTestEnv(t)
+		te.withNotifier()
+		te.createUser(t, "alice", oldPass)
+
+		session := te.login(t, "alice", oldPass)
+
+		rec := te.completeReset(t, session.Token, newPass)
+		expectStatus(t, rec, http.StatusUnauthorized)
+		expectCode(t, rec, httpsvc.CodeUnauthenticated)
+
+		te.login(t, "alice", oldPass)
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		te := newTestEnv(t)
+		te.Reset.User.MaxFailures = 2
+		notifier := te.withNotifier()
+		te.createUser(t, "alice", oldPass)
+
+		for i := 0; i < 2; i++ {
+			expectStatus(t, te.requestReset(t, "alice"), http.StatusAccepted)
+			notifier.expectMessage(t)
+		}
+
+		rec := te.requestReset(t, "alice")
+		expectStatus(t, rec, http.StatusTooManyRequests)
+		expectCode(t, rec, codeResetThrottled)
+
+		if rec.Header().Get("Retry-After") == "" {
+			t.Error("expected a Retry-After header")
+		}
+
+		notifier.expectNone(t)
+
+		// Unknown names count alike
+		for i := 0; i < 2; i++ {
+			expectStatus(t, te.requestReset(t, "bob"), http.StatusAccepted)
+		}
+
+		expectStatus(t, te.requestReset(t, "bob"), http.StatusTooManyRequests)
+	})
+
+	t.Run("no notifier", func(t *testing.T) {
+		te := newTestEnv(t)
+
+		if rec := te.requestReset(t, "alice"); rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected reset routes to be absent, got status %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,308 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/goware/urlx"
+	"github.com/julienschmidt/httprouter"
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"go.uber.org/zap/zapcore"
+)
+
+func revocationFromModel(data model.Revocation) token.Revocation {
+	res := token.Revocation{
+		TokenID:    data.TokenID,
+		Subject:    data.Subject,
+		Expiration: &token.NumericDate{Time: data.Expiration},
+	}
+
+	if !data.IssuedBefore.IsZero() {
+		res.IssuedBefore = &token.NumericDate{Time: data.IssuedBefore}
+	}
+
+	return res
+}
+
+// StoreRevocations TODO.
+func StoreRevocations(store interface {
+	ListRevocations(ctx context.Context) ([]model.Revocation, error)
+}) token.RevocationSource {
+	return token.RevocationSourceFunc(func(ctx context.Context) ([]token.Revocation, error) {
+		list, err := store.ListRevocations(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res := make([]token.Revocation, len(list))
+		for i, item := range list {
+			res[i] = revocationFromModel(item)
+		}
+
+		return res, nil
+	})
+}
+
+// UserRevoker TODO.
+type UserRevoker struct {
+	Store interface {
+		CreateRevocation(ctx context.Context, data model.Revocation) error
+		RevokeUserRefreshTokens(ctx context.Context, userID string) error
+	}
+
+	// Optional, nil skips pushing new revocations to local validaters
+	Revocations interface {
+		Add(items ...token.Revocation)
+	}
+
+	// Must cover the longest lived user token the issuer signs, i.e. its MaxTTL
+	TTL time.Duration
+}
+
+// RevokeSubject TODO.
+//
+// Revokes every user token issued to subject before the current second.
+func (ur UserRevoker) RevokeSubject(ctx context.Context, subject string) error {
+	now := time.Now()
+
+	// NOTE: Token "iat" claims carry whole seconds, a token issued right after
+	// the revocation must not fall on or before it
+	revocation := model.Revocation{
+		Subject:      subject,
+		IssuedBefore: now.Truncate(time.Second),
+		Expiration:   now.Add(ur.TTL),
+	}
+
+	if err := ur.Store.CreateRevocation(ctx, revocation); err != nil {
+		return err
+	}
+
+	if ur.Revocations != nil {
+		ur.Revocations.Add(revocationFromModel(revocation))
+	}
+
+	return nil
+}
+
+// RevokeUser TODO.
+//
+// Revokes every user token issued to userID so far, along with all of its
+// refresh tokens.
+func (ur UserRevoker) RevokeUser(ctx context.Context, userID string) error {
+	if err := ur.RevokeSubject(ctx, userID); err != nil {
+		return err
+	}
+
+	return ur.Store.RevokeUserRefreshTokens(ctx, userID)
+}
+
+func (s *Server) userRevoker() UserRevoker {
+	res := UserRevoker{Store: s.Store, TTL: s.MaxTTL.Duration}
+
+	if s.Revocations != nil {
+		res.Revocations = s.Revocations
+	}
+
+	return res
+}
+
+// HandleRevokeCreate TODO.
+func (s *Server) HandleRevokeCreate() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "tokenrevokecreate",
+		Description: "revoke attached user token and optional refresh token (logout)",
+		Method:      http.MethodPost,
+		MetricTag:   "token_revoke_create",
+	}
+
+	type requestBody struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	valUserClaims := s.validaterFactory(s.AudienceNames.User)
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		// Validate user token
+		claims, err := valUserClaims.ValidateClaims(r)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
+			return
+		}
+
+		if claims.TokenID == "" {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, errors.New("missing jti"), "user token can not be revoked"))
+			return
+		}
+
+		var reqBody requestBody
+
+		// Decode request body (optional)
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil && err != io.EOF {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, err, "failed to load request body"))
+			return
+		}
+
+		// Revoke user token until its natural expiration
+		revocation := model.Revocation{
+			TokenID:    claims.TokenID,
+			Expiration: claims.Expiration.Time,
+		}
+
+		if err := s.Store.CreateRevocation(r.Context(), revocation); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to revoke user token"))
+			return
+		}
+
+		if s.Revocations != nil {
+			s.Revocations.Add(revocationFromModel(revocation))
+		}
+
+		// Revoke refresh token family
+		if reqBody.RefreshToken != "" {
+			err := s.Store.RevokeRefreshToken(r.Context(), refreshTokenID(reqBody.RefreshToken))
+			if err != nil && !errors.Is(err, storeerr.ErrNotFound) {
+				s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to revoke refresh token"))
+				return
+			}
+		}
+
+		// Respond
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Handle: handle}
+}
+
+// HandleRevokeList TODO.
+func (s *Server) HandleRevokeList() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "tokenrevokelist",
+		Description: "list active token revocations (admin or service)",
+		Method:      http.MethodGet,
+		MetricTag:   "token_revoke_list",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceRevocations, Admin: true}
+	source := StoreRevocations(s.Store)
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		// Read active revocations
+		list, err := source.Revocations(r.Context())
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to list revocations"))
+			return
+		}
+
+		// Encode response body
+		bytes, err := json.Marshal(list)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
+			return
+		}
+
+		// Respond
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Handle: handle, Auth: &auth}
+}
+
+// ConfigRevocationClient TODO.
+type ConfigRevocationClient struct {
+	// Optional, empty reads revocations from the database instead
+	Address string                  `json:"address"`
+	Timeout time.Duration           `json:"timeout"`
+	TLS     httpsvc.ConfigClientTLS `json:"tls"`
+}
+
+// DefaultRevocationClientConfig TODO.
+func DefaultRevocationClientConfig() ConfigRevocationClient {
+	return ConfigRevocationClient{Timeout: 10 * time.Second}
+}
+
+// MarshalLogObject TODO.
+func (crc ConfigRevocationClient) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("address", crc.Address)
+	enc.AddDuration("timeout", crc.Timeout)
+	return enc.AddObject("tls", crc.TLS)
+}
+
+// RevocationClient TODO.
+//
+// Reads the revocation feed of a token service, authenticating by client
+// certificate. Suitable as the source of a polled token.RevocationList.
+type RevocationClient struct {
+	client *http.Client
+	url    string
+}
+
+// NewRevocationClient TODO.
+func NewRevocationClient(cfg ConfigRevocationClient) (*RevocationClient, error) {
+	tlsConfig, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tls configuration: %w", err)
+	}
+
+	if tlsConfig == nil || len(tlsConfig.Certificates) < 1 {
+		return nil, errors.New("revocation feed requires a tls client certificate")
+	}
+
+	base, err := urlx.ParseWithDefaultScheme(cfg.Address, "https")
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	if base.Scheme != "https" {
+		return nil, fmt.Errorf("tls enabled for non-https address '%s'", cfg.Address)
+	}
+
+	base.Path = RevokePath()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &RevocationClient{
+		client: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		url:    base.String(),
+	}, nil
+}
+
+// Revocations TODO.
+func (rc *RevocationClient) Revocations(ctx context.Context) ([]token.Revocation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rc.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response code: %d", resp.StatusCode)
+	}
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []token.Revocation
+	return res, json.Unmarshal(respBytes, &res)
+}
@@ -0,0 +1,360 @@
+package token
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	secrettoken "github.com/oligarch316/go-auth-service/pkg/secret/token"
+	"github.com/oligarch316/go-auth-service/pkg/store"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+)
+
+type fakeRevocationStore struct {
+	revocations []model.Revocation
+	revokedIDs  []string
+}
+
+func (frs *fakeRevocationStore) CreateRevocation(_ context.Context, data model.Revocation) error {
+	frs.revocations = append(frs.revocations, data)
+	return nil
+}
+
+func (frs *fakeRevocationStore) RevokeUserRefreshTokens(_ context.Context, userID string) error {
+	frs.revokedIDs = append(frs.revokedIDs, userID)
+	return nil
+}
+
+func TestUserRevokerSameSecond(t *testing.T) {
+	var (
+		db   = new(fakeRevocationStore)
+		list = token.NewRevocationList()
+
+		revoker = UserRevoker{Store: db, Revocations: list, TTL: time.Hour}
+	)
+
+	before := time.Now().Add(-time.Second)
+
+	if err := revoker.RevokeUser(context.Background(), "alice"); err != nil {
+		t.Fatalf("failed to revoke user: %s", err)
+	}
+
+	after := time.Now()
+
+	if len(db.revocations) != 1 || len(db.revokedIDs) != 1 {
+		t.Fatalf("expected one stored revocation and refresh revocation, got %+v, %+v", db.revocations, db.revokedIDs)
+	}
+
+	if stored := db.revocations[0].IssuedBefore; !stored.Equal(stored.Truncate(time.Second)) {
+		t.Errorf("expected whole second issued before, got %s", stored)
+	}
+
+	// iat as a signed token carries it, truncated to the second
+	claims := func(issuedAt time.Time) token.StandardClaims {
+		return token.StandardClaims{Subject: "alice", IssuedAt: &token.NumericDate{Time: issuedAt.Truncate(time.Second)}}
+	}
+
+	if !list.Revoked(claims(before)) {
+		t.Error("token issued before revocation not revoked")
+	}
+
+	if list.Revoked(claims(after)) {
+		t.Error("token issued right after revocation is revoked")
+	}
+}
+
+// fakeFeedStore serves a fixed revocation list and user set, every other
+// Backend method is left unimplemented
+type fakeFeedStore struct {
+	store.Backend
+
+	revocations []model.Revocation
+	users       map[string]model.User
+}
+
+func (ffs *fakeFeedStore) ListRevocations(_ context.Context) ([]model.Revocation, error) {
+	return ffs.revocations, nil
+}
+
+func (ffs *fakeFeedStore) ReadUser(_ context.Context, id string) (model.User, error) {
+	res, ok := ffs.users[id]
+	if !ok {
+		return res, storeerr.NotFound(errors.New(id))
+	}
+
+	return res, nil
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ca key: %s", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create ca certificate: %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse ca certificate: %s", err)
+	}
+
+	return testCA{cert: cert, key: key}
+}
+
+// Client certificate for the given common name, as PEM
+func (tc testCA) issue(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tc.cert, &key.PublicKey, tc.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return
+}
+
+func newTestSigner(t *testing.T) *secrettoken.Signer {
+	t.Helper()
+
+	key, err := secret.DefaultConfig().PrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %s", err)
+	}
+
+	res, err := secrettoken.NewSigner(key)
+	if err != nil {
+		t.Fatalf("failed to create signer: %s", err)
+	}
+
+	return res
+}
+
+func newTestFeedServer(t *testing.T, ca testCA, db *fakeFeedStore) (*Server, *httptest.Server) {
+	t.Helper()
+
+	cfg := DefaultServerConfig()
+	cfg.Services = ctype.NewStringSet("usersvc")
+
+	s := &Server{
+		ConfigServer: cfg,
+		Servelet:     testServelet(t),
+		Secret:       newTestSigner(t),
+		Store:        db,
+	}
+
+	router := httpsvc.NewRouter(s.Servelet, "/")
+	s.AddRoutes(router)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(router)
+	srv.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: clientCAs}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return s, srv
+}
+
+func newTestFeedClient(t *testing.T, srv *httptest.Server, ca testCA, commonName string) *RevocationClient {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, commonName)
+
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	res, err := NewRevocationClient(ConfigRevocationClient{
+		Address: srv.URL,
+		Timeout: 5 * time.Second,
+		TLS: httpsvc.ConfigClientTLS{
+			Enabled:     true,
+			CA:          secret.SourceConfig{Source: secret.Inline(serverCA)},
+			Certificate: secret.SourceConfig{Source: secret.Inline(certPEM)},
+			Key:         secret.SourceConfig{Source: secret.Inline(keyPEM)},
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("failed to create revocation client: %s", err)
+	}
+
+	return res
+}
+
+func TestRevocationFeedService(t *testing.T) {
+	var (
+		ca = newTestCA(t)
+		db = &fakeFeedStore{revocations: []model.Revocation{
+			{TokenID: "revoked", Expiration: time.Now().Add(time.Hour)},
+		}}
+	)
+
+	_, srv := newTestFeedServer(t, ca, db)
+
+	t.Run("trusted service", func(t *testing.T) {
+		client := newTestFeedClient(t, srv, ca, "usersvc")
+
+		list, err := client.Revocations(context.Background())
+		if err != nil {
+			t.Fatalf("failed to read revocations: %s", err)
+		}
+
+		if len(list) != 1 || list[0].TokenID != "revoked" {
+			t.Errorf("expected the stored revocation, got %+v", list)
+		}
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		client := newTestFeedClient(t, srv, ca, "othersvc")
+
+		if _, err := client.Revocations(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("expected 401 response, got: %v", err)
+		}
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		client := newTestFeedClient(t, srv, newTestCA(t), "usersvc")
+
+		if _, err := client.Revocations(context.Background()); err == nil {
+			t.Error("expected error for certificate from an untrusted ca")
+		}
+	})
+
+	t.Run("poll into list", func(t *testing.T) {
+		client := newTestFeedClient(t, srv, ca, "usersvc")
+		list := token.NewRevocationList()
+
+		if err := list.Refresh(context.Background(), client); err != nil {
+			t.Fatalf("failed to refresh: %s", err)
+		}
+
+		if !list.Revoked(token.StandardClaims{TokenID: "revoked"}) {
+			t.Error("polled revocation not applied")
+		}
+	})
+}
+
+func TestRevocationClientRequiresCertificate(t *testing.T) {
+	_, err := NewRevocationClient(ConfigRevocationClient{
+		Address: "localhost:8002",
+		TLS:     httpsvc.ConfigClientTLS{Enabled: true},
+	})
+
+	if err == nil {
+		t.Error("expected error for client without a certificate")
+	}
+}
+
+func TestRevocationFeedUser(t *testing.T) {
+	var (
+		ca = newTestCA(t)
+		db = &fakeFeedStore{users: map[string]model.User{
+			"admin": {ID: "admin", Admin: true},
+			"alice": {ID: "alice"},
+		}}
+	)
+
+	s, srv := newTestFeedServer(t, ca, db)
+
+	tests := []struct {
+		name     string
+		subject  string
+		expected int
+	}{
+		{name: "admin", subject: "admin", expected: http.StatusOK},
+		{name: "non admin", subject: "alice", expected: http.StatusForbidden},
+		{name: "anonymous", expected: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+RevokePath(), nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %s", err)
+			}
+
+			if test.subject != "" {
+				claims, err := s.claimsGenFactory(s.AudienceNames.User)(test.subject, time.Minute)
+				if err != nil {
+					t.Fatalf("failed to generate claims: %s", err)
+				}
+
+				tokenStr, err := s.Secret.Sign(claims)
+				if err != nil {
+					t.Fatalf("failed to sign token: %s", err)
+				}
+
+				req.Header.Set("Authorization", "Bearer "+tokenStr)
+			}
+
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("failed to perform request: %s", err)
+			}
+
+			resp.Body.Close()
+
+			if resp.StatusCode != test.expected {
+				t.Errorf("expected status %d, got %d", test.expected, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package token
 
-import "github.com/oligarch316/go-auth-service/pkg/http"
+import (
+	"path"
+
+	"github.com/oligarch316/go-auth-service/pkg/http"
+)
 
 const (
 	// APIVersion TODO.
@@ -13,17 +17,37 @@ const (
 const (
 	pathBase = "/token"
 
-	pathUser   = "/user"
-	pathSignup = "/signup"
+	pathUser    = "/user"
+	pathRefresh = "/refresh"
+	pathReset   = "/reset"
+	pathRevoke  = "/revoke"
+	pathSignup  = "/signup"
 )
 
+// UserPath TODO.
+func UserPath() string { return path.Join("/", APIVersion, pathBase, pathUser) }
+
+// RevokePath TODO.
+func RevokePath() string { return path.Join("/", APIVersion, pathBase, pathRevoke) }
+
 // AddRoutes TODO.
 func (s *Server) AddRoutes(r *httpsvc.Router) {
-	child := r.Child("/%s/%s", APIVersion, pathBase)
+	child := r.Child("/%s/%s", APIVersion, pathBase).WithAuthenticator(s)
 
 	child.Add(s.HandleUserCreate(), pathUser)
 	child.Add(s.HandleUserRead(), pathUser)
 
+	child.Add(s.HandleRefreshCreate(), pathRefresh)
+
+	// Reset tokens can't be delivered without a notifier
+	if s.Notifier != nil {
+		child.Add(s.HandleResetCreate(), pathReset)
+		child.Add(s.HandleResetUpdate(), pathReset)
+	}
+
+	child.Add(s.HandleRevokeCreate(), pathRevoke)
+	child.Add(s.HandleRevokeList(), pathRevoke)
+
 	child.Add(s.HandleSignupCreate(), pathSignup)
 	child.Add(s.HandleSignupRead(), pathSignup)
 }
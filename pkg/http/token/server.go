@@ -1,9 +1,12 @@
 package token
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -11,14 +14,35 @@ import (
 	"github.com/oligarch316/go-auth-service/pkg/http"
 	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
 	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/notify"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
 	"github.com/oligarch316/go-skeleton/pkg/config/types"
 	"go.uber.org/zap/zapcore"
 )
 
+const (
+	// AudienceRevocations TODO.
+	//
+	// Accepts either a trusted service's client certificate or an admin user
+	// token.
+	AudienceRevocations = "revocations"
+)
+
+// NOTE: Prefixes authenticated service subjects, which never collide with the
+// generated ids of user subjects
+const serviceSubjectPrefix = "service:"
+
+const (
+	codeInvalidCredentials = "invalid_credentials"
+	codeRefreshTokenReused = "refresh_token_reused"
+)
+
 // ConfigAudienceNames TODO.
 type ConfigAudienceNames struct {
 	User   string `json:"user"`
 	Signup string `json:"signup"`
+	Reset  string `json:"reset"`
 }
 
 // DefaultAudienceNamesConfig TODO.
@@ -26,6 +50,7 @@ func DefaultAudienceNamesConfig() ConfigAudienceNames {
 	return ConfigAudienceNames{
 		Signup: claims.DefaultAudienceNameSignup,
 		User:   claims.DefaultAudienceNameUser,
+		Reset:  claims.DefaultAudienceNameReset,
 	}
 }
 
@@ -33,6 +58,7 @@ func DefaultAudienceNamesConfig() ConfigAudienceNames {
 func (can ConfigAudienceNames) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("user", can.User)
 	enc.AddString("signup", can.Signup)
+	enc.AddString("reset", can.Reset)
 	return nil
 }
 
@@ -41,6 +67,15 @@ type ConfigServer struct {
 	AudienceNames ConfigAudienceNames `json:"audienceNames"`
 	IssuerName    string              `json:"issuerName"`
 	MaxTTL        ctype.Duration      `json:"maxTTL"`
+	RefreshTTL    ctype.Duration      `json:"refreshTTL"`
+
+	Login       ConfigLogin                `json:"login"`
+	Reset       ConfigReset                `json:"reset"`
+	Revocations token.ConfigRevocationList `json:"revocations"`
+
+	// Client certificate common names of services allowed to read the
+	// revocation feed. Requires TLS with client auth "request" or "require"
+	Services ctype.StringSet `json:"services"`
 }
 
 // DefaultServerConfig TODO.
@@ -49,13 +84,23 @@ func DefaultServerConfig() ConfigServer {
 		AudienceNames: DefaultAudienceNamesConfig(),
 		IssuerName:    claims.DefaultIssuerName,
 		MaxTTL:        ctype.Duration{Duration: 24 * time.Hour},
+		RefreshTTL:    ctype.Duration{Duration: 30 * 24 * time.Hour},
+		Login:         DefaultLoginConfig(),
+		Reset:         DefaultResetConfig(),
+		Revocations:   token.DefaultRevocationListConfig(),
+		Services:      ctype.NewStringSet(),
 	}
 }
 
 // MarshalLogObject TODO.
 func (cs ConfigServer) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddDuration("maxTTL", cs.MaxTTL.Duration)
+	enc.AddDuration("refreshTTL", cs.RefreshTTL.Duration)
 	enc.AddString("issuerName", cs.IssuerName)
+	enc.AddObject("login", cs.Login)
+	enc.AddObject("reset", cs.Reset)
+	enc.AddObject("revocations", cs.Revocations)
+	enc.AddArray("services", cs.Services)
 	return enc.AddObject("audienceNames", cs.AudienceNames)
 }
 
@@ -71,39 +116,115 @@ type Server struct {
 	}
 
 	Store interface {
-		LookupUser(name string) (model.User, error)
-		ReadInvite(id string) (model.Invite, error)
+		LookupUser(ctx context.Context, name string) (model.User, error)
+		ReadUser(ctx context.Context, id string) (model.User, error)
+		UpdateUser(ctx context.Context, id string, mData model.UserUpdate) error
+		ReadInvite(ctx context.Context, id string) (model.Invite, error)
+
+		CreateRefreshToken(ctx context.Context, data model.RefreshToken) error
+		RotateRefreshToken(ctx context.Context, id string, next model.RefreshToken) (model.RefreshToken, error)
+		RevokeRefreshToken(ctx context.Context, id string) error
+		RevokeUserRefreshTokens(ctx context.Context, userID string) error
+
+		CreateRevocation(ctx context.Context, data model.Revocation) error
+		ConsumeToken(ctx context.Context, data model.Revocation) error
+		ListRevocations(ctx context.Context) ([]model.Revocation, error)
+		PruneRevocations(ctx context.Context, before time.Time) error
+
+		ReadLoginAttempt(ctx context.Context, key string) (model.LoginAttempt, error)
+		RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (model.LoginAttempt, error)
+		ReleaseLoginAttempt(ctx context.Context, key string) error
+		DeleteLoginAttempt(ctx context.Context, key string) error
+		PruneLoginAttempts(ctx context.Context, before time.Time) error
 	}
+
+	// Optional, nil disables revocation checks on this server's own validation
+	Revocations *token.RevocationList
+
+	Passwords *password.Registry
+
+	// Optional, delivers password reset tokens out of band, nil disables reset
+	Notifier notify.Notifier
 }
 
-func (s Server) claimsGenFactory(audienceNames ...string) func(string, time.Duration) token.StandardClaims {
+func (s Server) claimsGenFactory(audienceNames ...string) func(string, time.Duration) (token.StandardClaims, error) {
 	allowedAud := ctype.NewStringSet(audienceNames...)
 
-	return func(id string, ttl time.Duration) token.StandardClaims {
+	return func(id string, ttl time.Duration) (token.StandardClaims, error) {
 		if ttl <= 0 || ttl > s.MaxTTL.Duration {
 			ttl = s.MaxTTL.Duration
 		}
 
+		tokenID, err := newTokenID()
+		if err != nil {
+			return token.StandardClaims{}, err
+		}
+
 		now := time.Now()
 
 		return token.StandardClaims{
 			Issuer:     s.IssuerName,
 			Subject:    id,
+			TokenID:    tokenID,
 			Audience:   allowedAud,
 			Expiration: &token.NumericDate{Time: now.Add(ttl)},
 			IssuedAt:   &token.NumericDate{Time: now},
-		}
+		}, nil
 	}
 }
 
-func (s Server) claimsValFactory(audienceName string) func(*http.Request) (string, error) {
-	return token.Validater{
+func (s Server) validaterFactory(audienceName string) token.Validater {
+	res := token.Validater{
 		Claims: token.ConfigValidater{
 			AllowedIssuers: ctype.NewStringSet(s.IssuerName),
 			AudienceName:   audienceName,
 		},
 		Secret: s.Secret,
-	}.Validate
+	}
+
+	if s.Revocations != nil {
+		res.Revocations = s.Revocations
+	}
+
+	return res
+}
+
+func (s Server) claimsValFactory(audienceName string) func(*http.Request) (string, error) {
+	return s.validaterFactory(audienceName).Validate
+}
+
+// Authenticate TODO.
+func (s *Server) Authenticate(r *http.Request, audience string) (string, error) {
+	if audience != AudienceRevocations {
+		return "", fmt.Errorf("unknown audience '%s'", audience)
+	}
+
+	if name, ok := s.serviceName(r); ok {
+		return serviceSubjectPrefix + name, nil
+	}
+
+	return s.claimsValFactory(s.AudienceNames.User)(r)
+}
+
+// IsAdmin TODO.
+func (s *Server) IsAdmin(ctx context.Context, subject string) (bool, error) {
+	// Service subjects are only issued for trusted services
+	if strings.HasPrefix(subject, serviceSubjectPrefix) {
+		return true, nil
+	}
+
+	user, err := s.Store.ReadUser(ctx, subject)
+	return user.Admin, err
+}
+
+// NOTE: Only certificates verified against the listener's client CA count
+func (s *Server) serviceName(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) < 1 || len(r.TLS.VerifiedChains[0]) < 1 {
+		return "", false
+	}
+
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	return name, name != "" && s.Services.Contains(name)
 }
 
 type tokenResponseBody struct {
@@ -112,6 +233,12 @@ type tokenResponseBody struct {
 	Expiration *token.NumericDate `json:"expiration"`
 }
 
+type userTokenResponseBody struct {
+	tokenResponseBody
+	RefreshToken      string             `json:"refreshToken"`
+	RefreshExpiration *token.NumericDate `json:"refreshExpiration"`
+}
+
 // HandleUserCreate TODO.
 func (s *Server) HandleUserCreate() httpsvc.Route {
 	info := httpsvc.RouteInfo{
@@ -127,7 +254,10 @@ func (s *Server) HandleUserCreate() httpsvc.Route {
 		TTL      ctype.Duration `json:"ttl"`
 	}
 
-	genClaims := s.claimsGenFactory(s.AudienceNames.User)
+	var (
+		genClaims = s.claimsGenFactory(s.AudienceNames.User)
+		dummyHash = newDummyHash(s.Passwords, s.Servelet.Logger)
+	)
 
 	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		var reqBody requestBody
@@ -138,34 +268,183 @@ func (s *Server) HandleUserCreate() httpsvc.Route {
 			return
 		}
 
-		// Lookup user data by name
-		data, err := s.Store.LookupUser(reqBody.Name)
+		// Reserve this attempt ahead of verification, unless throttled or locked out
+		loginKeys := s.loginKeys(r, reqBody.Name)
+
+		attempts, err := s.reserveLogin(r.Context(), loginKeys)
 		if err != nil {
+			s.handleLoginErr(w, r, err, "failed to record login attempt")
+			return
+		}
+
+		// Lookup user data by name
+		data, err := s.Store.LookupUser(r.Context(), reqBody.Name)
+		switch {
+		case errors.Is(err, storeerr.ErrNotFound):
+			// Unknown names fail exactly as a wrong password does, after as much work
+			data.PasswordHash = dummyHash()
+		case err != nil:
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to lookup user"))
 			return
 		}
 
 		// Valiate given password against existing user data
-		if err = data.PasswordHash.Compare(reqBody.Password); err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusForbidden, err, "failed to validate password"))
+		err = data.PasswordHash.Compare(s.Passwords, reqBody.Password)
+		if err == nil && data.ID == "" {
+			err = password.ErrMismatch
+		}
+
+		if err != nil {
+			s.recordLoginFailure(loginKeys, attempts)
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusForbidden, err, "failed to validate password").WithCode(codeInvalidCredentials))
+			return
+		}
+
+		s.releaseLogin(r.Context(), loginKeys)
+
+		// Outdated hash algorithm or parameters => upgrade while the password is at hand
+		if data.PasswordHash.NeedsRehash(s.Passwords) {
+			s.rehashPassword(r.Context(), data.ID, reqBody.Password)
+		}
+
+		// Create refresh token in a new family
+		refreshToken, refreshData, err := newRefreshToken(s.RefreshTTL.Duration)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to generate refresh token"))
+			return
+		}
+
+		if refreshData.FamilyID, err = newRefreshFamilyID(); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to generate refresh token"))
+			return
+		}
+
+		refreshData.UserID = data.ID
+
+		if err = s.Store.CreateRefreshToken(r.Context(), refreshData); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to create refresh token"))
+			return
+		}
+
+		// Create user claims
+		claims, err := genClaims(data.ID, reqBody.TTL.Duration)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to generate token claims"))
+			return
+		}
+
+		// Build token from claims
+		userToken, err := s.Secret.Sign(claims)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to sign token claims"))
+			return
+		}
+
+		// Encode response body
+		bytes, err := json.Marshal(userTokenResponseBody{
+			tokenResponseBody: tokenResponseBody{
+				ID:         data.ID,
+				Token:      userToken,
+				Expiration: claims.Expiration,
+			},
+			RefreshToken:      refreshToken,
+			RefreshExpiration: &token.NumericDate{Time: refreshData.Expiration},
+		})
+
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
+			return
+		}
+
+		// Respond
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(bytes)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Handle: handle}
+}
+
+// HandleRefreshCreate TODO.
+func (s *Server) HandleRefreshCreate() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "tokenrefreshcreate",
+		Description: "exchange a refresh token for a new user token and rotated refresh token",
+		Method:      http.MethodPost,
+		MetricTag:   "token_refresh_create",
+	}
+
+	type requestBody struct {
+		RefreshToken string         `json:"refreshToken"`
+		TTL          ctype.Duration `json:"ttl"`
+	}
+
+	genClaims := s.claimsGenFactory(s.AudienceNames.User)
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var reqBody requestBody
+
+		// Decode request body
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, err, "failed to load request body"))
+			return
+		}
+
+		// Generate successor refresh token
+		refreshToken, refreshData, err := newRefreshToken(s.RefreshTTL.Duration)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to generate refresh token"))
+			return
+		}
+
+		// Rotate presented refresh token
+		refreshData, err = s.Store.RotateRefreshToken(r.Context(), refreshTokenID(reqBody.RefreshToken), refreshData)
+		switch {
+		case errors.Is(err, storeerr.ErrRefreshTokenReused):
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "refresh token reuse detected, token family revoked").WithCode(codeRefreshTokenReused))
+			return
+		case errors.Is(err, storeerr.ErrNotFound):
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate refresh token"))
+			return
+		case err != nil:
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to rotate refresh token"))
+			return
+		}
+
+		// Confirm user still exists
+		if _, err := s.Store.ReadUser(r.Context(), refreshData.UserID); err != nil {
+			if errors.Is(err, storeerr.ErrNotFound) {
+				s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate refresh token"))
+				return
+			}
+
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
 			return
 		}
 
 		// Create user claims
-		claims := genClaims(data.ID, reqBody.TTL.Duration)
+		claims, err := genClaims(refreshData.UserID, reqBody.TTL.Duration)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to generate token claims"))
+			return
+		}
 
 		// Build token from claims
-		token, err := s.Secret.Sign(claims)
+		userToken, err := s.Secret.Sign(claims)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to sign token claims"))
 			return
 		}
 
 		// Encode response body
-		bytes, err := json.Marshal(tokenResponseBody{
-			ID:         data.ID,
-			Token:      token,
-			Expiration: claims.Expiration,
+		bytes, err := json.Marshal(userTokenResponseBody{
+			tokenResponseBody: tokenResponseBody{
+				ID:         refreshData.UserID,
+				Token:      userToken,
+				Expiration: claims.Expiration,
+			},
+			RefreshToken:      refreshToken,
+			RefreshExpiration: &token.NumericDate{Time: refreshData.Expiration},
 		})
 
 		if err != nil {
@@ -243,7 +522,7 @@ func (s *Server) HandleSignupCreate() httpsvc.Route {
 		}
 
 		// Lookup invite by id
-		inviteData, err := s.Store.ReadInvite(reqBody.InviteID)
+		inviteData, err := s.Store.ReadInvite(r.Context(), reqBody.InviteID)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read invite"))
 			return
@@ -256,7 +535,11 @@ func (s *Server) HandleSignupCreate() httpsvc.Route {
 		}
 
 		// Create signup claims
-		signupClaims := genSignupClaims(inviteData.ID, reqBody.TTL.Duration)
+		signupClaims, err := genSignupClaims(inviteData.ID, reqBody.TTL.Duration)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.InternalError(err, "failed to generate signup token claims"))
+			return
+		}
 
 		// Build token from claims
 		signupToken, err := s.Secret.Sign(signupClaims)
@@ -0,0 +1,277 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store"
+	"github.com/oligarch316/go-auth-service/pkg/store/sqlite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testEnv is a token server backed by an in memory sqlite store, served by
+// its own router
+type testEnv struct {
+	*Server
+
+	db     store.Backend
+	router *httpsvc.Router
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	passwords := password.NewRegistry(password.Bcrypt{Cost: bcrypt.MinCost})
+	servelet := testServelet(t)
+
+	db, err := sqlite.New(sqlite.DefaultConfig(), passwords, servelet.Corelet)
+	if err != nil {
+		t.Fatalf("failed to create sqlite store: %s", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	res := &testEnv{
+		Server: &Server{
+			ConfigServer: DefaultServerConfig(),
+			Servelet:     servelet,
+			Secret:       newTestSigner(t),
+			Store:        db,
+			Revocations:  token.NewRevocationList(),
+			Passwords:    passwords,
+		},
+		db:     db,
+		router: httpsvc.NewRouter(servelet, "/"),
+	}
+
+	res.AddRoutes(res.router)
+	return res
+}
+
+func (te *testEnv) createUser(t *testing.T, name, pass string) model.User {
+	t.Helper()
+
+	res, err := te.db.CreateUser(context.Background(), name, pass, model.UserUpdate{})
+	if err != nil {
+		t.Fatalf("failed to create user '%s': %s", name, err)
+	}
+
+	return res
+}
+
+func (te *testEnv) do(t *testing.T, method, urlPath string, body interface{}, bearer string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatalf("failed to encode request body: %s", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, urlPath, &reqBody)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	rec := httptest.NewRecorder()
+	te.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (te *testEnv) login(t *testing.T, name, pass string) userTokenResponseBody {
+	t.Helper()
+
+	rec := te.do(t, http.MethodPost, UserPath(), map[string]string{"name": name, "password": pass}, "")
+	expectStatus(t, rec, http.StatusCreated)
+
+	var res userTokenResponseBody
+	decodeBody(t, rec, &res)
+	return res
+}
+
+func (te *testEnv) refresh(t *testing.T, refreshToken string) *httptest.ResponseRecorder {
+	t.Helper()
+	return te.do(t, http.MethodPost, refreshPath(), map[string]string{"refreshToken": refreshToken}, "")
+}
+
+func refreshPath() string { return path.Join("/", APIVersion, pathBase, pathRefresh) }
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, expected int) {
+	t.Helper()
+
+	if rec.Code != expected {
+		t.Fatalf("expected status %d, got %d: %s", expected, rec.Code, rec.Body.String())
+	}
+}
+
+func expectCode(t *testing.T, rec *httptest.ResponseRecorder, expected string) {
+	t.Helper()
+
+	var body struct {
+		Code string `json:"code"`
+	}
+
+	decodeBody(t, rec, &body)
+
+	if body.Code != expected {
+		t.Errorf("expected code '%s', got '%s'", expected, body.Code)
+	}
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response body '%s': %s", rec.Body.String(), err)
+	}
+}
+
+func TestHandleUserCreate(t *testing.T) {
+	te := newTestEnv(t)
+	alice := te.createUser(t, "alice", "alice password")
+
+	t.Run("valid credentials", func(t *testing.T) {
+		resp := te.login(t, "alice", "alice password")
+
+		if resp.ID != alice.ID {
+			t.Errorf("expected id '%s', got '%s'", alice.ID, resp.ID)
+		}
+
+		if resp.RefreshToken == "" || resp.RefreshExpiration == nil {
+			t.Error("expected a refresh token")
+		}
+
+		expectStatus(t, te.do(t, http.MethodGet, UserPath(), nil, resp.Token), http.StatusOK)
+	})
+
+	tests := []struct {
+		name, user, pass string
+	}{
+		{name: "wrong password", user: "alice", pass: "wrong password"},
+		{name: "unknown user", user: "bob", pass: "alice password"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := te.do(t, http.MethodPost, UserPath(), map[string]string{"name": test.user, "password": test.pass}, "")
+
+			expectStatus(t, rec, http.StatusForbidden)
+			expectCode(t, rec, codeInvalidCredentials)
+		})
+	}
+}
+
+func TestHandleRefreshCreate(t *testing.T) {
+	t.Run("rotation", func(t *testing.T) {
+		te := newTestEnv(t)
+		alice := te.createUser(t, "alice", "alice password")
+		first := te.login(t, "alice", "alice password")
+
+		rec := te.refresh(t, first.RefreshToken)
+		expectStatus(t, rec, http.StatusCreated)
+
+		var second userTokenResponseBody
+		decodeBody(t, rec, &second)
+
+		if second.ID != alice.ID {
+			t.Errorf("expected id '%s', got '%s'", alice.ID, second.ID)
+		}
+
+		if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+			t.Errorf("expected a rotated refresh token, got '%s'", second.RefreshToken)
+		}
+
+		expectStatus(t, te.do(t, http.MethodGet, UserPath(), nil, second.Token), http.StatusOK)
+
+		// Successor rotates in turn
+		expectStatus(t, te.refresh(t, second.RefreshToken), http.StatusCreated)
+	})
+
+	t.Run("reuse revokes family", func(t *testing.T) {
+		te := newTestEnv(t)
+		te.createUser(t, "alice", "alice password")
+		first := te.login(t, "alice", "alice password")
+
+		rec := te.refresh(t, first.RefreshToken)
+		expectStatus(t, rec, http.StatusCreated)
+
+		var second userTokenResponseBody
+		decodeBody(t, rec, &second)
+
+		// Replaying the rotated token => reuse
+		rec = te.refresh(t, first.RefreshToken)
+		expectStatus(t, rec, http.StatusUnauthorized)
+		expectCode(t, rec, codeRefreshTokenReused)
+
+		// ... and the legitimate successor is revoked along with its family
+		expectStatus(t, te.refresh(t, second.RefreshToken), http.StatusUnauthorized)
+	})
+
+	t.Run("other families unaffected by reuse", func(t *testing.T) {
+		te := newTestEnv(t)
+		te.createUser(t, "alice", "alice password")
+
+		var (
+			victim = te.login(t, "alice", "alice password")
+			other  = te.login(t, "alice", "alice password")
+		)
+
+		expectStatus(t, te.refresh(t, victim.RefreshToken), http.StatusCreated)
+		expectStatus(t, te.refresh(t, victim.RefreshToken), http.StatusUnauthorized)
+
+		expectStatus(t, te.refresh(t, other.RefreshToken), http.StatusCreated)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		te := newTestEnv(t)
+
+		rec := te.refresh(t, "not a refresh token")
+		expectStatus(t, rec, http.StatusUnauthorized)
+		expectCode(t, rec, httpsvc.CodeUnauthenticated)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		te := newTestEnv(t)
+		te.createUser(t, "alice", "alice password")
+		te.RefreshTTL.Duration = time.Millisecond
+
+		first := te.login(t, "alice", "alice password")
+		time.Sleep(5 * time.Millisecond)
+
+		expectStatus(t, te.refresh(t, first.RefreshToken), http.StatusUnauthorized)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		te := newTestEnv(t)
+		alice := te.createUser(t, "alice", "alice password")
+		first := te.login(t, "alice", "alice password")
+
+		if err := te.db.DeleteUser(context.Background(), alice.ID); err != nil {
+			t.Fatalf("failed to delete user: %s", err)
+		}
+
+		expectStatus(t, te.refresh(t, first.RefreshToken), http.StatusUnauthorized)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		te := newTestEnv(t)
+
+		req := httptest.NewRequest(http.MethodPost, refreshPath(), bytes.NewBufferString("{"))
+		rec := httptest.NewRecorder()
+		te.router.ServeHTTP(rec, req)
+
+		expectStatus(t, rec, http.StatusBadRequest)
+	})
+}
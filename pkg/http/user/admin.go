@@ -0,0 +1,359 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+
+	queryName   = "name"
+	queryAdmin  = "admin"
+	queryOffset = "offset"
+	queryLimit  = "limit"
+)
+
+func parseListQuery(r *http.Request) (filter model.UserFilter, offset, limit int, err error) {
+	query := r.URL.Query()
+
+	filter.NamePrefix = query.Get(queryName)
+
+	if str := query.Get(queryAdmin); str != "" {
+		admin, parseErr := strconv.ParseBool(str)
+		if parseErr != nil {
+			err = fmt.Errorf("'%s': expected a boolean, got '%s'", queryAdmin, str)
+			return
+		}
+		filter.Admin = &admin
+	}
+
+	if str := query.Get(queryOffset); str != "" {
+		if offset, err = strconv.Atoi(str); err != nil || offset < 0 {
+			err = fmt.Errorf("'%s': expected a non-negative integer, got '%s'", queryOffset, str)
+			return
+		}
+	}
+
+	limit = defaultListLimit
+
+	if str := query.Get(queryLimit); str != "" {
+		if limit, err = strconv.Atoi(str); err != nil || limit < 1 || limit > maxListLimit {
+			err = fmt.Errorf("'%s': expected an integer from 1 to %d, got '%s'", queryLimit, maxListLimit, str)
+			return
+		}
+	}
+
+	return
+}
+
+// HandleAdminUserList TODO.
+func (s *Server) HandleAdminUserList() httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "adminuserlist",
+		Description: "list users, filtered by name prefix and admin status",
+		Method:      http.MethodGet,
+		MetricTag:   "admin_user_list",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+
+	type responseBody struct {
+		Users  []userResponseBody `json:"users"`
+		Total  int                `json:"total"`
+		Offset int                `json:"offset"`
+		Limit  int                `json:"limit"`
+	}
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		// Read filter and page from query
+		filter, offset, limit, err := parseListQuery(r)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, err, "failed to parse query parameters"))
+			return
+		}
+
+		// List user data
+		users, err := s.Store.ListUsers(r.Context(), filter, offset, limit)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to list users"))
+			return
+		}
+
+		total, err := s.Store.CountUsers(r.Context(), filter)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to count users"))
+			return
+		}
+
+		// Create response body
+		respBody := responseBody{
+			Users:  make([]userResponseBody, len(users)),
+			Total:  total,
+			Offset: offset,
+			Limit:  limit,
+		}
+
+		for i, user := range users {
+			respBody.Users[i] = userResponseBody{
+				ID:          user.ID,
+				Name:        user.Name,
+				DisplayName: user.DisplayName,
+				Admin:       user.Admin,
+			}
+		}
+
+		// Encode response body
+		bytes, err := json.Marshal(respBody)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
+			return
+		}
+
+		// Respond
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
+}
+
+// HandleAdminUserRead TODO.
+func (s *Server) HandleAdminUserRead(userIDParamName string) httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "adminuserread",
+		Description: fmt.Sprintf("any user data for id '%s'", userIDParamName),
+		Method:      http.MethodGet,
+		MetricTag:   "admin_user_read",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+
+	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		// Read user id from url
+		userID := params.ByName(userIDParamName)
+		if userID == "" {
+			s.Servelet.HandleErr(w, r, httpsvc.URLParamError(userIDParamName))
+			return
+		}
+
+		// Read user data
+		user, err := s.Store.ReadUser(r.Context(), userID)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
+			return
+		}
+
+		// Encode response body
+		bytes, err := json.Marshal(userResponseBody{
+			ID:          user.ID,
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+			Admin:       user.Admin,
+		})
+
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.EncodeResponseError(err))
+			return
+		}
+
+		// Respond
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
+}
+
+func (s *Server) handleAdminFlag(userIDParamName string, admin bool) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		adminID := httpsvc.Subject(r.Context())
+
+		// Read user id from url
+		userID := params.ByName(userIDParamName)
+		if userID == "" {
+			s.Servelet.HandleErr(w, r, httpsvc.URLParamError(userIDParamName))
+			return
+		}
+
+		// Refuse self demotion, lest the last admin lock everyone out
+		if !admin && userID == adminID {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(
+				http.StatusConflict,
+				errors.New("admin may not revoke their own admin status"),
+				"failed to revoke admin",
+			))
+			return
+		}
+
+		// Perform update
+		if err := s.Store.UpdateUser(r.Context(), userID, model.UserUpdate{Admin: &admin}); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to update user"))
+			return
+		}
+
+		s.Servelet.RequestLogger(r).Info(
+			"updated user admin status",
+			zap.String("userID", userID),
+			zap.Bool("admin", admin),
+			zap.String("adminID", adminID),
+		)
+
+		// Respond
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandleAdminGrant TODO.
+func (s *Server) HandleAdminGrant(userIDParamName string) httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "admingrant",
+		Description: fmt.Sprintf("grant admin to user with id '%s'", userIDParamName),
+		Method:      http.MethodPut,
+		MetricTag:   "admin_grant",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: s.handleAdminFlag(userIDParamName, true)}
+}
+
+// HandleAdminRevoke TODO.
+func (s *Server) HandleAdminRevoke(userIDParamName string) httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "adminrevoke",
+		Description: fmt.Sprintf("revoke admin from user with id '%s'", userIDParamName),
+		Method:      http.MethodDelete,
+		MetricTag:   "admin_revoke",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: s.handleAdminFlag(userIDParamName, false)}
+}
+
+// HandleAdminPasswordUpdate TODO.
+func (s *Server) HandleAdminPasswordUpdate(userIDParamName string) httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "adminpasswordupdate",
+		Description: fmt.Sprintf("set password for user with id '%s'", userIDParamName),
+		Method:      http.MethodPut,
+		MetricTag:   "admin_password_update",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+
+	type requestBody struct {
+		Password     string `json:"password"`
+		RevokeTokens bool   `json:"revokeTokens"`
+	}
+
+	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		// Read user id from url
+		userID := params.ByName(userIDParamName)
+		if userID == "" {
+			s.Servelet.HandleErr(w, r, httpsvc.URLParamError(userIDParamName))
+			return
+		}
+
+		var reqBody requestBody
+
+		// Decode request body
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, err, "failed to load request body"))
+			return
+		}
+
+		// Check password policy
+		if err := s.PasswordPolicy.Check(reqBody.Password); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.PasswordPolicyError(err))
+			return
+		}
+
+		// Perform update
+		if err := s.Store.UpdateUser(r.Context(), userID, model.UserUpdate{Password: &reqBody.Password}); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to update password"))
+			return
+		}
+
+		// Revoke outstanding tokens
+		if reqBody.RevokeTokens {
+			if err := s.userRevoker().RevokeUser(r.Context(), userID); err != nil {
+				s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to revoke user tokens"))
+				return
+			}
+		}
+
+		s.Servelet.RequestLogger(r).Info(
+			"reset user password",
+			zap.String("userID", userID),
+			zap.Bool("revokeTokens", reqBody.RevokeTokens),
+			zap.String("adminID", httpsvc.Subject(r.Context())),
+		)
+
+		// Respond
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
+}
+
+// HandleAdminUserDelete TODO.
+func (s *Server) HandleAdminUserDelete(userIDParamName string) httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "adminuserdelete",
+		Description: fmt.Sprintf("delete any user with id '%s'", userIDParamName),
+		Method:      http.MethodDelete,
+		MetricTag:   "admin_user_delete",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+
+	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		// Read user id from url
+		userID := params.ByName(userIDParamName)
+		if userID == "" {
+			s.Servelet.HandleErr(w, r, httpsvc.URLParamError(userIDParamName))
+			return
+		}
+
+		// Confirm user exists
+		if _, err := s.Store.ReadUser(r.Context(), userID); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
+			return
+		}
+
+		// Revoke outstanding tokens
+		// NOTE: Ahead of the delete, refresh tokens reference the user
+		if err := s.userRevoker().RevokeUser(r.Context(), userID); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to revoke user tokens"))
+			return
+		}
+
+		// Perform delete
+		if err := s.Store.DeleteUser(r.Context(), userID); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to delete user"))
+			return
+		}
+
+		s.Servelet.RequestLogger(r).Info(
+			"deleted user",
+			zap.String("userID", userID),
+			zap.String("adminID", httpsvc.Subject(r.Context())),
+		)
+
+		// Respond
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
+}
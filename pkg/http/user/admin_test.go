@@ -0,0 +1,318 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+)
+
+func adminUserPath(userID string, elems ...string) string {
+	return path.Join(append([]string{"/", APIVersion, pathAdmin, pathUser, userID}, elems...)...)
+}
+
+func (te *testEnv) readUser(t *testing.T, userID string) model.User {
+	t.Helper()
+
+	res, err := te.db.ReadUser(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("failed to read user '%s': %s", userID, err)
+	}
+
+	return res
+}
+
+type adminListBody struct {
+	Users []userResponseBody `json:"users"`
+	Total int                `json:"total"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+func userNames(users []userResponseBody) []string {
+	res := make([]string, len(users))
+	for i, user := range users {
+		res[i] = user.Name
+	}
+	return res
+}
+
+func TestAdminAuth(t *testing.T) {
+	te := newTestEnv(t)
+
+	var (
+		alice = te.createUser(t, "alice", "alice password", false)
+		bob   = te.createUser(t, "bob", "bob password", false)
+	)
+
+	routes := []struct{ method, path string }{
+		{http.MethodGet, adminUserPath("")},
+		{http.MethodGet, adminUserPath(bob.ID)},
+		{http.MethodDelete, adminUserPath(bob.ID)},
+		{http.MethodPut, adminUserPath(bob.ID, pathAdmin)},
+		{http.MethodDelete, adminUserPath(bob.ID, pathAdmin)},
+		{http.MethodPut, adminUserPath(bob.ID, pathPass)},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			expectStatus(t, te.do(t, route.method, route.path, nil, ""), http.StatusUnauthorized)
+
+			rec := te.do(t, route.method, route.path, map[string]string{"password": "new bob password"}, te.userToken(t, alice.ID))
+			expectStatus(t, rec, http.StatusForbidden)
+			expectCode(t, rec, httpsvc.CodeForbidden)
+		})
+	}
+
+	// Non admin requests leave data untouched
+	if user := te.readUser(t, bob.ID); user.Admin || user.PasswordHash.Compare(te.Passwords, "bob password") != nil {
+		t.Error("expected bob unchanged")
+	}
+}
+
+func TestHandleAdminUserList(t *testing.T) {
+	te := newTestEnv(t)
+
+	root := te.createUser(t, "root", "root password", true)
+	te.createUser(t, "alice", "alice password", false)
+	te.createUser(t, "alex", "alex password", true)
+	te.createUser(t, "bob", "bob password", false)
+
+	bearer := te.userToken(t, root.ID)
+
+	tests := []struct {
+		name        string
+		query       string
+		expectNames []string
+		expectTotal int
+	}{
+		{name: "all", query: "", expectNames: []string{"root", "alice", "alex", "bob"}, expectTotal: 4},
+		{name: "name prefix", query: "?name=al", expectNames: []string{"alice", "alex"}, expectTotal: 2},
+		{name: "admins", query: "?admin=true", expectNames: []string{"root", "alex"}, expectTotal: 2},
+		{name: "non admins with prefix", query: "?admin=false&name=b", expectNames: []string{"bob"}, expectTotal: 1},
+		{name: "first page", query: "?limit=2", expectNames: []string{"root", "alice"}, expectTotal: 4},
+		{name: "second page", query: "?limit=2&offset=2", expectNames: []string{"alex", "bob"}, expectTotal: 4},
+		{name: "past the end", query: "?offset=10", expectNames: []string{}, expectTotal: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := te.do(t, http.MethodGet, adminUserPath("")+test.query, nil, bearer)
+			expectStatus(t, rec, http.StatusOK)
+
+			var body adminListBody
+			decodeBody(t, rec, &body)
+
+			if actual := userNames(body.Users); !equalNames(actual, test.expectNames) {
+				t.Errorf("expected users %v, got %v", test.expectNames, actual)
+			}
+
+			if body.Total != test.expectTotal {
+				t.Errorf("expected total %d, got %d", test.expectTotal, body.Total)
+			}
+		})
+	}
+
+	t.Run("default limit", func(t *testing.T) {
+		rec := te.do(t, http.MethodGet, adminUserPath(""), nil, bearer)
+		expectStatus(t, rec, http.StatusOK)
+
+		var body adminListBody
+		decodeBody(t, rec, &body)
+
+		if body.Offset != 0 || body.Limit != defaultListLimit {
+			t.Errorf("expected offset 0 and limit %d, got %d and %d", defaultListLimit, body.Offset, body.Limit)
+		}
+	})
+
+	for _, query := range []string{"?admin=maybe", "?offset=-1", "?offset=x", "?limit=0", "?limit=501"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			rec := te.do(t, http.MethodGet, adminUserPath("")+query, nil, bearer)
+			expectStatus(t, rec, http.StatusBadRequest)
+			expectCode(t, rec, httpsvc.CodeBadRequest)
+		})
+	}
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestHandleAdminUserRead(t *testing.T) {
+	te := newTestEnv(t)
+
+	var (
+		root   = te.createUser(t, "root", "root password", true)
+		alice  = te.createUser(t, "alice", "alice password", false)
+		bearer = te.userToken(t, root.ID)
+	)
+
+	rec := te.do(t, http.MethodGet, adminUserPath(alice.ID), nil, bearer)
+	expectStatus(t, rec, http.StatusOK)
+
+	var body userResponseBody
+	decodeBody(t, rec, &body)
+
+	if body.ID != alice.ID || body.Name != "alice" || body.Admin {
+		t.Errorf("unexpected user data %+v", body)
+	}
+
+	rec = te.do(t, http.MethodGet, adminUserPath("999"), nil, bearer)
+	expectStatus(t, rec, http.StatusNotFound)
+	expectCode(t, rec, httpsvc.CodeNotFound)
+}
+
+func TestHandleAdminFlag(t *testing.T) {
+	t.Run("grant and revoke", func(t *testing.T) {
+		te := newTestEnv(t)
+
+		var (
+			root   = te.createUser(t, "root", "root password", true)
+			alice  = te.createUser(t, "alice", "alice password", false)
+			bearer = te.userToken(t, root.ID)
+		)
+
+		expectStatus(t, te.do(t, http.MethodPut, adminUserPath(alice.ID, pathAdmin), nil, bearer), http.StatusNoContent)
+
+		if !te.readUser(t, alice.ID).Admin {
+			t.Fatal("expected alice to be granted admin")
+		}
+
+		// Granted admin takes effect with existing tokens
+		aliceBearer := te.userToken(t, alice.ID)
+		expectStatus(t, te.do(t, http.MethodGet, adminUserPath(""), nil, aliceBearer), http.StatusOK)
+
+		expectStatus(t, te.do(t, http.MethodDelete, adminUserPath(alice.ID, pathAdmin), nil, bearer), http.StatusNoContent)
+
+		if te.readUser(t, alice.ID).Admin {
+			t.Fatal("expected alice's admin to be revoked")
+		}
+
+		// ... as does its revocation
+		expectStatus(t, te.do(t, http.MethodGet, adminUserPath(""), nil, aliceBearer), http.StatusForbidden)
+	})
+
+	t.Run("self demotion", func(t *testing.T) {
+		te := newTestEnv(t)
+		root := te.createUser(t, "root", "root password", true)
+
+		rec := te.do(t, http.MethodDelete, adminUserPath(root.ID, pathAdmin), nil, te.userToken(t, root.ID))
+		expectStatus(t, rec, http.StatusConflict)
+		expectCode(t, rec, httpsvc.CodeConflict)
+
+		if !te.readUser(t, root.ID).Admin {
+			t.Error("expected admin to keep their own admin status")
+		}
+	})
+
+	t.Run("self grant", func(t *testing.T) {
+		te := newTestEnv(t)
+		root := te.createUser(t, "root", "root password", true)
+
+		expectStatus(t, te.do(t, http.MethodPut, adminUserPath(root.ID, pathAdmin), nil, te.userToken(t, root.ID)), http.StatusNoContent)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		te := newTestEnv(t)
+		root := te.createUser(t, "root", "root password", true)
+
+		rec := te.do(t, http.MethodPut, adminUserPath("999", pathAdmin), nil, te.userToken(t, root.ID))
+		expectStatus(t, rec, http.StatusNotFound)
+	})
+}
+
+func TestHandleAdminPasswordUpdate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		te := newTestEnv(t)
+
+		var (
+			root  = te.createUser(t, "root", "root password", true)
+			alice = te.createUser(t, "alice", "alice password", false)
+
+			// NOTE: Revocations cover tokens issued before the current second
+			aliceBearer = te.userTokenAt(t, alice.ID, time.Now().Add(-2*time.Second))
+		)
+
+		body := map[string]interface{}{"password": "new alice password", "revokeTokens": true}
+		expectStatus(t, te.do(t, http.MethodPut, adminUserPath(alice.ID, pathPass), body, te.userToken(t, root.ID)), http.StatusNoContent)
+
+		if !te.checkPassword(t, "alice", "new alice password") {
+			t.Error("expected password to be replaced")
+		}
+
+		expectStatus(t, te.do(t, http.MethodGet, userPath(alice.ID), nil, aliceBearer), http.StatusUnauthorized)
+	})
+
+	t.Run("keep tokens", func(t *testing.T) {
+		te := newTestEnv(t)
+
+		var (
+			root        = te.createUser(t, "root", "root password", true)
+			alice       = te.createUser(t, "alice", "alice password", false)
+			aliceBearer = te.userTokenAt(t, alice.ID, time.Now().Add(-2*time.Second))
+		)
+
+		body := map[string]interface{}{"password": "new alice password"}
+		expectStatus(t, te.do(t, http.MethodPut, adminUserPath(alice.ID, pathPass), body, te.userToken(t, root.ID)), http.StatusNoContent)
+
+		expectStatus(t, te.do(t, http.MethodGet, userPath(alice.ID), nil, aliceBearer), http.StatusOK)
+	})
+
+	t.Run("policy violation", func(t *testing.T) {
+		te := newTestEnv(t)
+
+		var (
+			root  = te.createUser(t, "root", "root password", true)
+			alice = te.createUser(t, "alice", "alice password", false)
+		)
+
+		rec := te.do(t, http.MethodPut, adminUserPath(alice.ID, pathPass), map[string]string{"password": "short"}, te.userToken(t, root.ID))
+		expectStatus(t, rec, http.StatusBadRequest)
+		expectCode(t, rec, httpsvc.CodePasswordPolicy)
+
+		if !te.checkPassword(t, "alice", "alice password") {
+			t.Error("expected password unchanged")
+		}
+	})
+}
+
+func TestHandleAdminUserDelete(t *testing.T) {
+	te := newTestEnv(t)
+
+	var (
+		root        = te.createUser(t, "root", "root password", true)
+		alice       = te.createUser(t, "alice", "alice password", false)
+		aliceBearer = te.userTokenAt(t, alice.ID, time.Now().Add(-2*time.Second))
+		bearer      = te.userToken(t, root.ID)
+	)
+
+	expectStatus(t, te.do(t, http.MethodDelete, adminUserPath(alice.ID), nil, bearer), http.StatusNoContent)
+
+	if _, err := te.db.ReadUser(context.Background(), alice.ID); !errors.Is(err, storeerr.ErrNotFound) {
+		t.Errorf("expected deleted user to be not found, got: %v", err)
+	}
+
+	// Outstanding tokens revoked along with the user
+	expectStatus(t, te.do(t, http.MethodGet, userPath(alice.ID), nil, aliceBearer), http.StatusUnauthorized)
+
+	rec := te.do(t, http.MethodDelete, adminUserPath(alice.ID), nil, bearer)
+	expectStatus(t, rec, http.StatusNotFound)
+	expectCode(t, rec, httpsvc.CodeNotFound)
+}
@@ -1,10 +1,14 @@
 package command
 
 import (
+	"time"
+
 	"github.com/oligarch316/go-auth-service/internal/pkg/claims"
+	"github.com/oligarch316/go-auth-service/pkg/http"
 	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
 	httptoken "github.com/oligarch316/go-auth-service/pkg/http/token"
 	"github.com/oligarch316/go-auth-service/pkg/http/user"
+	"github.com/oligarch316/go-auth-service/pkg/password"
 	"github.com/oligarch316/go-auth-service/pkg/store"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
 	"github.com/oligarch316/go-skeleton/pkg/config/types"
@@ -14,9 +18,13 @@ import (
 
 // Config TODO.
 type Config struct {
-	AllowedIssuers ctype.StringSet               `json:"allowedIssuers"`
-	AudienceNames  httptoken.ConfigAudienceNames `json:"audienceNames"`
-	SecretCache    token.ConfigCache             `json:"secretCache"`
+	AllowedIssuers ctype.StringSet                  `json:"allowedIssuers"`
+	AudienceNames  httptoken.ConfigAudienceNames    `json:"audienceNames"`
+	SecretCache    token.ConfigCache                `json:"secretCache"`
+	Revocations    token.ConfigRevocationList       `json:"revocations"`
+	RevocationFeed httptoken.ConfigRevocationClient `json:"revocationFeed"`
+	RevocationTTL  ctype.Duration                   `json:"revocationTTL"`
+	PasswordPolicy password.Policy                  `json:"passwordPolicy"`
 }
 
 // DefaultConfig TODO.
@@ -25,22 +33,32 @@ func DefaultConfig() Config {
 		AllowedIssuers: ctype.NewStringSet(claims.DefaultIssuerName),
 		AudienceNames:  httptoken.DefaultAudienceNamesConfig(),
 		SecretCache:    token.DefaultCacheConfig(),
+		Revocations:    token.DefaultRevocationListConfig(),
+		RevocationFeed: httptoken.DefaultRevocationClientConfig(),
+		RevocationTTL:  ctype.Duration{Duration: 24 * time.Hour},
+		PasswordPolicy: password.DefaultPolicy(),
 	}
 }
 
 type cmdConfig struct {
-	Address string        `json:"address"`
-	DB      store.Config  `json:"db"`
-	Observ  observ.Config `json:"observ"`
-	UserSvc Config        `json:"usersvc"`
+	Address  string               `json:"address"`
+	HTTP     httpsvc.ConfigServer `json:"http"`
+	TLS      httpsvc.ConfigTLS    `json:"tls"`
+	DB       store.Config         `json:"db"`
+	Password password.Config      `json:"password"`
+	Observ   observ.Config        `json:"observ"`
+	UserSvc  Config               `json:"usersvc"`
 }
 
 func defaultCmdConfig() cmdConfig {
 	return cmdConfig{
-		Address: user.DefaultAddress,
-		DB:      store.DefaultConfig(),
-		Observ:  observ.DefaultConfig(),
-		UserSvc: DefaultConfig(),
+		Address:  user.DefaultAddress,
+		HTTP:     httpsvc.DefaultServerConfig(),
+		TLS:      httpsvc.DefaultTLSConfig(),
+		DB:       store.DefaultConfig(),
+		Password: password.DefaultConfig(),
+		Observ:   observ.DefaultConfig(),
+		UserSvc:  DefaultConfig(),
 	}
 }
 
@@ -3,12 +3,14 @@ package command
 import (
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
+	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/oligarch316/go-auth-service/pkg/http"
 	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	httptoken "github.com/oligarch316/go-auth-service/pkg/http/token"
 	httpuser "github.com/oligarch316/go-auth-service/pkg/http/user"
+	"github.com/oligarch316/go-auth-service/pkg/password"
 	"github.com/oligarch316/go-auth-service/pkg/store"
 	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
 	"github.com/oligarch316/go-skeleton/pkg/observ"
@@ -26,36 +28,117 @@ func New(name string, ns *namespace.NS) *cobra.Command {
 	}
 }
 
+// Server TODO.
+type Server struct {
+	*httpuser.Server
+
+	cache       *token.Cache
+	revocations *token.RevocationList
+}
+
+// Close TODO.
+func (s *Server) Close() error {
+	s.revocations.Close()
+
+	if s.cache != nil {
+		return s.cache.Close()
+	}
+
+	return nil
+}
+
 // NewServer TODO.
-func NewServer(cfg Config, srvlet *httpsvc.Servelet, db store.Backend) (*httpuser.Server, error) {
+func NewServer(cfg Config, srvlet *httpsvc.Servelet, db store.Backend, passwords *password.Registry) (*Server, error) {
 	cache, err := newCache(cfg.SecretCache, srvlet.Corelet.Named("cache"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token validater cache: %w", err)
 	}
 
-	return &httpuser.Server{
+	res, err := newServer(cfg, srvlet, db, passwords, cache)
+	if err != nil {
+		cache.Close()
+		return nil, err
+	}
+
+	res.cache = cache
+	return res, nil
+}
+
+// NewLocalServer creates a Server validating tokens directly against keys,
+// rather than fetching them from the secret service over http. For use
+// alongside the secret service in a single process.
+func NewLocalServer(cfg Config, srvlet *httpsvc.Servelet, db store.Backend, passwords *password.Registry, keys interface{ Load() *jwk.Set }) (*Server, error) {
+	return newServer(cfg, srvlet, db, passwords, token.KeySetValidater{Keys: keys})
+}
+
+func newServer(cfg Config, srvlet *httpsvc.Servelet, db store.Backend, passwords *password.Registry, secret interface {
+	Validate(token string, claims interface{}) error
+}) (*Server, error) {
+	source, err := revocationSource(cfg.RevocationFeed, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revocation feed client: %w", err)
+	}
+
+	revocations, err := token.LoadRevocationList(cfg.Revocations, source, srvlet.Corelet.Named("revocations"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load token revocations: %w", err)
+	}
+
+	server := &httpuser.Server{
 		Servelet: srvlet,
 		SignupValidater: token.Validater{
 			Claims: token.ConfigValidater{
 				AllowedIssuers: cfg.AllowedIssuers,
 				AudienceName:   cfg.AudienceNames.Signup,
 			},
-			Secret: cache,
+			Secret:      secret,
+			Revocations: revocations,
 		},
 		UserValidater: token.Validater{
 			Claims: token.ConfigValidater{
 				AllowedIssuers: cfg.AllowedIssuers,
 				AudienceName:   cfg.AudienceNames.User,
 			},
-			Secret: cache,
+			Secret:      secret,
+			Revocations: revocations,
 		},
-		Store: db,
-	}, nil
+		Store:          db,
+		Revocations:    revocations,
+		RevocationTTL:  cfg.RevocationTTL.Duration,
+		Passwords:      passwords,
+		PasswordPolicy: cfg.PasswordPolicy,
+	}
+
+	return &Server{Server: server, revocations: revocations}, nil
+}
+
+// NOTE: Without a feed address revocations are read from the shared database
+func revocationSource(cfg httptoken.ConfigRevocationClient, db store.Backend) (token.RevocationSource, error) {
+	if cfg.Address == "" {
+		return httptoken.StoreRevocations(db), nil
+	}
+
+	return httptoken.NewRevocationClient(cfg)
 }
 
 func newCache(cfg token.ConfigCache, corelet *observ.Corelet) (*token.Cache, error) {
-	onMiss := func() { corelet.Emitter.Count("misses", 1) }
-	return token.NewCache(append(cfg.Options(), token.WithOnMissHook(onMiss))...)
+	var (
+		onMiss    = func() { corelet.Emitter.Count("misses", 1) }
+		onRefresh = func() { corelet.Emitter.Count("refreshes", 1) }
+		onError   = func(err error) {
+			corelet.Logger.Warn("secret service unavailable", zap.Error(err))
+			corelet.Emitter.Count("errors", 1)
+		}
+	)
+
+	opts := append(
+		cfg.Options(),
+		token.WithOnMissHook(onMiss),
+		token.WithOnRefreshHook(onRefresh),
+		token.WithOnErrorHook(onError),
+	)
+
+	return token.NewCache(opts...)
 }
 
 func run(ns *namespace.NS) int {
@@ -81,14 +164,27 @@ func run(ns *namespace.NS) int {
 	root.Logger.Info("loaded configuration", zap.Strings("sources", srcRecord))
 	root.Logger.Info("created observability core", zap.Object("config", cfg.Observ))
 
+	// ----- Passwords
+	passwords, err := cfg.Password.Build()
+	if err != nil {
+		root.Logger.Error("failed to create password hashers", zap.Error(err))
+		return 1
+	}
+
+	root.Logger.Info("created password hashers", zap.Object("config", cfg.Password))
+
 	// ----- Database
-	db, err := cfg.DB.Build(observCore.Named("database"))
+	db, err := cfg.DB.Build(passwords, observCore.Named("database"))
 	if err != nil {
 		root.Logger.Error("failed to create database", zap.Error(err))
 		return 1
 	}
 
-	defer db.Close()
+	defer func() {
+		if err := db.Close(); err != nil {
+			root.Logger.Error("failed to close database", zap.Error(err))
+		}
+	}()
 
 	root.Logger.Info("created database", zap.Object("config", cfg.DB))
 
@@ -98,29 +194,44 @@ func run(ns *namespace.NS) int {
 		router   = httpsvc.NewRouter(servelet, "/")
 	)
 
-	server, err := NewServer(cfg.UserSvc, servelet.Named("user"), db)
+	server, err := NewServer(cfg.UserSvc, servelet.Named("user"), db, passwords)
 	if err != nil {
 		root.Logger.Error("failed to create server", zap.Error(err))
 		return 1
 	}
 
+	defer server.Close()
+
 	server.AddRoutes(router)
 	router.AddMetaRoutes()
 
+	// ----- TLS
+	serverTLS, err := httpsvc.NewServerTLS(cfg.TLS, observCore.Named("tls"))
+	if err != nil {
+		root.Logger.Error("failed to create tls configuration", zap.Error(err))
+		return 1
+	}
+
+	defer serverTLS.Close()
+
+	if serverTLS == nil {
+		root.Logger.Warn("tls disabled, serving plaintext http")
+	}
+
 	// ----- Run
 	root.Logger.Info(
 		"starting server",
 		zap.String("address", cfg.Address),
+		zap.Object("http", cfg.HTTP),
+		zap.Object("tls", cfg.TLS),
 		zap.Array("routes", router),
 	)
 
-	// TODO: Signal handlers
-	// TODO: TLS only
-
-	if err := http.ListenAndServe(cfg.Address, router); err != http.ErrServerClosed {
+	if err := cfg.HTTP.ListenAndServe(cfg.Address, router, serverTLS.Config(), root.Logger); err != nil {
 		root.Logger.Error("server failure", zap.Error(err))
 		return 1
 	}
 
+	root.Logger.Info("server stopped")
 	return 0
 }
@@ -11,8 +11,11 @@ const (
 )
 
 const (
+	pathAdmin  = "/admin"
 	pathUser   = "/user"
 	pathInvite = "/invite"
+	pathLock   = "/lockout"
+	pathPass   = "/password"
 
 	paramUserID   = "userID"
 	paramInviteID = "inviteID"
@@ -20,12 +23,23 @@ const (
 
 // AddRoutes TODO
 func (s *Server) AddRoutes(r *httpsvc.Router) {
-	child := r.Child("/%s", APIVersion)
+	child := r.Child("/%s", APIVersion).WithAuthenticator(s)
 
 	child.Add(s.HandleUserCreate(), pathUser)
 	child.Add(s.HandleUserRead(paramUserID), "/%s/%P", pathUser, paramUserID)
 	child.Add(s.HandleUserUpdate(paramUserID), "/%s/%P", pathUser, paramUserID)
 	child.Add(s.HandleUserDelete(paramUserID), "/%s/%P", pathUser, paramUserID)
+	child.Add(s.HandleUserPasswordUpdate(paramUserID), "/%s/%P/%s", pathUser, paramUserID, pathPass)
+	child.Add(s.HandleUserUnlock(paramUserID), "/%s/%P/%s", pathUser, paramUserID, pathLock)
+
+	admin := child.Child(pathAdmin)
+
+	admin.Add(s.HandleAdminUserList(), pathUser)
+	admin.Add(s.HandleAdminUserRead(paramUserID), "/%s/%P", pathUser, paramUserID)
+	admin.Add(s.HandleAdminUserDelete(paramUserID), "/%s/%P", pathUser, paramUserID)
+	admin.Add(s.HandleAdminGrant(paramUserID), "/%s/%P/%s", pathUser, paramUserID, pathAdmin)
+	admin.Add(s.HandleAdminRevoke(paramUserID), "/%s/%P/%s", pathUser, paramUserID, pathAdmin)
+	admin.Add(s.HandleAdminPasswordUpdate(paramUserID), "/%s/%P/%s", pathUser, paramUserID, pathPass)
 
 	child.Add(s.HandleInviteCreate(), pathInvite)
 	child.Add(s.HandleInviteList(), pathInvite)
@@ -1,16 +1,33 @@
 package user
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	httptoken "github.com/oligarch316/go-auth-service/pkg/http/token"
 	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"go.uber.org/zap"
 )
 
+const (
+	// AudienceSignup TODO.
+	AudienceSignup = "signup"
+
+	// AudienceUser TODO.
+	AudienceUser = "user"
+)
+
+const codeInvalidCredentials = "invalid_credentials"
+
 // Server TODO.
 type Server struct {
 	Servelet *httpsvc.Servelet
@@ -20,17 +37,57 @@ type Server struct {
 	}
 
 	Store interface {
-		CreateUserAndDeleteInvite(inviteID, name, password string, mData model.UserUpdate) (model.User, error)
+		CreateUserAndDeleteInvite(ctx context.Context, inviteID, name, password string, mData model.UserUpdate) (model.User, error)
+
+		CreateInvites(ctx context.Context, ownerID string, count int) ([]model.Invite, error)
+		DeleteInvite(ctx context.Context, id string) error
+		LookupInvites(ctx context.Context, ownerID string) ([]model.Invite, error)
+		ReadInvite(ctx context.Context, id string) (model.Invite, error)
 
-		CreateInvites(ownerID string, count int) ([]model.Invite, error)
-		DeleteInvite(id string) error
-		LookupInvites(ownerID string) ([]model.Invite, error)
-		ReadInvite(id string) (model.Invite, error)
+		DeleteUser(ctx context.Context, id string) error
+		ReadUser(ctx context.Context, id string) (model.User, error)
+		ListUsers(ctx context.Context, filter model.UserFilter, offset, limit int) ([]model.User, error)
+		CountUsers(ctx context.Context, filter model.UserFilter) (int, error)
+		UpdateUser(ctx context.Context, id string, mData model.UserUpdate) error
 
-		DeleteUser(id string) error
-		ReadUser(id string) (model.User, error)
-		UpdateUser(id string, mData model.UserUpdate) error
+		CreateRevocation(ctx context.Context, data model.Revocation) error
+		RevokeUserRefreshTokens(ctx context.Context, userID string) error
+
+		DeleteLoginAttempt(ctx context.Context, key string) error
+	}
+
+	// Optional, nil skips pushing new revocations to local validaters
+	Revocations interface {
+		Add(items ...token.Revocation)
 	}
+
+	// Must cover the longest lived token the issuer will sign
+	RevocationTTL time.Duration
+
+	Passwords      *password.Registry
+	PasswordPolicy password.Policy
+}
+
+func (s *Server) userRevoker() httptoken.UserRevoker {
+	return httptoken.UserRevoker{Store: s.Store, Revocations: s.Revocations, TTL: s.RevocationTTL}
+}
+
+// Authenticate TODO.
+func (s *Server) Authenticate(r *http.Request, audience string) (string, error) {
+	switch audience {
+	case AudienceSignup:
+		return s.SignupValidater.Validate(r)
+	case AudienceUser:
+		return s.UserValidater.Validate(r)
+	}
+
+	return "", fmt.Errorf("unknown audience '%s'", audience)
+}
+
+// IsAdmin TODO.
+func (s *Server) IsAdmin(ctx context.Context, subject string) (bool, error) {
+	user, err := s.Store.ReadUser(ctx, subject)
+	return user.Admin, err
 }
 
 type userResponseBody struct {
@@ -49,6 +106,8 @@ func (s *Server) HandleUserCreate() httpsvc.Route {
 		MetricTag:   "user_create",
 	}
 
+	auth := httpsvc.RouteAuth{Audience: AudienceSignup}
+
 	type requestBody struct {
 		Name        string  `json:"name"`
 		Password    string  `json:"password"`
@@ -56,12 +115,7 @@ func (s *Server) HandleUserCreate() httpsvc.Route {
 	}
 
 	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		// Validate signup token
-		inviteID, err := s.SignupValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate signup token"))
-			return
-		}
+		inviteID := httpsvc.Subject(r.Context())
 
 		var reqBody requestBody
 
@@ -71,8 +125,15 @@ func (s *Server) HandleUserCreate() httpsvc.Route {
 			return
 		}
 
+		// Check password policy
+		if err := s.PasswordPolicy.Check(reqBody.Password); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.PasswordPolicyError(err))
+			return
+		}
+
 		// Create user and delete invite
 		user, err := s.Store.CreateUserAndDeleteInvite(
+			r.Context(),
 			inviteID,
 			reqBody.Name,
 			reqBody.Password,
@@ -103,7 +164,7 @@ func (s *Server) HandleUserCreate() httpsvc.Route {
 		w.Write(bytes)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
 
 // HandleUserRead TODO.
@@ -115,27 +176,13 @@ func (s *Server) HandleUserRead(userIDParamName string) httpsvc.Route {
 		MetricTag:   "user_read",
 	}
 
-	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-		// Validate user token
-		userID, err := s.UserValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
-			return
-		}
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, SelfParam: userIDParamName}
 
-		// Confirm id match
-		if userID != params.ByName(userIDParamName) {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(
-				http.StatusForbidden,
-				errors.New("user id does not match url parameter"),
-				"failed to confirm user id",
-			),
-			)
-			return
-		}
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		userID := httpsvc.Subject(r.Context())
 
 		// Read user data
-		user, err := s.Store.ReadUser(userID)
+		user, err := s.Store.ReadUser(r.Context(), userID)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
 			return
@@ -160,7 +207,7 @@ func (s *Server) HandleUserRead(userIDParamName string) httpsvc.Route {
 		w.Write(bytes)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
 
 // HandleUserUpdate TODO.
@@ -172,28 +219,14 @@ func (s *Server) HandleUserUpdate(userIDParamName string) httpsvc.Route {
 		MetricTag:   "user_update",
 	}
 
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, SelfParam: userIDParamName}
+
 	type requestBody struct {
 		DisplayName *string `json:"displayName"`
 	}
 
-	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-		// Validate user token
-		userID, err := s.UserValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
-			return
-		}
-
-		// Confirm id match
-		if userID != params.ByName(userIDParamName) {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(
-				http.StatusForbidden,
-				errors.New("user id does not match url parameter"),
-				"failed to confirm user id",
-			),
-			)
-			return
-		}
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		userID := httpsvc.Subject(r.Context())
 
 		var reqBody requestBody
 
@@ -204,13 +237,13 @@ func (s *Server) HandleUserUpdate(userIDParamName string) httpsvc.Route {
 		}
 
 		// Perform update
-		if err = s.Store.UpdateUser(userID, model.UserUpdate{DisplayName: reqBody.DisplayName}); err != nil {
+		if err := s.Store.UpdateUser(r.Context(), userID, model.UserUpdate{DisplayName: reqBody.DisplayName}); err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to update user"))
 			return
 		}
 
 		// Read user data
-		user, err := s.Store.ReadUser(userID)
+		user, err := s.Store.ReadUser(r.Context(), userID)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
 			return
@@ -235,7 +268,80 @@ func (s *Server) HandleUserUpdate(userIDParamName string) httpsvc.Route {
 		w.Write(bytes)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
+}
+
+// HandleUserPasswordUpdate TODO.
+func (s *Server) HandleUserPasswordUpdate(userIDParamName string) httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "userpasswordupdate",
+		Description: fmt.Sprintf("change password for user with id '%s'", userIDParamName),
+		Method:      http.MethodPut,
+		MetricTag:   "user_password_update",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, SelfParam: userIDParamName}
+
+	type requestBody struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+
+		// Revokes every outstanding token, including the one presented here
+		RevokeAllTokens bool `json:"revokeAllTokens"`
+	}
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		userID := httpsvc.Subject(r.Context())
+
+		var reqBody requestBody
+
+		// Decode request body
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusBadRequest, err, "failed to load request body"))
+			return
+		}
+
+		// Read user data
+		user, err := s.Store.ReadUser(r.Context(), userID)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
+			return
+		}
+
+		// Validate current password
+		if err = user.PasswordHash.Compare(s.Passwords, reqBody.CurrentPassword); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusForbidden, err, "failed to validate current password").WithCode(codeInvalidCredentials))
+			return
+		}
+
+		// Check password policy
+		if err = s.PasswordPolicy.Check(reqBody.NewPassword); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.PasswordPolicyError(err))
+			return
+		}
+
+		// Perform update
+		if err = s.Store.UpdateUser(r.Context(), userID, model.UserUpdate{Password: &reqBody.NewPassword}); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to update password"))
+			return
+		}
+
+		s.Servelet.Emitter.Increment("password.changes")
+
+		// Revoke all outstanding tokens
+		// NOTE: Includes the token presented with this request, clients must login again
+		if reqBody.RevokeAllTokens {
+			if err = s.userRevoker().RevokeUser(r.Context(), userID); err != nil {
+				s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to revoke user tokens"))
+				return
+			}
+		}
+
+		// Respond
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
 
 // HandleUserDelete TODO.
@@ -248,37 +354,76 @@ func (s *Server) HandleUserDelete(userIDParamName string) httpsvc.Route {
 		MetricTag:   "user_delete",
 	}
 
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, SelfParam: userIDParamName}
+
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		userID := httpsvc.Subject(r.Context())
+
+		// Perform delete
+		if err := s.Store.DeleteUser(r.Context(), userID); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to delete user"))
+			return
+		}
+
+		// Revoke outstanding tokens
+		if err := s.userRevoker().RevokeSubject(r.Context(), userID); err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to revoke user tokens"))
+			return
+		}
+
+		// Respond
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
+}
+
+// HandleUserUnlock TODO.
+func (s *Server) HandleUserUnlock(userIDParamName string) httpsvc.Route {
+	info := httpsvc.RouteInfo{
+		Name:        "userunlock",
+		Description: fmt.Sprintf("clear failed login attempts for user with id '%s'", userIDParamName),
+		Method:      http.MethodDelete,
+		MetricTag:   "user_unlock",
+	}
+
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+
 	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-		// Validate user token
-		userID, err := s.UserValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
+		// Read user id from url
+		userID := params.ByName(userIDParamName)
+		if userID == "" {
+			s.Servelet.HandleErr(w, r, httpsvc.URLParamError(userIDParamName))
 			return
 		}
 
-		// Confirm id match
-		if userID != params.ByName(userIDParamName) {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(
-				http.StatusForbidden,
-				errors.New("user id does not match url parameter"),
-				"failed to confirm user id",
-			),
-			)
+		// Read user data
+		user, err := s.Store.ReadUser(r.Context(), userID)
+		if err != nil {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
 			return
 		}
 
-		// Perform delete
-		if err = s.Store.DeleteUser(userID); err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to delete user"))
+		// Clear failed login attempts
+		err = s.Store.DeleteLoginAttempt(r.Context(), model.LoginUserKey(user.Name))
+		if err != nil && !errors.Is(err, storeerr.ErrNotFound) {
+			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to clear login attempts"))
 			return
 		}
 
+		s.Servelet.Emitter.Increment("login.unlocks")
+		s.Servelet.RequestLogger(r).Info(
+			"cleared login lockout",
+			zap.String("userID", userID),
+			zap.String("adminID", httpsvc.Subject(r.Context())),
+		)
+
 		// Respond
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusNoContent)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
 
 type inviteResponseBody struct {
@@ -295,6 +440,8 @@ func (s *Server) HandleInviteCreate() httpsvc.Route {
 		MetricTag:   "invite_create",
 	}
 
+	auth := httpsvc.RouteAuth{Audience: AudienceUser, Admin: true}
+
 	type (
 		requestBody struct {
 			Count   int    `json:"count"`
@@ -307,26 +454,6 @@ func (s *Server) HandleInviteCreate() httpsvc.Route {
 	)
 
 	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		// Validate user token
-		userID, err := s.UserValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
-			return
-		}
-
-		// Read user data
-		user, err := s.Store.ReadUser(userID)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read user"))
-			return
-		}
-
-		// Assert user has admin privilages
-		if !user.Admin {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusForbidden, errors.New("user is not an admin"), "failed to confirm admin privilages"))
-			return
-		}
-
 		var reqBody requestBody
 
 		// Decode request body
@@ -336,7 +463,7 @@ func (s *Server) HandleInviteCreate() httpsvc.Route {
 		}
 
 		// Create invites
-		invites, err := s.Store.CreateInvites(reqBody.OwnerID, reqBody.Count)
+		invites, err := s.Store.CreateInvites(r.Context(), reqBody.OwnerID, reqBody.Count)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to create invites"))
 			return
@@ -361,7 +488,7 @@ func (s *Server) HandleInviteCreate() httpsvc.Route {
 		w.Write(bytes)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
 
 // HandleInviteList TODO.
@@ -373,20 +500,17 @@ func (s *Server) HandleInviteList() httpsvc.Route {
 		MetricTag:   "invite_list",
 	}
 
+	auth := httpsvc.RouteAuth{Audience: AudienceUser}
+
 	type responseBody struct {
 		Invites []inviteResponseBody `json:"invites"`
 	}
 
 	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		// Validate user token
-		userID, err := s.UserValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
-			return
-		}
+		userID := httpsvc.Subject(r.Context())
 
 		// Lookup invite data for user id
-		invites, err := s.Store.LookupInvites(userID)
+		invites, err := s.Store.LookupInvites(r.Context(), userID)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to lookup invites for user"))
 			return
@@ -411,7 +535,7 @@ func (s *Server) HandleInviteList() httpsvc.Route {
 		w.Write(bytes)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
 
 // HandleInviteRead TODO.
@@ -423,13 +547,10 @@ func (s *Server) HandleInviteRead(inviteIDParamName string) httpsvc.Route {
 		MetricTag:   "invite_read",
 	}
 
+	auth := httpsvc.RouteAuth{Audience: AudienceUser}
+
 	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-		// Validate user token
-		userID, err := s.UserValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
-			return
-		}
+		userID := httpsvc.Subject(r.Context())
 
 		// Read invite id from url
 		inviteID := params.ByName(inviteIDParamName)
@@ -439,7 +560,7 @@ func (s *Server) HandleInviteRead(inviteIDParamName string) httpsvc.Route {
 		}
 
 		// Read invite data
-		invite, err := s.Store.ReadInvite(inviteID)
+		invite, err := s.Store.ReadInvite(r.Context(), inviteID)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read invite data"))
 			return
@@ -468,7 +589,7 @@ func (s *Server) HandleInviteRead(inviteIDParamName string) httpsvc.Route {
 		w.Write(bytes)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
 
 // HandleInviteDelete TODO.
@@ -480,13 +601,10 @@ func (s *Server) HandleInviteDelete(inviteIDParamName string) httpsvc.Route {
 		MetricTag:   "invite_delete",
 	}
 
+	auth := httpsvc.RouteAuth{Audience: AudienceUser}
+
 	handle := func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-		// Validate user token
-		userID, err := s.UserValidater.Validate(r)
-		if err != nil {
-			s.Servelet.HandleErr(w, r, httpsvc.NewError(http.StatusUnauthorized, err, "failed to validate user token"))
-			return
-		}
+		userID := httpsvc.Subject(r.Context())
 
 		// Read invite id from url
 		inviteID := params.ByName(inviteIDParamName)
@@ -496,7 +614,7 @@ func (s *Server) HandleInviteDelete(inviteIDParamName string) httpsvc.Route {
 		}
 
 		// Read invite data
-		invite, err := s.Store.ReadInvite(inviteID)
+		invite, err := s.Store.ReadInvite(r.Context(), inviteID)
 		if err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to read invite data"))
 			return
@@ -513,7 +631,7 @@ func (s *Server) HandleInviteDelete(inviteIDParamName string) httpsvc.Route {
 		}
 
 		// Perform delete
-		if err = s.Store.DeleteInvite(inviteID); err != nil {
+		if err = s.Store.DeleteInvite(r.Context(), inviteID); err != nil {
 			s.Servelet.HandleErr(w, r, httpsvc.StoreError(err, "failed to delete invite"))
 			return
 		}
@@ -523,5 +641,5 @@ func (s *Server) HandleInviteDelete(inviteIDParamName string) httpsvc.Route {
 		w.WriteHeader(http.StatusNoContent)
 	}
 
-	return httpsvc.Route{RouteInfo: info, Handle: handle}
+	return httpsvc.Route{RouteInfo: info, Auth: &auth, Handle: handle}
 }
@@ -0,0 +1,351 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/oligarch316/go-auth-service/pkg/http"
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/secret"
+	secrettoken "github.com/oligarch316/go-auth-service/pkg/secret/token"
+	"github.com/oligarch316/go-auth-service/pkg/store"
+	"github.com/oligarch316/go-auth-service/pkg/store/sqlite"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	statsd "gopkg.in/alexcesaro/statsd.v2"
+)
+
+const testIssuer = "test-issuer"
+
+// testEnv is a user server backed by an in memory sqlite store, served by its
+// own router, along with the signer standing in for the token service
+type testEnv struct {
+	*Server
+
+	db     store.Backend
+	signer *secrettoken.Signer
+	router *httpsvc.Router
+}
+
+func testServelet(t *testing.T) *httpsvc.Servelet {
+	t.Helper()
+
+	emitter, err := statsd.New(statsd.Mute(true))
+	if err != nil {
+		t.Fatalf("failed to create emitter: %s", err)
+	}
+
+	return &httpsvc.Servelet{Corelet: &observ.Corelet{Logger: zap.NewNop(), Emitter: emitter}}
+}
+
+func newTestSigner(t *testing.T) *secrettoken.Signer {
+	t.Helper()
+
+	store, err := secret.Generate(jwa.EC).Store()
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	key, err := secret.NewPrivate(jwa.ES256, store)
+	if err != nil {
+		t.Fatalf("failed to create private key: %s", err)
+	}
+
+	res, err := secrettoken.NewSigner(key)
+	if err != nil {
+		t.Fatalf("failed to create signer: %s", err)
+	}
+
+	return res
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	passwords := password.NewRegistry(password.Bcrypt{Cost: bcrypt.MinCost})
+	servelet := testServelet(t)
+
+	db, err := sqlite.New(sqlite.DefaultConfig(), passwords, servelet.Corelet)
+	if err != nil {
+		t.Fatalf("failed to create sqlite store: %s", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	var (
+		signer      = newTestSigner(t)
+		revocations = token.NewRevocationList()
+		validater   = func(audience string) token.Validater {
+			return token.Validater{
+				Claims:      token.ConfigValidater{AllowedIssuers: ctype.NewStringSet(testIssuer), AudienceName: audience},
+				Secret:      signer,
+				Revocations: revocations,
+			}
+		}
+	)
+
+	res := &testEnv{
+		Server: &Server{
+			Servelet:        servelet,
+			SignupValidater: validater(AudienceSignup),
+			UserValidater:   validater(AudienceUser),
+			Store:           db,
+			Revocations:     revocations,
+			RevocationTTL:   time.Hour,
+			Passwords:       passwords,
+			PasswordPolicy:  password.DefaultPolicy(),
+		},
+		db:     db,
+		signer: signer,
+		router: httpsvc.NewRouter(servelet, "/"),
+	}
+
+	res.AddRoutes(res.router)
+	return res
+}
+
+func (te *testEnv) createUser(t *testing.T, name, pass string, admin bool) model.User {
+	t.Helper()
+
+	res, err := te.db.CreateUser(context.Background(), name, pass, model.UserUpdate{Admin: &admin})
+	if err != nil {
+		t.Fatalf("failed to create user '%s': %s", name, err)
+	}
+
+	return res
+}
+
+// Sign a user token for subject, as the token service would
+func (te *testEnv) userToken(t *testing.T, subject string) string {
+	t.Helper()
+	return te.userTokenAt(t, subject, time.Now())
+}
+
+func (te *testEnv) userTokenAt(t *testing.T, subject string, issuedAt time.Time) string {
+	t.Helper()
+
+	var jti [16]byte
+	if _, err := rand.Read(jti[:]); err != nil {
+		t.Fatalf("failed to generate token id: %s", err)
+	}
+
+	res, err := te.signer.Sign(token.StandardClaims{
+		Issuer:     testIssuer,
+		Subject:    subject,
+		TokenID:    hex.EncodeToString(jti[:]),
+		Audience:   ctype.NewStringSet(AudienceUser),
+		Expiration: &token.NumericDate{Time: issuedAt.Add(time.Hour)},
+		IssuedAt:   &token.NumericDate{Time: issuedAt},
+	})
+
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err)
+	}
+
+	return res
+}
+
+func (te *testEnv) do(t *testing.T, method, urlPath string, body interface{}, bearer string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatalf("failed to encode request body: %s", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, urlPath, &reqBody)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	rec := httptest.NewRecorder()
+	te.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// Confirm the stored password for name
+func (te *testEnv) checkPassword(t *testing.T, name, pass string) bool {
+	t.Helper()
+
+	user, err := te.db.LookupUser(context.Background(), name)
+	if err != nil {
+		t.Fatalf("failed to lookup user '%s': %s", name, err)
+	}
+
+	return user.PasswordHash.Compare(te.Passwords, pass) == nil
+}
+
+func userPath(userID string, elems ...string) string {
+	return path.Join(append([]string{"/", APIVersion, pathUser, userID}, elems...)...)
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, expected int) {
+	t.Helper()
+
+	if rec.Code != expected {
+		t.Fatalf("expected status %d, got %d: %s", expected, rec.Code, rec.Body.String())
+	}
+}
+
+func expectCode(t *testing.T, rec *httptest.ResponseRecorder, expected string) {
+	t.Helper()
+
+	var body struct {
+		Code string `json:"code"`
+	}
+
+	decodeBody(t, rec, &body)
+
+	if body.Code != expected {
+		t.Errorf("expected code '%s', got '%s'", expected, body.Code)
+	}
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response body '%s': %s", rec.Body.String(), err)
+	}
+}
+
+func TestHandleUserPasswordUpdate(t *testing.T) {
+	const (
+		oldPass = "old alice password"
+		newPass = "new alice password"
+	)
+
+	body := func(current, next string, revokeAll bool) map[string]interface{} {
+		return map[string]interface{}{"currentPassword": current, "newPassword": next, "revokeAllTokens": revokeAll}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		te := newTestEnv(t)
+		alice := te.createUser(t, "alice", oldPass, false)
+		bearer := te.userToken(t, alice.ID)
+
+		expectStatus(t, te.do(t, http.MethodPut, userPath(alice.ID, pathPass), body(oldPass, newPass, false), bearer), http.StatusNoContent)
+
+		if !te.checkPassword(t, "alice", newPass) || te.checkPassword(t, "alice", oldPass) {
+			t.Error("expected password to be replaced")
+		}
+
+		// Tokens outlive the change unless asked otherwise
+		expectStatus(t, te.do(t, http.MethodGet, userPath(alice.ID), nil, bearer), http.StatusOK)
+	})
+
+	t.Run("revoke all tokens", func(t *testing.T) {
+		te := newTestEnv(t)
+		alice := te.createUser(t, "alice", oldPass, false)
+
+		// NOTE: Revocations cover tokens issued before the current second
+		var (
+			earlier = time.Now().Add(-2 * time.Second)
+			bearer  = te.userTokenAt(t, alice.ID, earlier)
+			other   = te.userTokenAt(t, alice.ID, earlier)
+		)
+
+		expectStatus(t, te.do(t, http.MethodPut, userPath(alice.ID, pathPass), body(oldPass, newPass, true), bearer), http.StatusNoContent)
+
+		// Including the token presented with the change
+		expectStatus(t, te.do(t, http.MethodGet, userPath(alice.ID), nil, bearer), http.StatusUnauthorized)
+		expectStatus(t, te.do(t, http.MethodGet, userPath(alice.ID), nil, other), http.StatusUnauthorized)
+
+		// ... but not those issued after, i.e. on the next login
+		later := te.userTokenAt(t, alice.ID, time.Now().Add(time.Second))
+		expectStatus(t, te.do(t, http.MethodGet, userPath(alice.ID), nil, later), http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		body         interface{}
+		otherUser    bool
+		noToken      bool
+		expectStatus int
+		expectCode   string
+	}{
+		{
+			name:         "wrong current password",
+			body:         body("wrong password", newPass, false),
+			expectStatus: http.StatusForbidden,
+			expectCode:   codeInvalidCredentials,
+		},
+		{
+			name:         "policy violation",
+			body:         body(oldPass, "short", false),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   httpsvc.CodePasswordPolicy,
+		},
+		{
+			name:         "banned password",
+			body:         body(oldPass, "password1", false),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   httpsvc.CodePasswordPolicy,
+		},
+		{
+			name:         "malformed body",
+			body:         "not an object",
+			expectStatus: http.StatusBadRequest,
+			expectCode:   httpsvc.CodeBadRequest,
+		},
+		{
+			name:         "other user",
+			body:         body(oldPass, newPass, false),
+			otherUser:    true,
+			expectStatus: http.StatusForbidden,
+			expectCode:   httpsvc.CodeForbidden,
+		},
+		{
+			name:         "no token",
+			body:         body(oldPass, newPass, false),
+			noToken:      true,
+			expectStatus: http.StatusUnauthorized,
+			expectCode:   httpsvc.CodeUnauthenticated,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			te := newTestEnv(t)
+
+			var (
+				alice  = te.createUser(t, "alice", oldPass, false)
+				bob    = te.createUser(t, "bob", "bob password", false)
+				bearer = te.userToken(t, alice.ID)
+				target = alice.ID
+			)
+
+			if test.otherUser {
+				target = bob.ID
+			}
+
+			if test.noToken {
+				bearer = ""
+			}
+
+			rec := te.do(t, http.MethodPut, userPath(target, pathPass), test.body, bearer)
+			expectStatus(t, rec, test.expectStatus)
+			expectCode(t, rec, test.expectCode)
+
+			if !te.checkPassword(t, "alice", oldPass) || !te.checkPassword(t, "bob", "bob password") {
+				t.Error("expected passwords unchanged")
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+const (
+	loginKeyPrefixUser = "user:"
+	loginKeyPrefixAddr = "addr:"
+
+	resetKeyPrefixUser = "reset-user:"
+	resetKeyPrefixAddr = "reset-addr:"
+)
+
+// LoginUserKey TODO
+func LoginUserKey(name string) string { return loginKeyPrefixUser + name }
+
+// LoginAddrKey TODO
+func LoginAddrKey(addr string) string { return loginKeyPrefixAddr + addr }
+
+// ResetUserKey TODO
+func ResetUserKey(name string) string { return resetKeyPrefixUser + name }
+
+// ResetAddrKey TODO
+func ResetAddrKey(addr string) string { return resetKeyPrefixAddr + addr }
+
+// LoginAttempt TODO
+//
+// Failed login tracking for a single key, e.g. a user name or client address.
+type LoginAttempt struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+}
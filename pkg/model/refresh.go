@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// RefreshToken TODO
+type RefreshToken struct {
+	ID         string
+	FamilyID   string
+	UserID     string
+	Expiration time.Time
+	Rotated    bool
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Revocation TODO
+//
+// Revokes the single token identified by TokenID when set, otherwise every
+// token for Subject issued before IssuedBefore.
+type Revocation struct {
+	TokenID      string
+	Subject      string
+	IssuedBefore time.Time
+	Expiration   time.Time
+}
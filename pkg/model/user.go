@@ -1,24 +1,27 @@
 package model
 
-import "golang.org/x/crypto/bcrypt"
-
-const hashCost = bcrypt.DefaultCost
+import "github.com/oligarch316/go-auth-service/pkg/password"
 
 // PasswordHash TODO
 type PasswordHash []byte
 
 // Compare TODO
-func (p PasswordHash) Compare(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(p), []byte(password))
+func (p PasswordHash) Compare(passwords *password.Registry, pw string) error {
+	return passwords.Verify(string(p), pw)
 }
 
 // Set TODO
-func (p *PasswordHash) Set(password string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), hashCost)
+func (p *PasswordHash) Set(passwords *password.Registry, pw string) error {
+	hash, err := passwords.Hash(pw)
 	*p = PasswordHash(hash)
 	return err
 }
 
+// NeedsRehash TODO
+func (p PasswordHash) NeedsRehash(passwords *password.Registry) bool {
+	return passwords.NeedsRehash(string(p))
+}
+
 // User TODO
 type User struct {
 	ID           string
@@ -28,6 +31,12 @@ type User struct {
 	Admin        bool
 }
 
+// UserFilter TODO
+type UserFilter struct {
+	NamePrefix string
+	Admin      *bool
+}
+
 // UserUpdate TODO
 type UserUpdate struct {
 	DisplayName *string
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ConfigLocal TODO
+type ConfigLocal struct {
+	Path string `json:"path"`
+}
+
+// MarshalLogObject TODO
+func (cl ConfigLocal) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("path", cl.Path)
+	return nil
+}
+
+// Local TODO
+//
+// Intended for development. Messages, secrets included, are appended to the
+// configured path as JSON lines. Bodies are only logged at debug level.
+type Local struct {
+	path   string
+	logger *zap.Logger
+	mu     sync.Mutex
+}
+
+// NewLocal TODO
+func NewLocal(cfg ConfigLocal, logger *zap.Logger) *Local {
+	return &Local{path: cfg.Path, logger: logger}
+}
+
+// Notify TODO
+func (l *Local) Notify(_ context.Context, msg Message) error {
+	l.logger.Info("notification", zap.String("to", msg.To), zap.String("subject", msg.Subject))
+	l.logger.Debug("notification body", zap.String("to", msg.To), zap.String("body", msg.Body))
+
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(struct {
+		Time time.Time `json:"time"`
+		Message
+	}{Time: time.Now().UTC(), Message: msg})
+
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	typeLocal = "local"
+	typeSMTP  = "smtp"
+)
+
+// Message TODO
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier TODO
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Config TODO
+type Config struct {
+	Type  string      `json:"type"`
+	Local ConfigLocal `json:"local"`
+	SMTP  ConfigSMTP  `json:"smtp"`
+}
+
+// DefaultConfig TODO
+//
+// No type is configured by default, the development only "local" type must be
+// chosen explicitly.
+func DefaultConfig() Config {
+	return Config{
+		SMTP: ConfigSMTP{Timeout: ctype.Duration{Duration: 10 * time.Second}},
+	}
+}
+
+// Build TODO
+//
+// Returns a nil Notifier when no type is configured.
+func (c Config) Build(logger *zap.Logger) (Notifier, error) {
+	switch c.Type {
+	case "":
+		return nil, nil
+	case typeLocal:
+		return NewLocal(c.Local, logger), nil
+	case typeSMTP:
+		return NewSMTP(c.SMTP)
+	}
+
+	return nil, fmt.Errorf("unknown notifier type '%s'", c.Type)
+}
+
+// MarshalLogObject TODO
+func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("type", c.Type)
+
+	switch c.Type {
+	case typeLocal:
+		return enc.AddObject("local", c.Local)
+	case typeSMTP:
+		return enc.AddObject("smtp", c.SMTP)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"go.uber.org/zap/zapcore"
+)
+
+const redactedMsg = "<REDACTED>"
+
+// ConfigSMTP TODO
+type ConfigSMTP struct {
+	Address  string         `json:"address"`
+	From     string         `json:"from"`
+	Domain   string         `json:"domain"`
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	StartTLS bool           `json:"startTLS"`
+	Timeout  ctype.Duration `json:"timeout"`
+}
+
+// MarshalLogObject TODO
+func (cs ConfigSMTP) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("address", cs.Address)
+	enc.AddString("from", cs.From)
+	enc.AddString("domain", cs.Domain)
+	enc.AddString("username", cs.Username)
+	enc.AddBool("startTLS", cs.StartTLS)
+	enc.AddDuration("timeout", cs.Timeout.Duration)
+
+	if cs.Password != "" {
+		enc.AddString("password", redactedMsg)
+	}
+
+	return nil
+}
+
+// SMTP TODO
+type SMTP struct {
+	address, host, domain string
+	from                  *mail.Address
+	auth                  smtp.Auth
+	startTLS              bool
+	timeout               time.Duration
+}
+
+// NewSMTP TODO
+func NewSMTP(cfg ConfigSMTP) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp from address: %w", err)
+	}
+
+	res := &SMTP{
+		address:  cfg.Address,
+		host:     host,
+		domain:   cfg.Domain,
+		from:     from,
+		startTLS: cfg.StartTLS,
+		timeout:  cfg.Timeout.Duration,
+	}
+
+	// NOTE: PlainAuth refuses to send credentials over an unencrypted
+	// connection to anything but localhost
+	if cfg.Username != "" {
+		res.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+
+	return res, nil
+}
+
+// Users are addressed by name, qualified with the configured domain when the
+// name is not already an address
+func (s *SMTP) recipient(to string) (*mail.Address, error) {
+	if !strings.Contains(to, "@") {
+		if s.domain == "" {
+			return nil, fmt.Errorf("recipient '%s' is not an address and no domain is configured", to)
+		}
+		to += "@" + s.domain
+	}
+
+	return mail.ParseAddress(to)
+}
+
+func (s *SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bound the whole exchange, not just the dial
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Notify TODO
+func (s *SMTP) Notify(ctx context.Context, msg Message) error {
+	to, err := s.recipient(msg.To)
+	if err != nil {
+		return err
+	}
+
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("invalid subject: contains line break")
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	if s.startTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(s.format(to, msg)); err != nil {
+		w.Close()
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (s *SMTP) format(to *mail.Address, msg Message) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + s.from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+
+	body := strings.Replace(msg.Body, "\r\n", "\n", -1)
+	b.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	b.WriteString("\r\n")
+
+	return []byte(b.String())
+}
@@ -0,0 +1,240 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+)
+
+// fakeSMTP is a minimal single session SMTP server recording what it receives
+type fakeSMTP struct {
+	listener net.Listener
+	startTLS bool
+
+	rcpt chan string
+	data chan string
+}
+
+func newFakeSMTP(t *testing.T, startTLS bool) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	res := &fakeSMTP{
+		listener: listener,
+		startTLS: startTLS,
+		rcpt:     make(chan string, 1),
+		data:     make(chan string, 1),
+	}
+
+	t.Cleanup(func() { listener.Close() })
+	go res.serve()
+
+	return res
+}
+
+func (fs *fakeSMTP) serve() {
+	conn, err := fs.listener.Accept()
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO":
+			if fs.startTLS {
+				tp.PrintfLine("250-fake")
+				tp.PrintfLine("250 STARTTLS")
+			} else {
+				tp.PrintfLine("250 fake")
+			}
+		case "MAIL":
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			fs.rcpt <- strings.TrimPrefix(line, "RCPT TO:")
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+
+			body, err := readData(tp.R)
+			if err != nil {
+				return
+			}
+
+			fs.data <- body
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// Raw DATA payload up to the terminating ".", line endings left untouched
+func readData(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+
+		if line == ".\r\n" {
+			return b.String(), nil
+		}
+
+		b.WriteString(line)
+	}
+}
+
+func newTestSMTP(t *testing.T, fs *fakeSMTP, startTLS bool) *SMTP {
+	t.Helper()
+
+	res, err := NewSMTP(ConfigSMTP{
+		Address:  fs.listener.Addr().String(),
+		From:     "auth@example.com",
+		Domain:   "example.com",
+		StartTLS: startTLS,
+		Timeout:  ctype.Duration{Duration: 5 * time.Second},
+	})
+
+	if err != nil {
+		t.Fatalf("failed to create smtp notifier: %s", err)
+	}
+
+	return res
+}
+
+func TestSMTPStartTLSRequired(t *testing.T) {
+	fs := newFakeSMTP(t, false)
+	notifier := newTestSMTP(t, fs, true)
+
+	err := notifier.Notify(context.Background(), Message{To: "alice", Subject: "test", Body: "secret"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("expected STARTTLS refusal, got: %v", err)
+	}
+
+	select {
+	case <-fs.rcpt:
+		t.Fatal("message sent over an unencrypted connection")
+	default:
+	}
+}
+
+func TestSMTPRecipient(t *testing.T) {
+	fs := newFakeSMTP(t, false)
+	notifier := newTestSMTP(t, fs, false)
+
+	if err := notifier.Notify(context.Background(), Message{To: "alice", Subject: "test", Body: "body"}); err != nil {
+		t.Fatalf("failed to notify: %s", err)
+	}
+
+	if actual := <-fs.rcpt; actual != "<alice@example.com>" {
+		t.Errorf("expected recipient '<alice@example.com>', got '%s'", actual)
+	}
+}
+
+func TestSMTPRecipientInvalid(t *testing.T) {
+	notifier, err := NewSMTP(ConfigSMTP{Address: "127.0.0.1:25", From: "auth@example.com"})
+	if err != nil {
+		t.Fatalf("failed to create smtp notifier: %s", err)
+	}
+
+	for _, to := range []string{"alice", "not an address@"} {
+		if _, err := notifier.recipient(to); err == nil {
+			t.Errorf("expected error for recipient '%s'", to)
+		}
+	}
+
+	actual, err := notifier.recipient("bob@example.org")
+	if err != nil {
+		t.Fatalf("failed to parse recipient: %s", err)
+	}
+
+	if actual.Address != "bob@example.org" {
+		t.Errorf("expected address 'bob@example.org', got '%s'", actual.Address)
+	}
+}
+
+func TestSMTPBodyLineEndings(t *testing.T) {
+	fs := newFakeSMTP(t, false)
+	notifier := newTestSMTP(t, fs, false)
+
+	msg := Message{To: "alice", Subject: "test", Body: "one\ntwo\r\nthree"}
+	if err := notifier.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("failed to notify: %s", err)
+	}
+
+	data := <-fs.data
+
+	idx := strings.Index(data, "\r\n\r\n")
+	if idx < 0 {
+		t.Fatalf("missing header terminator in: %q", data)
+	}
+
+	if strings.Contains(strings.Replace(data, "\r\n", "", -1), "\n") {
+		t.Errorf("bare line feed in message: %q", data)
+	}
+
+	if actual, expected := data[idx+4:], "one\r\ntwo\r\nthree\r\n"; actual != expected {
+		t.Errorf("expected body %q, got %q", expected, actual)
+	}
+}
+
+func TestSMTPSubjectLineBreak(t *testing.T) {
+	fs := newFakeSMTP(t, false)
+	notifier := newTestSMTP(t, fs, false)
+
+	err := notifier.Notify(context.Background(), Message{To: "alice", Subject: "test\r\nBcc: eve@example.com", Body: "body"})
+	if err == nil {
+		t.Fatal("expected error for subject containing a line break")
+	}
+}
+
+func TestSMTPFormatLineEndings(t *testing.T) {
+	notifier, err := NewSMTP(ConfigSMTP{Address: "127.0.0.1:25", From: "auth@example.com", Domain: "example.com"})
+	if err != nil {
+		t.Fatalf("failed to create smtp notifier: %s", err)
+	}
+
+	to, err := notifier.recipient("alice")
+	if err != nil {
+		t.Fatalf("failed to parse recipient: %s", err)
+	}
+
+	// NOTE: Checked before the DATA writer, which would hide bare line feeds
+	formatted := string(notifier.format(to, Message{Subject: "test", Body: "one\ntwo\r\nthree"}))
+
+	if !strings.HasSuffix(formatted, "\r\n\r\none\r\ntwo\r\nthree\r\n") {
+		t.Errorf("unexpected body in: %q", formatted)
+	}
+
+	if strings.Contains(strings.Replace(formatted, "\r\n", "", -1), "\n") || strings.Contains(formatted, "\r\r") {
+		t.Errorf("mixed line endings in: %q", formatted)
+	}
+}
@@ -0,0 +1,145 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/crypto/argon2"
+)
+
+const idArgon2id = "argon2id"
+
+// Argon2id TODO
+type Argon2id struct {
+	Memory     uint32 `json:"memory"` // KiB
+	Iterations uint32 `json:"iterations"`
+	Threads    uint8  `json:"threads"`
+	SaltLength int    `json:"saltLength"`
+	KeyLength  uint32 `json:"keyLength"`
+}
+
+// DefaultArgon2id TODO
+func DefaultArgon2id() Argon2id {
+	return Argon2id{
+		Memory:     64 * 1024,
+		Iterations: 3,
+		Threads:    4,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
+}
+
+func (a Argon2id) validate() error {
+	switch {
+	case a.Iterations < 1:
+		return errors.New("argon2id iterations must be positive")
+	case a.Threads < 1:
+		return errors.New("argon2id threads must be positive")
+	case a.Memory < 8*uint32(a.Threads):
+		return fmt.Errorf("argon2id memory must be at least %d KiB", 8*uint32(a.Threads))
+	case a.SaltLength < 8:
+		return errors.New("argon2id salt length must be at least 8")
+	case a.KeyLength < 16:
+		return errors.New("argon2id key length must be at least 16")
+	}
+	return nil
+}
+
+// ID TODO
+func (Argon2id) ID() string { return idArgon2id }
+
+// Hash TODO
+func (a Argon2id) Hash(password string) (string, error) {
+	salt, err := newSalt(a.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	return phcString{
+		id:      idArgon2id,
+		version: argon2.Version,
+		params: map[string]string{
+			"m": strconv.FormatUint(uint64(a.Memory), 10),
+			"t": strconv.FormatUint(uint64(a.Iterations), 10),
+			"p": strconv.FormatUint(uint64(a.Threads), 10),
+		},
+		salt: salt,
+		hash: argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Threads, a.KeyLength),
+	}.String(), nil
+}
+
+func (Argon2id) parse(hash string) (phcString, Argon2id, error) {
+	var params Argon2id
+
+	res, err := parsePHC(hash)
+	if err != nil {
+		return res, params, err
+	}
+
+	if res.id != idArgon2id {
+		return res, params, fmt.Errorf("%w: '%s'", ErrUnknownAlgorithm, res.id)
+	}
+
+	if res.version != argon2.Version {
+		return res, params, fmt.Errorf("%w: unsupported argon2 version %d", ErrMalformedHash, res.version)
+	}
+
+	m, err := res.intParam("m")
+	if err != nil {
+		return res, params, err
+	}
+
+	t, err := res.intParam("t")
+	if err != nil {
+		return res, params, err
+	}
+
+	p, err := res.intParam("p")
+	if err != nil {
+		return res, params, err
+	}
+
+	if m > math.MaxUint32 || t > math.MaxUint32 || p > math.MaxUint8 {
+		return res, params, fmt.Errorf("%w: parameter out of range", ErrMalformedHash)
+	}
+
+	params = Argon2id{
+		Memory:     uint32(m),
+		Iterations: uint32(t),
+		Threads:    uint8(p),
+		SaltLength: len(res.salt),
+		KeyLength:  uint32(len(res.hash)),
+	}
+
+	return res, params, nil
+}
+
+// Verify TODO
+func (a Argon2id) Verify(hash, password string) error {
+	data, params, err := a.parse(hash)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), data.salt, params.Iterations, params.Memory, params.Threads, params.KeyLength)
+	return compareKeys(key, data.hash)
+}
+
+// NeedsRehash TODO
+func (a Argon2id) NeedsRehash(hash string) bool {
+	_, params, err := a.parse(hash)
+	return err != nil || params != a
+}
+
+// MarshalLogObject TODO
+func (a Argon2id) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddUint32("memory", a.Memory)
+	enc.AddUint32("iterations", a.Iterations)
+	enc.AddUint8("threads", a.Threads)
+	enc.AddInt("saltLength", a.SaltLength)
+	enc.AddUint32("keyLength", a.KeyLength)
+	return nil
+}
@@ -0,0 +1,56 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const idBcrypt = "bcrypt"
+
+// Bcrypt TODO
+type Bcrypt struct {
+	Cost int `json:"cost"`
+}
+
+// DefaultBcrypt TODO
+func DefaultBcrypt() Bcrypt { return Bcrypt{Cost: bcrypt.DefaultCost} }
+
+func (b Bcrypt) validate() error {
+	if b.Cost < bcrypt.MinCost || b.Cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+// ID TODO
+func (Bcrypt) ID() string { return idBcrypt }
+
+// Hash TODO
+func (b Bcrypt) Hash(password string) (string, error) {
+	res, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(res), err
+}
+
+// Verify TODO
+func (Bcrypt) Verify(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
+
+// NeedsRehash TODO
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.Cost
+}
+
+// MarshalLogObject TODO
+func (b Bcrypt) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("cost", b.Cost)
+	return nil
+}
@@ -0,0 +1,77 @@
+package password
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Config TODO
+type Config struct {
+	Preferred string   `json:"preferred"`
+	Bcrypt    Bcrypt   `json:"bcrypt"`
+	Argon2id  Argon2id `json:"argon2id"`
+	Scrypt    Scrypt   `json:"scrypt"`
+}
+
+// DefaultConfig TODO
+func DefaultConfig() Config {
+	return Config{
+		Preferred: idBcrypt,
+		Bcrypt:    DefaultBcrypt(),
+		Argon2id:  DefaultArgon2id(),
+		Scrypt:    DefaultScrypt(),
+	}
+}
+
+// Build TODO
+func (c Config) Build() (*Registry, error) {
+	if err := c.Bcrypt.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := c.Argon2id.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := c.Scrypt.validate(); err != nil {
+		return nil, err
+	}
+
+	var preferred Hasher
+
+	switch c.Preferred {
+	case idBcrypt:
+		preferred = c.Bcrypt
+	case idArgon2id:
+		preferred = c.Argon2id
+	case idScrypt:
+		preferred = c.Scrypt
+	case PBKDF2SHA1.id, PBKDF2SHA256.id, PBKDF2SHA512.id:
+		return nil, fmt.Errorf("%w: '%s' cannot be preferred", ErrVerifyOnly, c.Preferred)
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownAlgorithm, c.Preferred)
+	}
+
+	// NOTE: Every algorithm remains available for verification, so existing
+	// hashes keep working (and are upgraded) after the preference changes
+	res := NewRegistry(
+		preferred,
+		c.Bcrypt,
+		c.Argon2id,
+		c.Scrypt,
+		PBKDF2SHA1,
+		PBKDF2SHA256,
+		PBKDF2SHA512,
+	)
+
+	return res, nil
+}
+
+// MarshalLogObject TODO
+func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("preferred", c.Preferred)
+	enc.AddObject("bcrypt", c.Bcrypt)
+	enc.AddObject("argon2id", c.Argon2id)
+	return enc.AddObject("scrypt", c.Scrypt)
+}
@@ -0,0 +1,241 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Errors
+var (
+	ErrMismatch         = errors.New("password does not match hash")
+	ErrMalformedHash    = errors.New("malformed password hash")
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrVerifyOnly       = errors.New("password hash algorithm is verify only")
+)
+
+// Hasher TODO
+type Hasher interface {
+	ID() string
+	Hash(password string) (string, error)
+	Verify(hash, password string) error
+	NeedsRehash(hash string) bool
+}
+
+// Registry TODO
+type Registry struct {
+	preferred Hasher
+	hashers   map[string]Hasher
+}
+
+// NewRegistry TODO
+func NewRegistry(preferred Hasher, others ...Hasher) *Registry {
+	res := &Registry{
+		preferred: preferred,
+		hashers:   map[string]Hasher{preferred.ID(): preferred},
+	}
+
+	for _, item := range others {
+		if _, ok := res.hashers[item.ID()]; !ok {
+			res.hashers[item.ID()] = item
+		}
+	}
+
+	return res
+}
+
+// Preferred TODO
+func (r *Registry) Preferred() string { return r.preferred.ID() }
+
+func (r *Registry) lookup(hash string) (Hasher, error) {
+	id, err := identify(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if res, ok := r.hashers[id]; ok {
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("%w: '%s'", ErrUnknownAlgorithm, id)
+}
+
+// Hash TODO
+func (r *Registry) Hash(password string) (string, error) { return r.preferred.Hash(password) }
+
+// Verify TODO
+func (r *Registry) Verify(hash, password string) error {
+	hasher, err := r.lookup(hash)
+	if err != nil {
+		return err
+	}
+
+	return hasher.Verify(hash, password)
+}
+
+// NeedsRehash TODO
+func (r *Registry) NeedsRehash(hash string) bool {
+	hasher, err := r.lookup(hash)
+	if err != nil || hasher.ID() != r.preferred.ID() {
+		return true
+	}
+
+	return hasher.NeedsRehash(hash)
+}
+
+// Digests shorter than this are refused, an empty or truncated hash segment
+// must never verify
+const minHashLength = 16
+
+// NOTE: PHC strings take the form
+// $<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*][$<salt>[$<hash>]]
+// with salt and hash in unpadded standard base64
+
+type phcString struct {
+	id         string
+	version    int
+	params     map[string]string
+	salt, hash []byte
+}
+
+func identify(hash string) (string, error) {
+	parts := strings.SplitN(hash, "$", 3)
+	if len(parts) < 3 || parts[0] != "" || parts[1] == "" {
+		return "", ErrMalformedHash
+	}
+
+	switch parts[1] {
+	case "2a", "2b", "2y":
+		// Modular crypt bcrypt prefixes
+		return idBcrypt, nil
+	}
+
+	return parts[1], nil
+}
+
+func parsePHC(hash string) (phcString, error) {
+	var res phcString
+
+	parts := strings.Split(hash, "$")
+	if len(parts) < 2 || parts[0] != "" || parts[1] == "" {
+		return res, ErrMalformedHash
+	}
+
+	res.id, parts = parts[1], parts[2:]
+
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "v=") {
+		version, err := strconv.Atoi(strings.TrimPrefix(parts[0], "v="))
+		if err != nil {
+			return res, fmt.Errorf("%w: invalid version", ErrMalformedHash)
+		}
+
+		res.version, parts = version, parts[1:]
+	}
+
+	res.params = make(map[string]string)
+
+	if len(parts) > 0 && strings.Contains(parts[0], "=") {
+		for _, item := range strings.Split(parts[0], ",") {
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return res, fmt.Errorf("%w: invalid parameter '%s'", ErrMalformedHash, item)
+			}
+
+			res.params[kv[0]] = kv[1]
+		}
+
+		parts = parts[1:]
+	}
+
+	if len(parts) != 2 {
+		return res, fmt.Errorf("%w: expected salt and hash", ErrMalformedHash)
+	}
+
+	var err error
+
+	if res.salt, err = decodeB64(parts[0]); err != nil {
+		return res, fmt.Errorf("%w: invalid salt encoding", ErrMalformedHash)
+	}
+
+	if res.hash, err = decodeB64(parts[1]); err != nil {
+		return res, fmt.Errorf("%w: invalid hash encoding", ErrMalformedHash)
+	}
+
+	if len(res.salt) == 0 {
+		return res, fmt.Errorf("%w: empty salt", ErrMalformedHash)
+	}
+
+	if len(res.hash) < minHashLength {
+		return res, fmt.Errorf("%w: hash shorter than %d bytes", ErrMalformedHash, minHashLength)
+	}
+
+	return res, nil
+}
+
+func (ps phcString) intParam(name string) (int, error) {
+	str, ok := ps.params[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: missing parameter '%s'", ErrMalformedHash, name)
+	}
+
+	res, err := strconv.Atoi(str)
+	if err != nil || res <= 0 {
+		return 0, fmt.Errorf("%w: invalid parameter '%s'", ErrMalformedHash, name)
+	}
+
+	return res, nil
+}
+
+func (ps phcString) String() string {
+	var b strings.Builder
+
+	b.WriteString("$" + ps.id)
+
+	if ps.version > 0 {
+		b.WriteString("$v=" + strconv.Itoa(ps.version))
+	}
+
+	b.WriteString("$" + encodeParams(ps.params))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(ps.salt))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(ps.hash))
+	return b.String()
+}
+
+// NOTE: Fixed parameter order keeps generated strings stable
+var paramOrder = []string{"m", "t", "ln", "r", "p", "i", "l"}
+
+func encodeParams(params map[string]string) string {
+	items := make([]string, 0, len(params))
+
+	for _, name := range paramOrder {
+		if val, ok := params[name]; ok {
+			items = append(items, name+"="+val)
+		}
+	}
+
+	return strings.Join(items, ",")
+}
+
+// NOTE: Accepts the "adapted" base64 alphabet ('.' in place of '+') used by
+// passlib, and tolerates trailing padding, so imported hashes decode as is
+func decodeB64(str string) ([]byte, error) {
+	str = strings.TrimRight(strings.Replace(str, ".", "+", -1), "=")
+	return base64.RawStdEncoding.DecodeString(str)
+}
+
+func newSalt(size int) ([]byte, error) {
+	res := make([]byte, size)
+	_, err := rand.Read(res)
+	return res, err
+}
+
+func compareKeys(a, b []byte) error {
+	if len(a) == 0 || len(b) == 0 || subtle.ConstantTimeCompare(a, b) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
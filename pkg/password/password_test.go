@@ -0,0 +1,259 @@
+package password
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Known answer vectors, each verifying "password" unless noted otherwise
+var (
+	// RFC 6070 and its SHA-2 counterparts: salt "salt", 4096 rounds
+	vectorPBKDF2SHA1   = "$pbkdf2$4096$c2FsdA$SwB5AbdlSJq.rUnZJvch0GWkKcE"
+	vectorPBKDF2SHA256 = "$pbkdf2-sha256$i=4096$c2FsdA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o"
+	vectorPBKDF2SHA512 = "$pbkdf2-sha512$4096$c2FsdA$0Zexsz2wFD4BixLz0dFHnmzevcyXxcD4f2kC4HL0V7UUPzBgJkGz1VzTNZiMs2uEN2Bg7NUy4Dm3QqI5Q0ry1Q"
+
+	// RFC 7914: salt "NaCl", N=1024, r=8, p=16
+	vectorScrypt = "$scrypt$ln=10,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA"
+
+	// Argon2 reference implementation: salt "somesalt", t=2, m=64, p=1
+	vectorArgon2id = "$argon2id$v=19$m=64,t=2,p=1$c29tZXNhbHQ$Bo1ismRVk2qm6+YAYLCmWHDb+j3fjUH3"
+
+	// OpenBSD test suite, password "abc"
+	vectorBcrypt = "$2a$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i"
+)
+
+func testRegistry() *Registry {
+	return NewRegistry(
+		Bcrypt{Cost: bcrypt.MinCost},
+		DefaultArgon2id(),
+		DefaultScrypt(),
+		PBKDF2SHA1,
+		PBKDF2SHA256,
+		PBKDF2SHA512,
+	)
+}
+
+func TestRegistryVerify(t *testing.T) {
+	registry := testRegistry()
+
+	tests := []struct {
+		name, hash, password string
+	}{
+		{name: "pbkdf2-sha1", hash: vectorPBKDF2SHA1, password: "password"},
+		{name: "pbkdf2-sha256", hash: vectorPBKDF2SHA256, password: "password"},
+		{name: "pbkdf2-sha512", hash: vectorPBKDF2SHA512, password: "password"},
+		{name: "scrypt", hash: vectorScrypt, password: "password"},
+		{name: "argon2id", hash: vectorArgon2id, password: "password"},
+		{name: "bcrypt", hash: vectorBcrypt, password: "abc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := registry.Verify(test.hash, test.password); err != nil {
+				t.Errorf("expected match, got: %s", err)
+			}
+
+			if err := registry.Verify(test.hash, test.password+"x"); !errors.Is(err, ErrMismatch) {
+				t.Errorf("expected '%s', got: %v", ErrMismatch, err)
+			}
+		})
+	}
+}
+
+func TestRegistryVerifyMalformed(t *testing.T) {
+	registry := testRegistry()
+
+	tests := []struct {
+		name, hash string
+		expected   error
+	}{
+		{name: "empty", hash: "", expected: ErrMalformedHash},
+		{name: "no id", hash: "$$c2FsdA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o", expected: ErrMalformedHash},
+		{name: "unknown id", hash: "$md5$c2FsdA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o", expected: ErrUnknownAlgorithm},
+		{name: "empty hash pbkdf2", hash: "$pbkdf2-sha256$1000$c2FsdHNhbHQ$", expected: ErrMalformedHash},
+		{name: "empty salt pbkdf2", hash: "$pbkdf2-sha256$1000$$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o", expected: ErrMalformedHash},
+		{name: "short hash pbkdf2", hash: "$pbkdf2-sha256$1000$c2FsdA$xeR41ZKI", expected: ErrMalformedHash},
+		{name: "missing hash pbkdf2", hash: "$pbkdf2-sha256$i=1000$c2FsdA", expected: ErrMalformedHash},
+		{name: "zero rounds pbkdf2", hash: "$pbkdf2-sha256$0$c2FsdA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o", expected: ErrMalformedHash},
+		{name: "length mismatch pbkdf2", hash: "$pbkdf2-sha256$i=4096,l=16$c2FsdA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o", expected: ErrMalformedHash},
+		{name: "bad encoding pbkdf2", hash: "$pbkdf2-sha256$4096$c2F*dA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o", expected: ErrMalformedHash},
+		{name: "empty hash scrypt", hash: "$scrypt$ln=10,r=8,p=1$c2FsdHNhbHQ$", expected: ErrMalformedHash},
+		{name: "missing param scrypt", hash: "$scrypt$ln=10,r=8$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI", expected: ErrMalformedHash},
+		{name: "out of range scrypt", hash: "$scrypt$ln=31,r=8,p=1$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI", expected: ErrMalformedHash},
+		{name: "empty hash argon2id", hash: "$argon2id$v=19$m=64,t=2,p=1$c29tZXNhbHQ$", expected: ErrMalformedHash},
+		{name: "empty salt argon2id", hash: "$argon2id$v=19$m=64,t=2,p=1$$Bo1ismRVk2qm6+YAYLCmWHDb+j3fjUH3", expected: ErrMalformedHash},
+		{name: "version argon2id", hash: "$argon2id$v=16$m=64,t=2,p=1$c29tZXNhbHQ$Bo1ismRVk2qm6+YAYLCmWHDb+j3fjUH3", expected: ErrMalformedHash},
+		{name: "bad param argon2id", hash: "$argon2id$v=19$m=x,t=2,p=1$c29tZXNhbHQ$Bo1ismRVk2qm6+YAYLCmWHDb+j3fjUH3", expected: ErrMalformedHash},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := registry.Verify(test.hash, "anything")
+			if !errors.Is(err, test.expected) {
+				t.Errorf("expected '%s', got: %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestDecodeB64(t *testing.T) {
+	tests := []struct {
+		name, encoded string
+	}{
+		{name: "standard", encoded: "SwB5AbdlSJq+rUnZJvch0GWkKcE"},
+		{name: "passlib alphabet", encoded: "SwB5AbdlSJq.rUnZJvch0GWkKcE"},
+		{name: "padded", encoded: "SwB5AbdlSJq+rUnZJvch0GWkKcE="},
+		{name: "passlib padded", encoded: "SwB5AbdlSJq.rUnZJvch0GWkKcE="},
+	}
+
+	expected := "4b007901b765489abead49d926f721d065a429c1"
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := decodeB64(test.encoded)
+			if err != nil {
+				t.Fatalf("failed to decode: %s", err)
+			}
+
+			if hexStr := hex.EncodeToString(actual); hexStr != expected {
+				t.Errorf("expected %s, got %s", expected, hexStr)
+			}
+		})
+	}
+}
+
+func TestCompareKeysEmpty(t *testing.T) {
+	for _, pair := range [][2][]byte{{nil, nil}, {{}, {}}, {nil, {1}}, {{1}, nil}} {
+		if err := compareKeys(pair[0], pair[1]); !errors.Is(err, ErrMismatch) {
+			t.Errorf("compare %v with %v: expected '%s', got: %v", pair[0], pair[1], ErrMismatch, err)
+		}
+	}
+}
+
+func TestRegistryNeedsRehash(t *testing.T) {
+	var (
+		preferred = Argon2id{Memory: 64, Iterations: 1, Threads: 1, SaltLength: 16, KeyLength: 16}
+		registry  = NewRegistry(preferred, Bcrypt{Cost: bcrypt.MinCost}, PBKDF2SHA256)
+	)
+
+	current, err := registry.Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash: %s", err)
+	}
+
+	cheaper := preferred
+	cheaper.Iterations = 2
+
+	stale, err := cheaper.Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash: %s", err)
+	}
+
+	otherAlgorithm, err := Bcrypt{Cost: bcrypt.MinCost}.Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash: %s", err)
+	}
+
+	tests := []struct {
+		name, hash string
+		expected   bool
+	}{
+		{name: "current", hash: current, expected: false},
+		{name: "changed cost", hash: stale, expected: true},
+		{name: "changed algorithm", hash: otherAlgorithm, expected: true},
+		{name: "verify only", hash: vectorPBKDF2SHA256, expected: true},
+		{name: "unknown", hash: "$md5$c2FsdA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o", expected: true},
+		{name: "malformed", hash: "garbage", expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := registry.NeedsRehash(test.hash); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHasherRoundTrip(t *testing.T) {
+	hashers := []Hasher{
+		Bcrypt{Cost: bcrypt.MinCost},
+		Argon2id{Memory: 64, Iterations: 1, Threads: 1, SaltLength: 16, KeyLength: 16},
+		Scrypt{LogN: 4, BlockSize: 8, Parallelism: 1, SaltLength: 16, KeyLength: 16},
+	}
+
+	for _, hasher := range hashers {
+		t.Run(hasher.ID(), func(t *testing.T) {
+			hash, err := hasher.Hash("password")
+			if err != nil {
+				t.Fatalf("failed to hash: %s", err)
+			}
+
+			if err := hasher.Verify(hash, "password"); err != nil {
+				t.Errorf("expected match, got: %s", err)
+			}
+
+			if err := hasher.Verify(hash, "Password"); !errors.Is(err, ErrMismatch) {
+				t.Errorf("expected '%s', got: %v", ErrMismatch, err)
+			}
+
+			if hasher.NeedsRehash(hash) {
+				t.Error("fresh hash reported as needing rehash")
+			}
+		})
+	}
+}
+
+func TestHasherNeedsRehashCost(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, changed Hasher
+	}{
+		{
+			name:    "bcrypt",
+			current: Bcrypt{Cost: bcrypt.MinCost},
+			changed: Bcrypt{Cost: bcrypt.MinCost + 1},
+		},
+		{
+			name:    "argon2id",
+			current: Argon2id{Memory: 64, Iterations: 1, Threads: 1, SaltLength: 16, KeyLength: 16},
+			changed: Argon2id{Memory: 128, Iterations: 1, Threads: 1, SaltLength: 16, KeyLength: 16},
+		},
+		{
+			name:    "scrypt",
+			current: Scrypt{LogN: 4, BlockSize: 8, Parallelism: 1, SaltLength: 16, KeyLength: 16},
+			changed: Scrypt{LogN: 5, BlockSize: 8, Parallelism: 1, SaltLength: 16, KeyLength: 16},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := test.current.Hash("password")
+			if err != nil {
+				t.Fatalf("failed to hash: %s", err)
+			}
+
+			if !test.changed.NeedsRehash(hash) {
+				t.Error("hash with old parameters not reported as needing rehash")
+			}
+
+			// Old parameters still verify
+			if err := test.changed.Verify(hash, "password"); err != nil {
+				t.Errorf("expected match, got: %s", err)
+			}
+		})
+	}
+}
+
+func TestPBKDF2VerifyOnly(t *testing.T) {
+	if _, err := PBKDF2SHA256.Hash("password"); !errors.Is(err, ErrVerifyOnly) {
+		t.Errorf("expected '%s', got: %v", ErrVerifyOnly, err)
+	}
+
+	if _, err := (Config{Preferred: PBKDF2SHA256.ID(), Bcrypt: DefaultBcrypt(), Argon2id: DefaultArgon2id(), Scrypt: DefaultScrypt()}).Build(); !errors.Is(err, ErrVerifyOnly) {
+		t.Errorf("expected '%s' preferring pbkdf2, got: %v", ErrVerifyOnly, err)
+	}
+}
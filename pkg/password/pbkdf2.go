@@ -0,0 +1,82 @@
+package password
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// PBKDF2 TODO
+//
+// Verify only, for hashes imported from other systems. Accepts both the PHC
+// form ($pbkdf2-sha256$i=<rounds>$<salt>$<hash>) and the passlib form
+// ($pbkdf2-sha256$<rounds>$<salt>$<hash>).
+type PBKDF2 struct {
+	id     string
+	digest func() hash.Hash
+}
+
+// PBKDF2 variants
+var (
+	PBKDF2SHA1   = PBKDF2{id: "pbkdf2", digest: sha1.New}
+	PBKDF2SHA256 = PBKDF2{id: "pbkdf2-sha256", digest: sha256.New}
+	PBKDF2SHA512 = PBKDF2{id: "pbkdf2-sha512", digest: sha512.New}
+)
+
+// ID TODO
+func (p PBKDF2) ID() string { return p.id }
+
+// Hash TODO
+func (p PBKDF2) Hash(string) (string, error) {
+	return "", fmt.Errorf("%w: '%s'", ErrVerifyOnly, p.id)
+}
+
+func (p PBKDF2) parse(hash string) (phcString, int, error) {
+	parts := strings.Split(hash, "$")
+
+	// Passlib form => rewrite bare rounds as a PHC parameter
+	if len(parts) == 5 && !strings.Contains(parts[2], "=") {
+		parts[2] = "i=" + parts[2]
+		hash = strings.Join(parts, "$")
+	}
+
+	res, err := parsePHC(hash)
+	if err != nil {
+		return res, 0, err
+	}
+
+	if res.id != p.id {
+		return res, 0, fmt.Errorf("%w: '%s'", ErrUnknownAlgorithm, res.id)
+	}
+
+	rounds, err := res.intParam("i")
+	if err != nil {
+		return res, 0, err
+	}
+
+	if l, ok := res.params["l"]; ok && l != strconv.Itoa(len(res.hash)) {
+		return res, 0, fmt.Errorf("%w: hash length mismatch", ErrMalformedHash)
+	}
+
+	return res, rounds, nil
+}
+
+// Verify TODO
+func (p PBKDF2) Verify(hash, password string) error {
+	data, rounds, err := p.parse(hash)
+	if err != nil {
+		return err
+	}
+
+	key := pbkdf2.Key([]byte(password), data.salt, rounds, len(data.hash), p.digest)
+	return compareKeys(key, data.hash)
+}
+
+// NeedsRehash TODO
+func (PBKDF2) NeedsRehash(string) bool { return true }
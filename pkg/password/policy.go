@@ -0,0 +1,117 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// PolicyError TODO
+type PolicyError struct{ Violations []string }
+
+func (pe PolicyError) Error() string {
+	return "password policy violated: " + strings.Join(pe.Violations, "; ")
+}
+
+// Policy TODO
+type Policy struct {
+	MinLength     int      `json:"minLength"`
+	MaxLength     int      `json:"maxLength"`
+	RequireUpper  bool     `json:"requireUpper"`
+	RequireLower  bool     `json:"requireLower"`
+	RequireDigit  bool     `json:"requireDigit"`
+	RequireSymbol bool     `json:"requireSymbol"`
+	Banned        []string `json:"banned"`
+}
+
+// DefaultPolicy TODO
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength: 8,
+		MaxLength: 128,
+		Banned: []string{
+			"password",
+			"password1",
+			"12345678",
+			"123456789",
+			"1234567890",
+			"qwertyuiop",
+			"iloveyou",
+			"letmein1",
+		},
+	}
+}
+
+// Check TODO
+func (p Policy) Check(password string) error {
+	var (
+		violations                  []string
+		upper, lower, digit, symbol bool
+		length                      = utf8.RuneCountInString(password)
+	)
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+
+	if p.RequireUpper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+
+	if p.RequireLower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	for _, item := range p.Banned {
+		if strings.EqualFold(password, item) {
+			violations = append(violations, "is too common")
+			break
+		}
+	}
+
+	if len(violations) > 0 {
+		return PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// MarshalLogObject TODO
+func (p Policy) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("minLength", p.MinLength)
+	enc.AddInt("maxLength", p.MaxLength)
+	enc.AddBool("requireUpper", p.RequireUpper)
+	enc.AddBool("requireLower", p.RequireLower)
+	enc.AddBool("requireDigit", p.RequireDigit)
+	enc.AddBool("requireSymbol", p.RequireSymbol)
+	enc.AddInt("banned", len(p.Banned))
+	return nil
+}
@@ -0,0 +1,142 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/crypto/scrypt"
+)
+
+const idScrypt = "scrypt"
+
+// Scrypt TODO
+type Scrypt struct {
+	LogN        int `json:"logN"`
+	BlockSize   int `json:"blockSize"`
+	Parallelism int `json:"parallelism"`
+	SaltLength  int `json:"saltLength"`
+	KeyLength   int `json:"keyLength"`
+}
+
+// DefaultScrypt TODO
+func DefaultScrypt() Scrypt {
+	return Scrypt{
+		LogN:        15,
+		BlockSize:   8,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+func (s Scrypt) validate() error {
+	switch {
+	case s.LogN < 1 || s.LogN > 30:
+		return errors.New("scrypt logN must be between 1 and 30")
+	case s.BlockSize < 1:
+		return errors.New("scrypt block size must be positive")
+	case s.Parallelism < 1:
+		return errors.New("scrypt parallelism must be positive")
+	case s.SaltLength < 8:
+		return errors.New("scrypt salt length must be at least 8")
+	case s.KeyLength < 16:
+		return errors.New("scrypt key length must be at least 16")
+	}
+	return nil
+}
+
+// ID TODO
+func (Scrypt) ID() string { return idScrypt }
+
+func (s Scrypt) key(password string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(password), salt, 1<<uint(s.LogN), s.BlockSize, s.Parallelism, s.KeyLength)
+}
+
+// Hash TODO
+func (s Scrypt) Hash(password string) (string, error) {
+	salt, err := newSalt(s.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := s.key(password, salt)
+	if err != nil {
+		return "", err
+	}
+
+	return phcString{
+		id: idScrypt,
+		params: map[string]string{
+			"ln": strconv.Itoa(s.LogN),
+			"r":  strconv.Itoa(s.BlockSize),
+			"p":  strconv.Itoa(s.Parallelism),
+		},
+		salt: salt,
+		hash: key,
+	}.String(), nil
+}
+
+func (Scrypt) parse(hash string) (phcString, Scrypt, error) {
+	var params Scrypt
+
+	res, err := parsePHC(hash)
+	if err != nil {
+		return res, params, err
+	}
+
+	if res.id != idScrypt {
+		return res, params, fmt.Errorf("%w: '%s'", ErrUnknownAlgorithm, res.id)
+	}
+
+	if params.LogN, err = res.intParam("ln"); err != nil {
+		return res, params, err
+	}
+
+	if params.BlockSize, err = res.intParam("r"); err != nil {
+		return res, params, err
+	}
+
+	if params.Parallelism, err = res.intParam("p"); err != nil {
+		return res, params, err
+	}
+
+	if params.LogN > 30 {
+		return res, params, fmt.Errorf("%w: parameter out of range", ErrMalformedHash)
+	}
+
+	params.SaltLength, params.KeyLength = len(res.salt), len(res.hash)
+	return res, params, nil
+}
+
+// Verify TODO
+func (s Scrypt) Verify(hash, password string) error {
+	data, params, err := s.parse(hash)
+	if err != nil {
+		return err
+	}
+
+	key, err := params.key(password, data.salt)
+	if err != nil {
+		return err
+	}
+
+	return compareKeys(key, data.hash)
+}
+
+// NeedsRehash TODO
+func (s Scrypt) NeedsRehash(hash string) bool {
+	_, params, err := s.parse(hash)
+	return err != nil || params != s
+}
+
+// MarshalLogObject TODO
+func (s Scrypt) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("logN", s.LogN)
+	enc.AddInt("blockSize", s.BlockSize)
+	enc.AddInt("parallelism", s.Parallelism)
+	enc.AddInt("saltLength", s.SaltLength)
+	enc.AddInt("keyLength", s.KeyLength)
+	return nil
+}
@@ -0,0 +1,127 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// KeyState TODO
+type KeyState string
+
+// Key states
+//
+// Rotating keys proceeds in three deployments. First add the new key as
+// "verify" everywhere so consumers learn of it. Then promote it to "active"
+// while demoting the previous key to "verify", tokens signed by the previous
+// key remain valid. Finally, once the longest token lifetime has passed, mark
+// the previous key "retired" (or remove it entirely).
+const (
+	KeyStateActive  KeyState = "active"
+	KeyStateVerify  KeyState = "verify"
+	KeyStateRetired KeyState = "retired"
+)
+
+// UnmarshalJSON TODO
+func (ks *KeyState) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	switch state := KeyState(str); state {
+	case KeyStateActive, KeyStateVerify, KeyStateRetired:
+		*ks = state
+		return nil
+	}
+
+	return fmt.Errorf("unknown key state '%s'", str)
+}
+
+// ConfigKey TODO
+type ConfigKey struct {
+	Config
+	State KeyState
+}
+
+// UnmarshalJSON TODO
+func (ck *ConfigKey) UnmarshalJSON(data []byte) error {
+	tmp := struct {
+		State *KeyState `json:"state"`
+	}{State: &ck.State}
+
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+
+	// Keys configured without a state predate the keyring and are active
+	if ck.State == "" {
+		ck.State = KeyStateActive
+	}
+
+	return ck.Config.UnmarshalJSON(data)
+}
+
+// MarshalJSON TODO
+func (ck ConfigKey) MarshalJSON() ([]byte, error) {
+	data, err := ck.Config.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var tmp map[string]interface{}
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return nil, err
+	}
+
+	tmp["state"] = ck.State
+	return json.Marshal(tmp)
+}
+
+// ConfigKeyring TODO
+type ConfigKeyring []ConfigKey
+
+// DefaultKeyringConfig TODO
+func DefaultKeyringConfig() ConfigKeyring {
+	return ConfigKeyring{
+		{Config: DefaultConfig(), State: KeyStateActive},
+	}
+}
+
+// PrivateKeys TODO
+func (ck ConfigKeyring) PrivateKeys() (active jwk.Key, verify []jwk.Key, err error) {
+	for _, item := range ck {
+		if item.State == KeyStateRetired {
+			continue
+		}
+
+		if item.State != KeyStateActive {
+			key, err := item.VerifyKey()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			verify = append(verify, key)
+			continue
+		}
+
+		key, err := item.PrivateKey()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if active != nil {
+			return nil, nil, errors.New("multiple active keys configured")
+		}
+
+		active = key
+	}
+
+	if active == nil {
+		return nil, nil, errors.New("no active key configured")
+	}
+
+	return active, verify, nil
+}
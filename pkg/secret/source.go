@@ -32,6 +32,9 @@ const (
 // Source TODO
 type Source interface{ Store() (pemutil.Store, error) }
 
+// PEMSource TODO
+type PEMSource interface{ PEM() ([]byte, error) }
+
 func sourceToType(source Source) (string, error) {
 	switch t := source.(type) {
 	case nil:
@@ -88,6 +91,9 @@ func (i Inline) Store() (pemutil.Store, error) {
 	return pemutil.DecodeBytes([]byte(i))
 }
 
+// PEM TODO
+func (i Inline) PEM() ([]byte, error) { return []byte(i), nil }
+
 func (i Inline) String() string { return redactedMsg }
 
 // Env TODO
@@ -95,11 +101,20 @@ type Env string
 
 // Store TODO
 func (e Env) Store() (pemutil.Store, error) {
+	bytes, err := e.PEM()
+	if err != nil {
+		return nil, err
+	}
+	return pemutil.DecodeBytes(bytes)
+}
+
+// PEM TODO
+func (e Env) PEM() ([]byte, error) {
 	str, ok := os.LookupEnv(string(e))
 	if !ok {
 		return nil, fmt.Errorf("env: variable '%s' not set", e)
 	}
-	return pemutil.DecodeBytes([]byte(str))
+	return []byte(str), nil
 }
 
 // File TODO
@@ -107,7 +122,7 @@ type File string
 
 // Store TODO
 func (f File) Store() (pemutil.Store, error) {
-	bytes, err := ioutil.ReadFile(string(f))
+	bytes, err := f.PEM()
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +130,70 @@ func (f File) Store() (pemutil.Store, error) {
 	return pemutil.DecodeBytes(bytes)
 }
 
+// PEM TODO
+func (f File) PEM() ([]byte, error) { return ioutil.ReadFile(string(f)) }
+
+// SourceConfig TODO
+type SourceConfig struct{ Source }
+
+// UnmarshalJSON TODO
+func (sc *SourceConfig) UnmarshalJSON(data []byte) error {
+	var tmp struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+
+	if tmp.Type == "" {
+		if tmp.Data != nil {
+			return errors.New("source: missing type")
+		}
+		sc.Source = nil
+		return nil
+	}
+
+	newSource, err := typeToSource(tmp.Type)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+
+	sc.Source = newSource
+	return json.Unmarshal(tmp.Data, sc.Source)
+}
+
+// MarshalJSON TODO
+func (sc SourceConfig) MarshalJSON() ([]byte, error) {
+	t, err := sourceToType(sc.Source)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+
+	tmp := struct {
+		Type string      `json:"type,omitempty"`
+		Data interface{} `json:"data,omitempty"`
+	}{
+		Type: t,
+		Data: sc.Source,
+	}
+
+	return json.Marshal(tmp)
+}
+
+// PEM TODO
+func (sc SourceConfig) PEM() ([]byte, error) {
+	switch t := sc.Source.(type) {
+	case nil:
+		return nil, errors.New("source: not configured")
+	case PEMSource:
+		return t.PEM()
+	default:
+		return nil, fmt.Errorf("source: type '%T' does not provide raw pem data", t)
+	}
+}
+
 // Config TODO
 type Config struct {
 	Algorithm jwa.SignatureAlgorithm
@@ -149,6 +228,24 @@ func (c Config) PublicKey() (jwk.Key, error) {
 	return NewPublic(c.Algorithm, pemStore)
 }
 
+// VerifyKey TODO
+func (c Config) VerifyKey() (jwk.Key, error) {
+	pemStore, err := c.Store()
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys kept only for verification may be public material, e.g. another
+	// issuer's key, so only require a private block when one is present
+	for blockType := range pemStore {
+		if blockIsPrivate(blockType) {
+			return NewPrivate(c.Algorithm, pemStore)
+		}
+	}
+
+	return NewPublic(c.Algorithm, pemStore)
+}
+
 // UnmarshalJSON TODO
 func (c *Config) UnmarshalJSON(data []byte) error {
 	var tmp struct {
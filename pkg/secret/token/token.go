@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/lestrrat-go/jwx/jws"
@@ -124,3 +125,94 @@ func (s Signer) Sign(claims interface{}) (string, error) {
 func (s Signer) Validate(token string, claims interface{}) error {
 	return s.v.Validate(token, claims)
 }
+
+// KeyID TODO
+func KeyID(token string) (string, error) {
+	msg, err := jws.ParseString(token)
+	if err != nil {
+		return "", err
+	}
+
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return "", errors.New("token must carry exactly one signature")
+	}
+
+	kid := sigs[0].ProtectedHeaders().KeyID()
+	if kid == "" {
+		return "", errors.New("token missing key id (kid)")
+	}
+
+	return kid, nil
+}
+
+// Keyring TODO
+type Keyring struct {
+	signer     *Signer
+	validaters map[string]Validater
+}
+
+// NewKeyring TODO
+func NewKeyring(active jwk.Key, verify ...jwk.Key) (*Keyring, error) {
+	signer, err := NewSigner(active)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Keyring{
+		signer:     signer,
+		validaters: map[string]Validater{active.KeyID(): signer.v},
+	}
+
+	for _, key := range verify {
+		if _, exists := res.validaters[key.KeyID()]; exists {
+			return nil, fmt.Errorf("duplicate key id '%s'", key.KeyID())
+		}
+
+		if secret.IsPrivate(key) {
+			if key, err = signKeyToValKey(key); err != nil {
+				return nil, err
+			}
+		}
+
+		v, err := newValidater(key)
+		if err != nil {
+			return nil, err
+		}
+
+		res.validaters[key.KeyID()] = *v
+	}
+
+	return res, nil
+}
+
+// KeyID TODO
+func (k Keyring) KeyID() string { return k.signer.v.headers.KeyID() }
+
+// KeyIDs TODO
+func (k Keyring) KeyIDs() []string {
+	res := make([]string, 0, len(k.validaters))
+	for kid := range k.validaters {
+		res = append(res, kid)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Sign TODO
+func (k Keyring) Sign(claims interface{}) (string, error) { return k.signer.Sign(claims) }
+
+// Validate TODO
+func (k Keyring) Validate(token string, claims interface{}) error {
+	kid, err := KeyID(token)
+	if err != nil {
+		return err
+	}
+
+	v, ok := k.validaters[kid]
+	if !ok {
+		return fmt.Errorf("unknown key id '%s'", kid)
+	}
+
+	return v.Validate(token, claims)
+}
@@ -1,37 +1,73 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/mongo"
 	"github.com/oligarch316/go-auth-service/pkg/store/sqlite"
 	"github.com/oligarch316/go-skeleton/pkg/observ"
 	"go.uber.org/zap/zapcore"
 )
 
-// TODO: Should the Backend methods all take context as 1st arg?
-
 // Backend TODO.
 type Backend interface {
 	Close() error
 
 	// Invites
-	CreateInvites(ownerID string, count int) ([]model.Invite, error)
-	ReadInvite(id string) (model.Invite, error)
-	UpdateInvite(id string, mData model.InviteUpdate) error
-	DeleteInvite(id string) error
-	LookupInvites(ownerID string) ([]model.Invite, error)
+	CreateInvites(ctx context.Context, ownerID string, count int) ([]model.Invite, error)
+	ReadInvite(ctx context.Context, id string) (model.Invite, error)
+	UpdateInvite(ctx context.Context, id string, mData model.InviteUpdate) error
+	DeleteInvite(ctx context.Context, id string) error
+	LookupInvites(ctx context.Context, ownerID string) ([]model.Invite, error)
 
 	// Users
-	CreateUser(name, password string, mData model.UserUpdate) (model.User, error)
-	ReadUser(id string) (model.User, error)
-	UpdateUser(id string, mData model.UserUpdate) error
-	DeleteUser(id string) error
-	LookupUser(name string) (model.User, error)
+	CreateUser(ctx context.Context, name, password string, mData model.UserUpdate) (model.User, error)
+	ReadUser(ctx context.Context, id string) (model.User, error)
+	UpdateUser(ctx context.Context, id string, mData model.UserUpdate) error
+	DeleteUser(ctx context.Context, id string) error
+	LookupUser(ctx context.Context, name string) (model.User, error)
+	ListUsers(ctx context.Context, filter model.UserFilter, offset, limit int) ([]model.User, error)
+	CountUsers(ctx context.Context, filter model.UserFilter) (int, error)
+
+	// Refresh tokens
+	CreateRefreshToken(ctx context.Context, data model.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, id string, next model.RefreshToken) (model.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+
+	// Revocations
+	CreateRevocation(ctx context.Context, data model.Revocation) error
+	ConsumeToken(ctx context.Context, data model.Revocation) error
+	ListRevocations(ctx context.Context) ([]model.Revocation, error)
+	PruneRevocations(ctx context.Context, before time.Time) error
+
+	// Login attempts
+	ReadLoginAttempt(ctx context.Context, key string) (model.LoginAttempt, error)
+	RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (model.LoginAttempt, error)
+	ReleaseLoginAttempt(ctx context.Context, key string) error
+	DeleteLoginAttempt(ctx context.Context, key string) error
+	PruneLoginAttempts(ctx context.Context, before time.Time) error
 
 	// Combined
-	CreateUserAndDeleteInvite(inviteID, name, password string, mData model.UserUpdate) (model.User, error)
+	CreateUserAndDeleteInvite(ctx context.Context, inviteID, name, password string, mData model.UserUpdate) (model.User, error)
+}
+
+// Migrater TODO.
+type Migrater interface {
+	Close() error
+	Migrate(ctx context.Context) (applied int, err error)
+	Status(ctx context.Context) (MigrationStatus, error)
+}
+
+// MigrationStatus TODO.
+type MigrationStatus struct {
+	Current, Latest int
+	Pending         []string
 }
 
 const (
@@ -41,16 +77,52 @@ const (
 
 type sqliteConfig struct{ sqlite.Config }
 
-func (sc sqliteConfig) Build(corelet *observ.Corelet) (Backend, error) {
-	res, err := sqlite.New(sc.Config, corelet)
+func (sc sqliteConfig) Build(passwords *password.Registry, corelet *observ.Corelet) (Backend, error) {
+	res, err := sqlite.New(sc.Config, passwords, corelet)
 	return res, err
 }
 
+func (sc sqliteConfig) BuildMigrater() (Migrater, error) {
+	res, err := sqlite.NewMigrater(sc.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqliteMigrater{res}, nil
+}
+
+type sqliteMigrater struct{ *sqlite.Migrater }
+
+func (sm sqliteMigrater) Status(ctx context.Context) (MigrationStatus, error) {
+	current, err := sm.Version(ctx)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	return MigrationStatus{
+		Current: current,
+		Latest:  sqlite.LatestVersion(),
+		Pending: sqlite.Pending(current),
+	}, nil
+}
+
+type mongoConfig struct{ mongo.Config }
+
+func (mc mongoConfig) Build(passwords *password.Registry, corelet *observ.Corelet) (Backend, error) {
+	res, err := mongo.New(mc.Config, passwords, corelet)
+	return res, err
+}
+
+func (mc mongoConfig) BuildMigrater() (Migrater, error) {
+	return nil, fmt.Errorf("type '%s' does not support migrations", backendTypeMongo)
+}
+
 // Config TODO.
 type Config struct {
 	dType   string
 	dynamic interface {
-		Build(*observ.Corelet) (Backend, error)
+		Build(*password.Registry, *observ.Corelet) (Backend, error)
+		BuildMigrater() (Migrater, error)
 		zapcore.ObjectMarshaler
 	}
 }
@@ -64,7 +136,12 @@ func DefaultConfig() Config {
 }
 
 // Build TODO.
-func (c Config) Build(corelet *observ.Corelet) (Backend, error) { return c.dynamic.Build(corelet) }
+func (c Config) Build(passwords *password.Registry, corelet *observ.Corelet) (Backend, error) {
+	return c.dynamic.Build(passwords, corelet)
+}
+
+// BuildMigrater TODO.
+func (c Config) BuildMigrater() (Migrater, error) { return c.dynamic.BuildMigrater() }
 
 // UnmarshalJSON TODO.
 func (c *Config) UnmarshalJSON(data []byte) error {
@@ -83,7 +160,7 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 	case backendTypeSQLite:
 		c.dynamic = &sqliteConfig{Config: sqlite.DefaultConfig()}
 	case backendTypeMongo:
-		return fmt.Errorf("type '%s' not yet implemented", tmp.Type)
+		c.dynamic = &mongoConfig{Config: mongo.DefaultConfig()}
 	default:
 		return fmt.Errorf("unknown type '%s'", tmp.Type)
 	}
@@ -0,0 +1,604 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/mongo"
+	"github.com/oligarch316/go-auth-service/pkg/store/sqlite"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Mongo contract tests run against this URI, and are skipped when no server
+// answers there
+const envMongoURI = "AUTHSVC_TEST_MONGO_URI"
+
+var testBackends = []struct {
+	name  string
+	build func(*testing.T) Backend
+}{
+	{name: "sqlite", build: newSQLiteTestBackend(":memory:")},
+	{name: "sqlite-file", build: newSQLiteTestBackend("")},
+	{name: "mongo", build: newMongoTestBackend},
+}
+
+func testPasswords() *password.Registry {
+	return password.NewRegistry(password.Bcrypt{Cost: bcrypt.MinCost})
+}
+
+func testCorelet() *observ.Corelet { return &observ.Corelet{Logger: zap.NewNop()} }
+
+// Empty dbPath => a fresh database file in a temporary directory
+func newSQLiteTestBackend(dbPath string) func(*testing.T) Backend {
+	return func(t *testing.T) Backend {
+		cfg := sqlite.DefaultConfig()
+		cfg.DBPath = dbPath
+
+		if dbPath == "" {
+			cfg.DBPath = filepath.Join(t.TempDir(), "store.db")
+		}
+
+		res, err := sqlite.New(cfg, testPasswords(), testCorelet())
+		if err != nil {
+			t.Fatalf("failed to create sqlite store: %s", err)
+		}
+
+		t.Cleanup(func() { res.Close() })
+		return res
+	}
+}
+
+var (
+	mongoProbe    sync.Once
+	mongoProbeErr error
+)
+
+// Unavailability is detected once, rather than waiting out a timeout per test
+func probeMongo(uri string) error {
+	mongoProbe.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		client, err := mongodriver.Connect(ctx, options.Client().ApplyURI(uri))
+		if err != nil {
+			mongoProbeErr = err
+			return
+		}
+
+		defer client.Disconnect(context.Background())
+		mongoProbeErr = client.Ping(ctx, nil)
+	})
+
+	return mongoProbeErr
+}
+
+func newMongoTestBackend(t *testing.T) Backend {
+	cfg := mongo.DefaultConfig()
+	cfg.Database = fmt.Sprintf("authsvc_test_%d", time.Now().UnixNano())
+	cfg.Timeout = ctype.Duration{Duration: 5 * time.Second}
+
+	if uri := os.Getenv(envMongoURI); uri != "" {
+		cfg.URI = uri
+	}
+
+	if err := probeMongo(cfg.URI); err != nil {
+		t.Skipf("mongo unavailable: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
+	defer cancel()
+
+	client, err := mongodriver.Connect(ctx, options.Client().ApplyURI(cfg.URI))
+	if err != nil {
+		t.Fatalf("failed to connect to mongo: %s", err)
+	}
+
+	res, err := mongo.NewFromClient(cfg, client, testPasswords(), testCorelet())
+	if err != nil {
+		client.Disconnect(context.Background())
+		t.Fatalf("failed to create mongo store: %s", err)
+	}
+
+	// NOTE: Closing the store disconnects the client, so drop first
+	t.Cleanup(func() {
+		client.Database(cfg.Database).Drop(context.Background())
+		res.Close()
+	})
+
+	return res
+}
+
+func forEachBackend(t *testing.T, fn func(*testing.T, Backend)) {
+	for _, item := range testBackends {
+		build := item.build
+		t.Run(item.name, func(t *testing.T) { fn(t, build(t)) })
+	}
+}
+
+func expectErr(t *testing.T, err, kind error, format string, a ...interface{}) {
+	t.Helper()
+
+	if !errors.Is(err, kind) {
+		t.Errorf("%s: expected '%s', got: %v", fmt.Sprintf(format, a...), kind, err)
+	}
+}
+
+func mustCreateUser(t *testing.T, db Backend, name string) model.User {
+	t.Helper()
+
+	res, err := db.CreateUser(context.Background(), name, "password "+name, model.UserUpdate{})
+	if err != nil {
+		t.Fatalf("failed to create user '%s': %s", name, err)
+	}
+
+	return res
+}
+
+func TestBackendUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db Backend) {
+		var (
+			ctx         = context.Background()
+			displayName = "Alice"
+			admin       = true
+		)
+
+		alice, err := db.CreateUser(ctx, "alice", "password alice", model.UserUpdate{DisplayName: &displayName})
+		if err != nil {
+			t.Fatalf("failed to create user: %s", err)
+		}
+
+		if alice.ID == "" || alice.Name != "alice" || alice.DisplayName != displayName || alice.Admin {
+			t.Errorf("unexpected created user: %+v", alice)
+		}
+
+		_, err = db.CreateUser(ctx, "alice", "other password", model.UserUpdate{})
+		expectErr(t, err, storeerr.ErrConflict, "create duplicate name")
+
+		bob := mustCreateUser(t, db, "bob")
+
+		// Read and lookup
+		read, err := db.ReadUser(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("failed to read user: %s", err)
+		}
+
+		if read.Name != alice.Name || read.DisplayName != alice.DisplayName {
+			t.Errorf("expected %+v, got %+v", alice, read)
+		}
+
+		if err := read.PasswordHash.Compare(testPasswords(), "password alice"); err != nil {
+			t.Errorf("stored password hash does not verify: %s", err)
+		}
+
+		lookedUp, err := db.LookupUser(ctx, "bob")
+		if err != nil {
+			t.Fatalf("failed to lookup user: %s", err)
+		}
+
+		if lookedUp.ID != bob.ID {
+			t.Errorf("expected id '%s', got '%s'", bob.ID, lookedUp.ID)
+		}
+
+		_, err = db.LookupUser(ctx, "carol")
+		expectErr(t, err, storeerr.ErrNotFound, "lookup unknown name")
+
+		_, err = db.ReadUser(ctx, "not an id")
+		expectErr(t, err, storeerr.ErrInvalidID, "read malformed id")
+
+		// Update
+		if err := db.UpdateUser(ctx, bob.ID, model.UserUpdate{Admin: &admin}); err != nil {
+			t.Fatalf("failed to update user: %s", err)
+		}
+
+		if read, _ = db.ReadUser(ctx, bob.ID); !read.Admin {
+			t.Error("update did not set admin")
+		}
+
+		// List and count
+		list, err := db.ListUsers(ctx, model.UserFilter{Admin: &admin}, 0, 10)
+		if err != nil {
+			t.Fatalf("failed to list users: %s", err)
+		}
+
+		if len(list) != 1 || list[0].ID != bob.ID {
+			t.Errorf("expected only '%s' listed as admin, got %+v", bob.ID, list)
+		}
+
+		count, err := db.CountUsers(ctx, model.UserFilter{NamePrefix: "al"})
+		if err != nil {
+			t.Fatalf("failed to count users: %s", err)
+		}
+
+		if count != 1 {
+			t.Errorf("expected 1 user with prefix 'al', got %d", count)
+		}
+
+		// Delete
+		if err := db.DeleteUser(ctx, alice.ID); err != nil {
+			t.Fatalf("failed to delete user: %s", err)
+		}
+
+		_, err = db.ReadUser(ctx, alice.ID)
+		expectErr(t, err, storeerr.ErrNotFound, "read deleted user")
+
+		expectErr(t, db.DeleteUser(ctx, alice.ID), storeerr.ErrNotFound, "delete deleted user")
+		expectErr(t, db.UpdateUser(ctx, alice.ID, model.UserUpdate{Admin: &admin}), storeerr.ErrNotFound, "update deleted user")
+	})
+}
+
+func TestBackendInvites(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db Backend) {
+		var (
+			ctx   = context.Background()
+			owner = mustCreateUser(t, db, "owner")
+			other = mustCreateUser(t, db, "other")
+		)
+
+		invites, err := db.CreateInvites(ctx, owner.ID, 3)
+		if err != nil {
+			t.Fatalf("failed to create invites: %s", err)
+		}
+
+		if len(invites) != 3 {
+			t.Fatalf("expected 3 invites, got %d", len(invites))
+		}
+
+		read, err := db.ReadInvite(ctx, invites[0].ID)
+		if err != nil {
+			t.Fatalf("failed to read invite: %s", err)
+		}
+
+		if read.OwnerID != owner.ID {
+			t.Errorf("expected owner '%s', got '%s'", owner.ID, read.OwnerID)
+		}
+
+		if err := db.UpdateInvite(ctx, invites[0].ID, model.InviteUpdate{OwnerID: &other.ID}); err != nil {
+			t.Fatalf("failed to update invite: %s", err)
+		}
+
+		owned, err := db.LookupInvites(ctx, owner.ID)
+		if err != nil {
+			t.Fatalf("failed to lookup invites: %s", err)
+		}
+
+		if len(owned) != 2 {
+			t.Errorf("expected 2 invites for owner, got %d", len(owned))
+		}
+
+		if err := db.DeleteInvite(ctx, invites[1].ID); err != nil {
+			t.Fatalf("failed to delete invite: %s", err)
+		}
+
+		_, err = db.ReadInvite(ctx, invites[1].ID)
+		expectErr(t, err, storeerr.ErrNotFound, "read deleted invite")
+
+		expectErr(t, db.DeleteInvite(ctx, invites[1].ID), storeerr.ErrNotFound, "delete deleted invite")
+		expectErr(t, db.UpdateInvite(ctx, invites[1].ID, model.InviteUpdate{OwnerID: &owner.ID}), storeerr.ErrNotFound, "update deleted invite")
+	})
+}
+
+func TestBackendCreateUserAndDeleteInvite(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db Backend) {
+		var (
+			ctx   = context.Background()
+			owner = mustCreateUser(t, db, "owner")
+		)
+
+		invites, err := db.CreateInvites(ctx, owner.ID, 2)
+		if err != nil {
+			t.Fatalf("failed to create invites: %s", err)
+		}
+
+		created, err := db.CreateUserAndDeleteInvite(ctx, invites[0].ID, "alice", "password alice", model.UserUpdate{})
+		if err != nil {
+			t.Fatalf("failed to create user from invite: %s", err)
+		}
+
+		if _, err := db.ReadUser(ctx, created.ID); err != nil {
+			t.Errorf("failed to read created user: %s", err)
+		}
+
+		_, err = db.ReadInvite(ctx, invites[0].ID)
+		expectErr(t, err, storeerr.ErrNotFound, "read consumed invite")
+
+		_, err = db.CreateUserAndDeleteInvite(ctx, invites[0].ID, "bob", "password bob", model.UserUpdate{})
+		expectErr(t, err, storeerr.ErrConflict, "reuse consumed invite")
+
+		// Taken name => nothing is consumed
+		_, err = db.CreateUserAndDeleteInvite(ctx, invites[1].ID, "alice", "password alice", model.UserUpdate{})
+		expectErr(t, err, storeerr.ErrConflict, "create duplicate name from invite")
+
+		if _, err := db.ReadInvite(ctx, invites[1].ID); err != nil {
+			t.Errorf("invite consumed by failed creation: %s", err)
+		}
+	})
+}
+
+func TestBackendRefreshTokens(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db Backend) {
+		var (
+			ctx        = context.Background()
+			user       = mustCreateUser(t, db, "alice")
+			expiration = time.Now().Add(time.Hour)
+		)
+
+		first := model.RefreshToken{ID: "first", FamilyID: "family", UserID: user.ID, Expiration: expiration}
+		if err := db.CreateRefreshToken(ctx, first); err != nil {
+			t.Fatalf("failed to create refresh token: %s", err)
+		}
+
+		expectErr(t, db.CreateRefreshToken(ctx, first), storeerr.ErrConflict, "create duplicate refresh token")
+
+		// Rotation carries the family and user forward
+		second, err := db.RotateRefreshToken(ctx, "first", model.RefreshToken{ID: "second", Expiration: expiration})
+		if err != nil {
+			t.Fatalf("failed to rotate refresh token: %s", err)
+		}
+
+		if second.FamilyID != "family" || second.UserID != user.ID {
+			t.Errorf("rotated token lost its family or user: %+v", second)
+		}
+
+		// Reuse revokes the entire family
+		_, err = db.RotateRefreshToken(ctx, "first", model.RefreshToken{ID: "third", Expiration: expiration})
+		if !errors.Is(err, storeerr.ErrRefreshTokenReused) {
+			t.Errorf("reuse rotated token: expected '%s', got: %v", storeerr.ErrRefreshTokenReused, err)
+		}
+
+		_, err = db.RotateRefreshToken(ctx, "second", model.RefreshToken{ID: "fourth", Expiration: expiration})
+		expectErr(t, err, storeerr.ErrNotFound, "rotate token from revoked family")
+
+		// Expired
+		expired := model.RefreshToken{ID: "expired", FamilyID: "expired", UserID: user.ID, Expiration: time.Now().Add(-time.Minute)}
+		if err := db.CreateRefreshToken(ctx, expired); err != nil {
+			t.Fatalf("failed to create refresh token: %s", err)
+		}
+
+		_, err = db.RotateRefreshToken(ctx, "expired", model.RefreshToken{ID: "renewed", Expiration: expiration})
+		expectErr(t, err, storeerr.ErrNotFound, "rotate expired token")
+
+		// Revocation
+		for _, id := range []string{"a", "b"} {
+			rt := model.RefreshToken{ID: id, FamilyID: "family " + id, UserID: user.ID, Expiration: expiration}
+			if err := db.CreateRefreshToken(ctx, rt); err != nil {
+				t.Fatalf("failed to create refresh token: %s", err)
+			}
+		}
+
+		if err := db.RevokeRefreshToken(ctx, "a"); err != nil {
+			t.Fatalf("failed to revoke refresh token: %s", err)
+		}
+
+		expectErr(t, db.RevokeRefreshToken(ctx, "a"), storeerr.ErrNotFound, "revoke revoked token")
+
+		if err := db.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+			t.Fatalf("failed to revoke user refresh tokens: %s", err)
+		}
+
+		_, err = db.RotateRefreshToken(ctx, "b", model.RefreshToken{ID: "c", Expiration: expiration})
+		expectErr(t, err, storeerr.ErrNotFound, "rotate token of revoked user")
+	})
+}
+
+func TestBackendRevocations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db Backend) {
+		var (
+			ctx = context.Background()
+			now = time.Now()
+		)
+
+		revocations := []model.Revocation{
+			{TokenID: "token", Expiration: now.Add(time.Hour)},
+			{Subject: "subject", IssuedBefore: now, Expiration: now.Add(time.Hour)},
+			{TokenID: "expired", Expiration: now.Add(-time.Minute)},
+		}
+
+		for _, item := range revocations {
+			if err := db.CreateRevocation(ctx, item); err != nil {
+				t.Fatalf("failed to create revocation: %s", err)
+			}
+		}
+
+		// Revoking twice is harmless
+		if err := db.CreateRevocation(ctx, revocations[0]); err != nil {
+			t.Errorf("failed to repeat revocation: %s", err)
+		}
+
+		list, err := db.ListRevocations(ctx)
+		if err != nil {
+			t.Fatalf("failed to list revocations: %s", err)
+		}
+
+		seen := make(map[string]bool)
+		for _, item := range list {
+			seen[item.TokenID+item.Subject] = true
+		}
+
+		if !seen["token"] || !seen["subject"] || seen["expired"] {
+			t.Errorf("expected unexpired revocations only, got %+v", list)
+		}
+
+		// Listing leaves expired entries for pruning
+		expired := model.Revocation{TokenID: "expired", Expiration: now.Add(time.Hour)}
+		expectErr(t, db.ConsumeToken(ctx, expired), storeerr.ErrConflict, "consume expired token before pruning")
+
+		if err := db.PruneRevocations(ctx, now); err != nil {
+			t.Fatalf("failed to prune revocations: %s", err)
+		}
+
+		if list, err := db.ListRevocations(ctx); err != nil || len(list) != 2 {
+			t.Errorf("expected 2 revocations after pruning, got %+v (%v)", list, err)
+		}
+
+		// Expired entries are gone, so the token id is free again
+		if err := db.ConsumeToken(ctx, expired); err != nil {
+			t.Errorf("failed to consume pruned token id: %s", err)
+		}
+
+		// Single use tokens
+		consumed := model.Revocation{TokenID: "single use", Expiration: now.Add(time.Hour)}
+
+		if err := db.ConsumeToken(ctx, consumed); err != nil {
+			t.Fatalf("failed to consume token: %s", err)
+		}
+
+		expectErr(t, db.ConsumeToken(ctx, consumed), storeerr.ErrConflict, "consume token twice")
+		expectErr(t, db.ConsumeToken(ctx, revocations[0]), storeerr.ErrConflict, "consume revoked token")
+	})
+}
+
+func TestBackendLoginAttempts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db Backend) {
+		var (
+			ctx         = context.Background()
+			key         = model.LoginUserKey("alice")
+			resetBefore = time.Now().Add(-time.Hour)
+		)
+
+		_, err := db.ReadLoginAttempt(ctx, key)
+		expectErr(t, err, storeerr.ErrNotFound, "read unknown attempt")
+
+		for i := 1; i <= 3; i++ {
+			attempt, err := db.RecordLoginFailure(ctx, key, resetBefore)
+			if err != nil {
+				t.Fatalf("failed to record login failure: %s", err)
+			}
+
+			if attempt.Key != key || attempt.Failures != i {
+				t.Errorf("expected %d failures for '%s', got %+v", i, key, attempt)
+			}
+		}
+
+		if err := db.ReleaseLoginAttempt(ctx, key); err != nil {
+			t.Fatalf("failed to release login attempt: %s", err)
+		}
+
+		if attempt, _ := db.ReadLoginAttempt(ctx, key); attempt.Failures != 2 {
+			t.Errorf("expected 2 failures after release, got %d", attempt.Failures)
+		}
+
+		// Failures older than resetBefore start over, for this key only
+		other := model.LoginAddrKey("192.0.2.1")
+		if _, err := db.RecordLoginFailure(ctx, other, resetBefore); err != nil {
+			t.Fatalf("failed to record login failure: %s", err)
+		}
+
+		attempt, err := db.RecordLoginFailure(ctx, key, time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatalf("failed to record login failure: %s", err)
+		}
+
+		if attempt.Failures != 1 {
+			t.Errorf("expected stale failures reset to 1, got %d", attempt.Failures)
+		}
+
+		if attempt, _ := db.ReadLoginAttempt(ctx, other); attempt.Failures != 1 {
+			t.Errorf("reset leaked into '%s': %+v", other, attempt)
+		}
+
+		// Prune and delete
+		if err := db.PruneLoginAttempts(ctx, time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("failed to prune login attempts: %s", err)
+		}
+
+		_, err = db.ReadLoginAttempt(ctx, other)
+		expectErr(t, err, storeerr.ErrNotFound, "read pruned attempt")
+
+		if _, err := db.RecordLoginFailure(ctx, key, resetBefore); err != nil {
+			t.Fatalf("failed to record login failure: %s", err)
+		}
+
+		if err := db.DeleteLoginAttempt(ctx, key); err != nil {
+			t.Fatalf("failed to delete login attempt: %s", err)
+		}
+
+		expectErr(t, db.DeleteLoginAttempt(ctx, key), storeerr.ErrNotFound, "delete deleted attempt")
+		expectErr(t, db.ReleaseLoginAttempt(ctx, key), storeerr.ErrNotFound, "release deleted attempt")
+	})
+}
+
+func TestBackendCreateUserAndDeleteInviteConcurrent(t *testing.T) {
+	const concurrency = 16
+
+	forEachBackend(t, func(t *testing.T, db Backend) {
+		var (
+			ctx   = context.Background()
+			owner = mustCreateUser(t, db, "owner")
+			errs  = make(chan error, concurrency)
+			start = make(chan struct{})
+			wg    sync.WaitGroup
+		)
+
+		invites, err := db.CreateInvites(ctx, owner.ID, 1)
+		if err != nil {
+			t.Fatalf("failed to create invite: %s", err)
+		}
+
+		for i := 0; i < concurrency; i++ {
+			name := fmt.Sprintf("user%d", i)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+
+				_, err := db.CreateUserAndDeleteInvite(ctx, invites[0].ID, name, "password "+name, model.UserUpdate{})
+				errs <- err
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+		close(errs)
+
+		var successes int
+
+		for err := range errs {
+			switch {
+			case err == nil:
+				successes++
+			case !errors.Is(err, storeerr.ErrInviteConsumed):
+				t.Errorf("expected invite consumed error, got: %s", err)
+			}
+		}
+
+		if successes != 1 {
+			t.Errorf("expected exactly 1 success, got %d", successes)
+		}
+
+		// Exactly one invited user alongside the owner
+		users, err := db.ListUsers(ctx, model.UserFilter{}, 0, concurrency+1)
+		if err != nil {
+			t.Fatalf("failed to list users: %s", err)
+		}
+
+		var invited int
+		for _, item := range users {
+			if item.ID != owner.ID {
+				invited++
+			}
+		}
+
+		if invited != 1 {
+			t.Errorf("expected exactly 1 invited user, got %d", invited)
+		}
+
+		if _, err := db.ReadInvite(ctx, invites[0].ID); !errors.Is(err, storeerr.ErrNotFound) {
+			t.Errorf("expected consumed invite to be gone, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package command
+
+import (
+	"github.com/oligarch316/go-auth-service/pkg/store"
+	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
+	"github.com/spf13/cobra"
+)
+
+type cmdConfig struct {
+	DB store.Config `json:"db"`
+}
+
+func defaultCmdConfig() cmdConfig {
+	return cmdConfig{DB: store.DefaultConfig()}
+}
+
+// NewConfig TODO.
+func NewConfig(name string, ns *namespace.NS) *cobra.Command {
+	cfg := defaultCmdConfig()
+	return ns.NewCommand(name, &cfg)
+}
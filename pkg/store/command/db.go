@@ -0,0 +1,100 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/oligarch316/go-auth-service/pkg/store"
+	"github.com/oligarch316/go-skeleton/pkg/config/namespace"
+	"github.com/spf13/cobra"
+)
+
+// New TODO.
+func New(name string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: "Manage the database schema",
+		Long:  "Manage the database schema",
+	}
+}
+
+// NewMigrate TODO.
+func NewMigrate(name string, ns *namespace.NS) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: "Apply pending database migrations",
+		Long:  "Apply pending database migrations",
+		Run:   func(_ *cobra.Command, _ []string) { os.Exit(runMigrate(ns)) },
+	}
+}
+
+// NewStatus TODO.
+func NewStatus(name string, ns *namespace.NS) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: "Show database migration status",
+		Long:  "Show database migration status",
+		Run:   func(_ *cobra.Command, _ []string) { os.Exit(runStatus(ns)) },
+	}
+}
+
+func loadMigrater(ns *namespace.NS) (store.Migrater, bool) {
+	cfg := defaultCmdConfig()
+
+	if _, err := ns.LoadAndRecord(&cfg); err != nil {
+		log.Printf("[bootstrap] failed to load configuration: %s\n", err)
+		return nil, false
+	}
+
+	migrater, err := cfg.DB.BuildMigrater()
+	if err != nil {
+		log.Printf("[bootstrap] failed to open database: %s\n", err)
+		return nil, false
+	}
+
+	return migrater, true
+}
+
+func runMigrate(ns *namespace.NS) int {
+	migrater, ok := loadMigrater(ns)
+	if !ok {
+		return 1
+	}
+
+	defer migrater.Close()
+
+	applied, err := migrater.Migrate(context.Background())
+	if err != nil {
+		log.Printf("[migrate] %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("Applied %d migration(s)\n", applied)
+	return 0
+}
+
+func runStatus(ns *namespace.NS) int {
+	migrater, ok := loadMigrater(ns)
+	if !ok {
+		return 1
+	}
+
+	defer migrater.Close()
+
+	status, err := migrater.Status(context.Background())
+	if err != nil {
+		log.Printf("[status] %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("Current Version: %d\n", status.Current)
+	fmt.Printf("Latest Version:  %d\n", status.Latest)
+
+	for _, item := range status.Pending {
+		fmt.Printf("Pending:         %s\n", item)
+	}
+
+	return 0
+}
@@ -0,0 +1,250 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap/zapcore"
+)
+
+const codeDuplicateKey = 11000
+
+// ConfigCollectionNames TODO.
+type ConfigCollectionNames struct {
+	Invites       string `json:"invites"`
+	LoginAttempts string `json:"loginAttempts"`
+	RefreshTokens string `json:"refreshTokens"`
+	Revocations   string `json:"revocations"`
+	Users         string `json:"users"`
+}
+
+// MarshalLogObject TODO.
+func (ccn ConfigCollectionNames) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("invites", ccn.Invites)
+	enc.AddString("loginAttempts", ccn.LoginAttempts)
+	enc.AddString("refreshTokens", ccn.RefreshTokens)
+	enc.AddString("revocations", ccn.Revocations)
+	enc.AddString("users", ccn.Users)
+	return nil
+}
+
+// Config TODO.
+type Config struct {
+	URI             string                `json:"uri"`
+	Database        string                `json:"database"`
+	CollectionNames ConfigCollectionNames `json:"collectionNames"`
+	Timeout         ctype.Duration        `json:"timeout"`
+}
+
+// MarshalLogObject TODO.
+func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	// NOTE: URI may carry credentials, so don't log it
+	enc.AddString("database", c.Database)
+	enc.AddDuration("timeout", c.Timeout.Duration)
+	return enc.AddObject("collections", c.CollectionNames)
+}
+
+// DefaultConfig TODO.
+func DefaultConfig() Config {
+	return Config{
+		URI:      "mongodb://localhost:27017/?replicaSet=rs0",
+		Database: "authsvc",
+		CollectionNames: ConfigCollectionNames{
+			Invites:       "invites",
+			LoginAttempts: "login_attempts",
+			RefreshTokens: "refresh_tokens",
+			Revocations:   "revocations",
+			Users:         "users",
+		},
+		Timeout: ctype.Duration{Duration: 10 * time.Second},
+	}
+}
+
+type timeout time.Duration
+
+func (t timeout) context(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, time.Duration(t))
+}
+
+// Store TODO.
+type Store struct {
+	*observ.Corelet
+
+	client *mongo.Client
+	timeout
+	*usersStore
+	*invitesStore
+	*refreshTokensStore
+	*revocationsStore
+	*loginAttemptsStore
+}
+
+// New TODO.
+func New(cfg Config, passwords *password.Registry, corelet *observ.Corelet) (*Store, error) {
+	ctx, cancel := timeout(cfg.Timeout.Duration).context(context.Background())
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URI))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+
+	res, err := NewFromClient(cfg, client, passwords, corelet)
+	if err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// NewFromClient TODO.
+func NewFromClient(cfg Config, client *mongo.Client, passwords *password.Registry, corelet *observ.Corelet) (*Store, error) {
+	var (
+		db = client.Database(cfg.Database)
+		t  = timeout(cfg.Timeout.Duration)
+	)
+
+	invites := newInvitesStore(db.Collection(cfg.CollectionNames.Invites), t)
+
+	users, err := newUsersStore(db.Collection(cfg.CollectionNames.Users), t, passwords)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokens, err := newRefreshTokensStore(client, db.Collection(cfg.CollectionNames.RefreshTokens), t)
+	if err != nil {
+		return nil, err
+	}
+
+	revocations, err := newRevocationsStore(db.Collection(cfg.CollectionNames.Revocations), t)
+	if err != nil {
+		return nil, err
+	}
+
+	loginAttempts, err := newLoginAttemptsStore(db.Collection(cfg.CollectionNames.LoginAttempts), t)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		Corelet: corelet,
+
+		client:             client,
+		timeout:            t,
+		invitesStore:       invites,
+		usersStore:         users,
+		refreshTokensStore: refreshTokens,
+		revocationsStore:   revocations,
+		loginAttemptsStore: loginAttempts,
+	}, nil
+}
+
+// Close TODO.
+func (s *Store) Close() error {
+	ctx, cancel := s.context(context.Background())
+	defer cancel()
+
+	return s.client.Disconnect(ctx)
+}
+
+// CreateUserAndDeleteInvite TODO.
+func (s *Store) CreateUserAndDeleteInvite(ctx context.Context, inviteID, name, password string, mData model.UserUpdate) (model.User, error) {
+	var inv invite
+
+	if err := inv.setID(inviteID); err != nil {
+		return model.User{}, err
+	}
+
+	u, err := newUser(s.passwords, name, password, mData)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return model.User{}, err
+	}
+
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := s.invitesStore.coll.DeleteOne(sessCtx, inv.filter())
+		if err != nil {
+			return nil, err
+		}
+
+		if result.DeletedCount < 1 {
+			return nil, storeerr.ErrInviteConsumed
+		}
+
+		return nil, s.usersStore.insert(sessCtx, &u)
+	})
+
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return u.toModel(), nil
+}
+
+func parseID(id string) (primitive.ObjectID, error) {
+	res, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return res, storeerr.InvalidID(id, err)
+	}
+	return res, nil
+}
+
+func storeErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return storeerr.NotFound(err)
+	case isDuplicateKey(err):
+		return storeerr.Conflict(err)
+	}
+
+	return err
+}
+
+func checkMatched(count int64) error {
+	if count < 1 {
+		return storeerr.NotFound(mongo.ErrNoDocuments)
+	}
+
+	return nil
+}
+
+func isDuplicateKey(err error) bool {
+	var writeErr mongo.WriteException
+	if !errors.As(err, &writeErr) {
+		return false
+	}
+
+	for _, item := range writeErr.WriteErrors {
+		if item.Code == codeDuplicateKey {
+			return true
+		}
+	}
+
+	return false
+}
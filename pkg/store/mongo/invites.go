@@ -0,0 +1,168 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type invite struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	OwnerID primitive.ObjectID `bson:"owner_id"`
+}
+
+func (i *invite) setID(id string) (err error) {
+	i.ID, err = parseID(id)
+	return
+}
+
+func (i *invite) setOwnerID(id string) (err error) {
+	i.OwnerID, err = parseID(id)
+	return
+}
+
+func (i invite) filter() bson.M { return bson.M{"_id": i.ID} }
+
+func (i invite) toModel() model.Invite {
+	return model.Invite{
+		ID:      i.ID.Hex(),
+		OwnerID: i.OwnerID.Hex(),
+	}
+}
+
+type invitesStore struct {
+	coll *mongo.Collection
+	timeout
+}
+
+func newInvitesStore(coll *mongo.Collection, t timeout) *invitesStore {
+	return &invitesStore{coll: coll, timeout: t}
+}
+
+func (is *invitesStore) CreateInvites(ctx context.Context, ownerID string, count int) ([]model.Invite, error) {
+	var (
+		inv invite
+		res []model.Invite
+	)
+
+	if err := inv.setOwnerID(ownerID); err != nil {
+		return res, err
+	}
+
+	if count < 1 {
+		return res, nil
+	}
+
+	docs := make([]interface{}, count)
+	for i := range docs {
+		docs[i] = invite{ID: primitive.NewObjectID(), OwnerID: inv.OwnerID}
+	}
+
+	ctx, cancel := is.context(ctx)
+	defer cancel()
+
+	if _, err := is.coll.InsertMany(ctx, docs); err != nil {
+		return res, storeErr(err)
+	}
+
+	for _, doc := range docs {
+		res = append(res, doc.(invite).toModel())
+	}
+
+	return res, nil
+}
+
+func (is *invitesStore) ReadInvite(ctx context.Context, id string) (res model.Invite, err error) {
+	var inv invite
+
+	if err = inv.setID(id); err != nil {
+		return
+	}
+
+	ctx, cancel := is.context(ctx)
+	defer cancel()
+
+	if err = is.coll.FindOne(ctx, inv.filter()).Decode(&inv); err != nil {
+		err = storeErr(err)
+		return
+	}
+
+	return inv.toModel(), nil
+}
+
+func (is *invitesStore) UpdateInvite(ctx context.Context, id string, mData model.InviteUpdate) error {
+	if mData.OwnerID == nil {
+		return nil
+	}
+
+	var inv invite
+
+	if err := inv.setID(id); err != nil {
+		return err
+	}
+
+	if err := inv.setOwnerID(*mData.OwnerID); err != nil {
+		return err
+	}
+
+	ctx, cancel := is.context(ctx)
+	defer cancel()
+
+	result, err := is.coll.UpdateOne(ctx, inv.filter(), bson.M{"$set": bson.M{"owner_id": inv.OwnerID}})
+	if err != nil {
+		return storeErr(err)
+	}
+
+	return checkMatched(result.MatchedCount)
+}
+
+func (is *invitesStore) DeleteInvite(ctx context.Context, id string) error {
+	var inv invite
+
+	if err := inv.setID(id); err != nil {
+		return err
+	}
+
+	ctx, cancel := is.context(ctx)
+	defer cancel()
+
+	result, err := is.coll.DeleteOne(ctx, inv.filter())
+	if err != nil {
+		return storeErr(err)
+	}
+
+	return checkMatched(result.DeletedCount)
+}
+
+func (is *invitesStore) LookupInvites(ctx context.Context, ownerID string) ([]model.Invite, error) {
+	var (
+		inv     invite
+		invList = make([]invite, 0)
+	)
+
+	if err := inv.setOwnerID(ownerID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := is.context(ctx)
+	defer cancel()
+
+	cursor, err := is.coll.Find(ctx, bson.M{"owner_id": inv.OwnerID})
+	if err != nil {
+		return nil, storeErr(err)
+	}
+
+	if err := cursor.All(ctx, &invList); err != nil {
+		return nil, storeErr(err)
+	}
+
+	res := make([]model.Invite, len(invList))
+	for i, item := range invList {
+		res[i] = item.toModel()
+	}
+
+	return res, nil
+}
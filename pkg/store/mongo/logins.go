@@ -0,0 +1,116 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type loginAttempt struct {
+	ID          string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure"`
+}
+
+func (la loginAttempt) toModel() model.LoginAttempt {
+	return model.LoginAttempt{
+		Key:         la.ID,
+		Failures:    la.Failures,
+		LastFailure: la.LastFailure,
+	}
+}
+
+type loginAttemptsStore struct {
+	coll *mongo.Collection
+	timeout
+}
+
+func newLoginAttemptsStore(coll *mongo.Collection, t timeout) (*loginAttemptsStore, error) {
+	ctx, cancel := t.context(context.Background())
+	defer cancel()
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "last_failure", Value: 1}}}
+
+	if _, err := coll.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, err
+	}
+
+	return &loginAttemptsStore{coll: coll, timeout: t}, nil
+}
+
+func (ls *loginAttemptsStore) ReadLoginAttempt(ctx context.Context, key string) (model.LoginAttempt, error) {
+	var res loginAttempt
+
+	ctx, cancel := ls.context(ctx)
+	defer cancel()
+
+	if err := ls.coll.FindOne(ctx, bson.M{"_id": key}).Decode(&res); err != nil {
+		return model.LoginAttempt{}, storeErr(err)
+	}
+
+	return res.toModel(), nil
+}
+
+func (ls *loginAttemptsStore) RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (model.LoginAttempt, error) {
+	var res loginAttempt
+
+	ctx, cancel := ls.context(ctx)
+	defer cancel()
+
+	// Failures older than the reset point are forgotten => start over
+	var (
+		stale = bson.M{"$lt": bson.A{"$last_failure", resetBefore.UTC()}}
+		count = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}}
+
+		update = mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"failures":     bson.M{"$cond": bson.A{stale, 1, count}},
+			"last_failure": time.Now().UTC(),
+		}}}}
+
+		opts = options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	)
+
+	if err := ls.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&res); err != nil {
+		return model.LoginAttempt{}, storeErr(err)
+	}
+
+	return res.toModel(), nil
+}
+
+func (ls *loginAttemptsStore) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	ctx, cancel := ls.context(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": key, "failures": bson.M{"$gt": 0}}
+
+	result, err := ls.coll.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"failures": -1}})
+	if err != nil {
+		return storeErr(err)
+	}
+
+	return checkMatched(result.MatchedCount)
+}
+
+func (ls *loginAttemptsStore) PruneLoginAttempts(ctx context.Context, before time.Time) error {
+	ctx, cancel := ls.context(ctx)
+	defer cancel()
+
+	_, err := ls.coll.DeleteMany(ctx, bson.M{"last_failure": bson.M{"$lt": before.UTC()}})
+	return storeErr(err)
+}
+
+func (ls *loginAttemptsStore) DeleteLoginAttempt(ctx context.Context, key string) error {
+	ctx, cancel := ls.context(ctx)
+	defer cancel()
+
+	result, err := ls.coll.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return storeErr(err)
+	}
+
+	return checkMatched(result.DeletedCount)
+}
@@ -0,0 +1,187 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type refreshToken struct {
+	ID         string             `bson:"_id"`
+	FamilyID   string             `bson:"family_id"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	Expiration time.Time          `bson:"expiration"`
+	Rotated    bool               `bson:"rotated"`
+}
+
+func newRefreshToken(data model.RefreshToken) (refreshToken, error) {
+	rt := refreshToken{
+		ID:         data.ID,
+		FamilyID:   data.FamilyID,
+		Expiration: data.Expiration.UTC(),
+		Rotated:    data.Rotated,
+	}
+
+	return rt, rt.setUserID(data.UserID)
+}
+
+func (rt *refreshToken) setUserID(id string) (err error) {
+	rt.UserID, err = parseID(id)
+	return
+}
+
+func (rt refreshToken) filter() bson.M { return bson.M{"_id": rt.ID} }
+
+func (rt refreshToken) toModel() model.RefreshToken {
+	return model.RefreshToken{
+		ID:         rt.ID,
+		FamilyID:   rt.FamilyID,
+		UserID:     rt.UserID.Hex(),
+		Expiration: rt.Expiration,
+		Rotated:    rt.Rotated,
+	}
+}
+
+type refreshTokensStore struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+	timeout
+}
+
+func newRefreshTokensStore(client *mongo.Client, coll *mongo.Collection, t timeout) (*refreshTokensStore, error) {
+	ctx, cancel := t.context(context.Background())
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expiration", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	if _, err := coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return nil, err
+	}
+
+	return &refreshTokensStore{client: client, coll: coll, timeout: t}, nil
+}
+
+func (rs *refreshTokensStore) CreateRefreshToken(ctx context.Context, data model.RefreshToken) error {
+	rt, err := newRefreshToken(data)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	_, err = rs.coll.InsertOne(ctx, rt)
+	return storeErr(err)
+}
+
+func (rs *refreshTokensStore) rotate(sessCtx mongo.SessionContext, id string, next *refreshToken) error {
+	prev := refreshToken{ID: id}
+
+	if err := rs.coll.FindOne(sessCtx, prev.filter()).Decode(&prev); err != nil {
+		return storeErr(err)
+	}
+
+	// Presenting an already rotated token => revoke the entire family
+	if prev.Rotated {
+		if _, err := rs.coll.DeleteMany(sessCtx, bson.M{"family_id": prev.FamilyID}); err != nil {
+			return err
+		}
+		return storeerr.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(prev.Expiration) {
+		return storeerr.ErrRefreshTokenExpired
+	}
+
+	result, err := rs.coll.UpdateOne(sessCtx, bson.M{"_id": prev.ID, "rotated": false}, bson.M{"$set": bson.M{"rotated": true}})
+	if err != nil {
+		return storeErr(err)
+	}
+
+	if err := checkMatched(result.MatchedCount); err != nil {
+		return err
+	}
+
+	next.FamilyID, next.UserID = prev.FamilyID, prev.UserID
+
+	_, err = rs.coll.InsertOne(sessCtx, next)
+	return storeErr(err)
+}
+
+func (rs *refreshTokensStore) RotateRefreshToken(ctx context.Context, id string, next model.RefreshToken) (model.RefreshToken, error) {
+	nextRT := refreshToken{
+		ID:         next.ID,
+		Expiration: next.Expiration.UTC(),
+	}
+
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	session, err := rs.client.StartSession()
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	defer session.EndSession(ctx)
+
+	var reuseErr error
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		err := rs.rotate(sessCtx, id, &nextRT)
+		if errors.Is(err, storeerr.ErrRefreshTokenReused) {
+			// Commit the family revocation, but still report the reuse
+			reuseErr = err
+			return nil, nil
+		}
+		return nil, err
+	})
+
+	switch {
+	case err != nil:
+		return model.RefreshToken{}, err
+	case reuseErr != nil:
+		return model.RefreshToken{}, reuseErr
+	}
+
+	return nextRT.toModel(), nil
+}
+
+func (rs *refreshTokensStore) RevokeRefreshToken(ctx context.Context, id string) error {
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	var rt refreshToken
+	if err := rs.coll.FindOne(ctx, refreshToken{ID: id}.filter()).Decode(&rt); err != nil {
+		return storeErr(err)
+	}
+
+	_, err := rs.coll.DeleteMany(ctx, bson.M{"family_id": rt.FamilyID})
+	return err
+}
+
+func (rs *refreshTokensStore) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	var rt refreshToken
+	if err := rt.setUserID(userID); err != nil {
+		return err
+	}
+
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	_, err := rs.coll.DeleteMany(ctx, bson.M{"user_id": rt.UserID})
+	return err
+}
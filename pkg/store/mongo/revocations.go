@@ -0,0 +1,126 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const revocationSubjectPrefix = "sub:"
+
+type revocation struct {
+	ID           string     `bson:"_id"`
+	TokenID      string     `bson:"token_id,omitempty"`
+	Subject      string     `bson:"subject,omitempty"`
+	IssuedBefore *time.Time `bson:"issued_before,omitempty"`
+	Expiration   time.Time  `bson:"expiration"`
+}
+
+func newRevocation(data model.Revocation) revocation {
+	res := revocation{Expiration: data.Expiration.UTC()}
+
+	if data.TokenID != "" {
+		res.ID, res.TokenID = data.TokenID, data.TokenID
+		return res
+	}
+
+	issuedBefore := data.IssuedBefore.UTC()
+	res.ID, res.Subject, res.IssuedBefore = revocationSubjectPrefix+data.Subject, data.Subject, &issuedBefore
+	return res
+}
+
+func (r revocation) toModel() model.Revocation {
+	res := model.Revocation{
+		TokenID:    r.TokenID,
+		Subject:    r.Subject,
+		Expiration: r.Expiration,
+	}
+
+	if r.IssuedBefore != nil {
+		res.IssuedBefore = *r.IssuedBefore
+	}
+
+	return res
+}
+
+type revocationsStore struct {
+	coll *mongo.Collection
+	timeout
+}
+
+func newRevocationsStore(coll *mongo.Collection, t timeout) (*revocationsStore, error) {
+	ctx, cancel := t.context(context.Background())
+	defer cancel()
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiration", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	if _, err := coll.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, err
+	}
+
+	return &revocationsStore{coll: coll, timeout: t}, nil
+}
+
+func (rs *revocationsStore) CreateRevocation(ctx context.Context, data model.Revocation) error {
+	rev := newRevocation(data)
+
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	_, err := rs.coll.ReplaceOne(ctx, bson.M{"_id": rev.ID}, rev, options.Replace().SetUpsert(true))
+	return storeErr(err)
+}
+
+// NOTE: Unlike CreateRevocation, fails when the token id is already revoked,
+// so exactly one caller consumes a single use token
+func (rs *revocationsStore) ConsumeToken(ctx context.Context, data model.Revocation) error {
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	_, err := rs.coll.InsertOne(ctx, newRevocation(data))
+	if errors.Is(storeErr(err), storeerr.ErrConflict) {
+		return storeerr.ErrTokenConsumed
+	}
+	return storeErr(err)
+}
+
+func (rs *revocationsStore) ListRevocations(ctx context.Context) ([]model.Revocation, error) {
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	// NOTE: The TTL monitor only runs periodically, so filter expired entries here as well
+	cursor, err := rs.coll.Find(ctx, bson.M{"expiration": bson.M{"$gt": time.Now().UTC()}})
+	if err != nil {
+		return nil, err
+	}
+
+	revList := make([]revocation, 0)
+	if err := cursor.All(ctx, &revList); err != nil {
+		return nil, err
+	}
+
+	res := make([]model.Revocation, len(revList))
+	for i, item := range revList {
+		res[i] = item.toModel()
+	}
+
+	return res, nil
+}
+
+// NOTE: The TTL index removes expired entries eventually, this only hurries it
+func (rs *revocationsStore) PruneRevocations(ctx context.Context, before time.Time) error {
+	ctx, cancel := rs.context(ctx)
+	defer cancel()
+
+	_, err := rs.coll.DeleteMany(ctx, bson.M{"expiration": bson.M{"$lte": before.UTC()}})
+	return storeErr(err)
+}
@@ -0,0 +1,250 @@
+package mongo
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type user struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Name         string             `bson:"name"`
+	DisplayName  *string            `bson:"display_name,omitempty"`
+	PasswordHash string             `bson:"password_hash"`
+	Admin        bool               `bson:"admin"`
+}
+
+func newUser(passwords *password.Registry, name, pw string, mData model.UserUpdate) (user, error) {
+	u := user{
+		ID:          primitive.NewObjectID(),
+		Name:        name,
+		DisplayName: mData.DisplayName,
+	}
+
+	if mData.Admin != nil {
+		u.Admin = *mData.Admin
+	}
+
+	return u, u.setPasswordHash(passwords, pw)
+}
+
+func (u *user) setID(id string) (err error) {
+	u.ID, err = parseID(id)
+	return
+}
+
+func (u *user) setPasswordHash(passwords *password.Registry, pw string) error {
+	ph := make(model.PasswordHash, 0)
+	err := ph.Set(passwords, pw)
+	u.PasswordHash = string(ph)
+	return err
+}
+
+func (u user) filter() bson.M { return bson.M{"_id": u.ID} }
+
+func (u user) toModel() model.User {
+	res := model.User{
+		ID:           u.ID.Hex(),
+		Name:         u.Name,
+		PasswordHash: model.PasswordHash(u.PasswordHash),
+		Admin:        u.Admin,
+	}
+
+	if u.DisplayName != nil {
+		res.DisplayName = *u.DisplayName
+	}
+
+	return res
+}
+
+type usersStore struct {
+	coll      *mongo.Collection
+	passwords *password.Registry
+	timeout
+}
+
+func newUsersStore(coll *mongo.Collection, t timeout, passwords *password.Registry) (*usersStore, error) {
+	ctx, cancel := t.context(context.Background())
+	defer cancel()
+
+	nameIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	if _, err := coll.Indexes().CreateOne(ctx, nameIndex); err != nil {
+		return nil, err
+	}
+
+	return &usersStore{coll: coll, passwords: passwords, timeout: t}, nil
+}
+
+func (us *usersStore) insert(ctx context.Context, u *user) error {
+	_, err := us.coll.InsertOne(ctx, u)
+	if isDuplicateKey(err) {
+		return storeerr.ErrUsernameTaken
+	}
+	return storeErr(err)
+}
+
+func (us *usersStore) CreateUser(ctx context.Context, name, password string, mData model.UserUpdate) (model.User, error) {
+	u, err := newUser(us.passwords, name, password, mData)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	ctx, cancel := us.context(ctx)
+	defer cancel()
+
+	if err := us.insert(ctx, &u); err != nil {
+		return model.User{}, err
+	}
+
+	return u.toModel(), nil
+}
+
+func (us *usersStore) ReadUser(ctx context.Context, id string) (res model.User, err error) {
+	var u user
+
+	if err = u.setID(id); err != nil {
+		return
+	}
+
+	ctx, cancel := us.context(ctx)
+	defer cancel()
+
+	if err = us.coll.FindOne(ctx, u.filter()).Decode(&u); err != nil {
+		err = storeErr(err)
+		return
+	}
+
+	return u.toModel(), nil
+}
+
+func (us *usersStore) UpdateUser(ctx context.Context, id string, mData model.UserUpdate) error {
+	var u user
+
+	if err := u.setID(id); err != nil {
+		return err
+	}
+
+	setItems := bson.M{}
+
+	if mData.Password != nil {
+		if err := u.setPasswordHash(us.passwords, *mData.Password); err != nil {
+			return err
+		}
+		setItems["password_hash"] = u.PasswordHash
+	}
+
+	if mData.DisplayName != nil {
+		setItems["display_name"] = *mData.DisplayName
+	}
+
+	if mData.Admin != nil {
+		setItems["admin"] = *mData.Admin
+	}
+
+	if len(setItems) < 1 {
+		return nil
+	}
+
+	ctx, cancel := us.context(ctx)
+	defer cancel()
+
+	result, err := us.coll.UpdateOne(ctx, u.filter(), bson.M{"$set": setItems})
+	if err != nil {
+		return storeErr(err)
+	}
+
+	return checkMatched(result.MatchedCount)
+}
+
+func (us *usersStore) DeleteUser(ctx context.Context, id string) error {
+	var u user
+
+	if err := u.setID(id); err != nil {
+		return err
+	}
+
+	ctx, cancel := us.context(ctx)
+	defer cancel()
+
+	result, err := us.coll.DeleteOne(ctx, u.filter())
+	if err != nil {
+		return storeErr(err)
+	}
+
+	return checkMatched(result.DeletedCount)
+}
+
+func (us *usersStore) LookupUser(ctx context.Context, name string) (model.User, error) {
+	var u user
+
+	ctx, cancel := us.context(ctx)
+	defer cancel()
+
+	if err := us.coll.FindOne(ctx, bson.M{"name": name}).Decode(&u); err != nil {
+		return model.User{}, storeErr(err)
+	}
+
+	return u.toModel(), nil
+}
+
+func userFilter(filter model.UserFilter) bson.M {
+	res := bson.M{}
+
+	if filter.NamePrefix != "" {
+		res["name"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.NamePrefix), Options: "i"}
+	}
+
+	if filter.Admin != nil {
+		res["admin"] = *filter.Admin
+	}
+
+	return res
+}
+
+func (us *usersStore) ListUsers(ctx context.Context, filter model.UserFilter, offset, limit int) ([]model.User, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetSkip(int64(offset))
+
+	// Zero limit => no limit
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	ctx, cancel := us.context(ctx)
+	defer cancel()
+
+	cursor, err := us.coll.Find(ctx, userFilter(filter), opts)
+	if err != nil {
+		return nil, storeErr(err)
+	}
+
+	userList := make([]user, 0)
+	if err := cursor.All(ctx, &userList); err != nil {
+		return nil, storeErr(err)
+	}
+
+	res := make([]model.User, len(userList))
+	for i, item := range userList {
+		res[i] = item.toModel()
+	}
+
+	return res, nil
+}
+
+func (us *usersStore) CountUsers(ctx context.Context, filter model.UserFilter) (int, error) {
+	ctx, cancel := us.context(ctx)
+	defer cancel()
+
+	res, err := us.coll.CountDocuments(ctx, userFilter(filter))
+	return int(res), storeErr(err)
+}
@@ -1,11 +1,20 @@
 package sqlite
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
 	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
 	"github.com/oligarch316/go-skeleton/pkg/observ"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	// TODO: Explain to golint for some reason
@@ -14,27 +23,62 @@ import (
 
 const driverName = "sqlite3"
 
+// NOTE: Immediate transactions take the write lock up front, so concurrent
+// transactions (e.g. racing signups on one invite) serialize instead of
+// failing with SQLITE_BUSY on lock upgrade.
+func dataSourceName(dbPath string) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return dbPath + sep + "_txlock=immediate"
+}
+
+func connect(dbPath string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect(driverName, dataSourceName(dbPath))
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: Every connection to ":memory:" opens a distinct, empty database
+	if strings.HasPrefix(dbPath, ":memory:") {
+		db.SetMaxOpenConns(1)
+	}
+
+	return db, nil
+}
+
 // ConfigTableNames TODO.
 type ConfigTableNames struct {
-	Invites string `json:"invites"`
-	Users   string `json:"users"`
+	Invites       string `json:"invites"`
+	LoginAttempts string `json:"loginAttempts"`
+	Migrations    string `json:"migrations"`
+	RefreshTokens string `json:"refreshTokens"`
+	Revocations   string `json:"revocations"`
+	Users         string `json:"users"`
 }
 
 // MarshalLogObject TODO.
 func (ctn ConfigTableNames) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("invites", ctn.Invites)
+	enc.AddString("loginAttempts", ctn.LoginAttempts)
+	enc.AddString("migrations", ctn.Migrations)
+	enc.AddString("refreshTokens", ctn.RefreshTokens)
+	enc.AddString("revocations", ctn.Revocations)
 	enc.AddString("users", ctn.Users)
 	return nil
 }
 
 // Config TODO.
 type Config struct {
-	DBPath     string           `json:"dbPath"`
-	TableNames ConfigTableNames `json:"tableNames"`
+	AutoMigrate bool             `json:"autoMigrate"`
+	DBPath      string           `json:"dbPath"`
+	TableNames  ConfigTableNames `json:"tableNames"`
 }
 
 // MarshalLogObject TODO.
 func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddBool("autoMigrate", c.AutoMigrate)
 	enc.AddString("dbPath", c.DBPath)
 	enc.AddObject("tables", c.TableNames)
 	return nil
@@ -43,10 +87,15 @@ func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 // DefaultConfig TODO.
 func DefaultConfig() Config {
 	return Config{
-		DBPath: ":memory:",
+		AutoMigrate: true,
+		DBPath:      ":memory:",
 		TableNames: ConfigTableNames{
-			Invites: "invites",
-			Users:   "users",
+			Invites:       "invites",
+			LoginAttempts: "login_attempts",
+			Migrations:    "schema_migrations",
+			RefreshTokens: "refresh_tokens",
+			Revocations:   "revocations",
+			Users:         "users",
 		},
 	}
 }
@@ -58,21 +107,54 @@ type Store struct {
 	db *sqlx.DB
 	*usersStore
 	*invitesStore
+	*refreshTokensStore
+	*revocationsStore
+	*loginAttemptsStore
 }
 
 // New TODO.
-func New(cfg Config, corelet *observ.Corelet) (*Store, error) {
-	db, err := sqlx.Connect(driverName, cfg.DBPath)
+func New(cfg Config, passwords *password.Registry, corelet *observ.Corelet) (*Store, error) {
+	db, err := connect(cfg.DBPath)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := prepareSchema(cfg, db, corelet); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	res, err := newStore(cfg, db, passwords, corelet)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func newStore(cfg Config, db *sqlx.DB, passwords *password.Registry, corelet *observ.Corelet) (*Store, error) {
 	invites, err := newInvitesStore(cfg.TableNames.Invites, db)
 	if err != nil {
 		return nil, err
 	}
 
-	users, err := newUsersStore(cfg.TableNames.Users, db)
+	users, err := newUsersStore(cfg.TableNames.Users, db, passwords)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokens, err := newRefreshTokensStore(cfg.TableNames.RefreshTokens, db)
+	if err != nil {
+		return nil, err
+	}
+
+	revocations, err := newRevocationsStore(cfg.TableNames.Revocations, db)
+	if err != nil {
+		return nil, err
+	}
+
+	loginAttempts, err := newLoginAttemptsStore(cfg.TableNames.LoginAttempts, db)
 	if err != nil {
 		return nil, err
 	}
@@ -80,17 +162,125 @@ func New(cfg Config, corelet *observ.Corelet) (*Store, error) {
 	return &Store{
 		Corelet: corelet,
 
-		db:           db,
-		invitesStore: invites,
-		usersStore:   users,
+		db:                 db,
+		invitesStore:       invites,
+		usersStore:         users,
+		refreshTokensStore: refreshTokens,
+		revocationsStore:   revocations,
+		loginAttemptsStore: loginAttempts,
 	}, nil
 }
 
+func prepareSchema(cfg Config, db *sqlx.DB, corelet *observ.Corelet) error {
+	var (
+		ctx      = context.Background()
+		migrater = &Migrater{db: db, tables: cfg.TableNames}
+	)
+
+	if cfg.AutoMigrate {
+		applied, err := migrater.Migrate(ctx)
+		if err != nil {
+			return err
+		}
+
+		if applied > 0 {
+			corelet.Logger.Info("applied schema migrations", zap.Int("count", applied), zap.Int("version", LatestVersion()))
+		}
+
+		return nil
+	}
+
+	current, err := migrater.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if latest := LatestVersion(); current != latest {
+		return fmt.Errorf("database schema at version %d, expected %d (migrations required)", current, latest)
+	}
+
+	return nil
+}
+
 // Close TODO.
 func (s *Store) Close() error { return s.db.Close() }
 
 // CreateUserAndDeleteInvite TODO.
-func (s *Store) CreateUserAndDeleteInvite(inviteID, name, password string, mData model.UserUpdate) (model.User, error) {
-	// TODO
-	return model.User{}, errors.New("CreateUserAndDeleteInvite not yet implemented")
+func (s *Store) CreateUserAndDeleteInvite(ctx context.Context, inviteID, name, password string, mData model.UserUpdate) (model.User, error) {
+	var inv invite
+
+	if err := inv.setID(inviteID); err != nil {
+		return model.User{}, err
+	}
+
+	u, err := newUser(s.passwords, name, password, mData)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if err := s.createUserAndDeleteInvite(ctx, tx, inv, &u); err != nil {
+		tx.Rollback()
+		return model.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.User{}, err
+	}
+
+	return u.toModel(), nil
+}
+
+func (s *Store) createUserAndDeleteInvite(ctx context.Context, tx *sqlx.Tx, inv invite, u *user) error {
+	if err := s.invitesStore.consume(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	return s.usersStore.insert(ctx, tx, u)
+}
+
+func parseID(id string) (uint64, error) {
+	idInt, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, storeerr.InvalidID(id, err)
+	}
+	return uint64(idInt), nil
+}
+
+func storeErr(err error) error {
+	var sqliteErr sqlite3.Error
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return storeerr.NotFound(err)
+	case errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey):
+		return storeerr.Conflict(err)
+	case errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint:
+		return storeerr.Constraint(err)
+	}
+
+	return err
+}
+
+func checkAffected(result sql.Result, err error) error {
+	if err != nil {
+		return storeErr(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count < 1 {
+		return storeerr.NotFound(sql.ErrNoRows)
+	}
+
+	return nil
 }
@@ -1,10 +1,14 @@
 package sqlite
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"strconv"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
 )
 
 type invite struct {
@@ -12,16 +16,14 @@ type invite struct {
 	OwnerID uint64 `db:"owner_id"`
 }
 
-func (i *invite) setID(id string) error {
-	idInt, err := strconv.ParseInt(id, 10, 64)
-	i.ID = uint64(idInt)
-	return err
+func (i *invite) setID(id string) (err error) {
+	i.ID, err = parseID(id)
+	return
 }
 
-func (i *invite) setOwnerID(id string) error {
-	idInt, err := strconv.ParseInt(id, 10, 64)
-	i.OwnerID = uint64(idInt)
-	return err
+func (i *invite) setOwnerID(id string) (err error) {
+	i.OwnerID, err = parseID(id)
+	return
 }
 
 func (i invite) toModel() model.Invite {
@@ -70,7 +72,26 @@ func newInvitesStore(tableName string, db *sqlx.DB) (*invitesStore, error) {
 	}, nil
 }
 
-func (is *invitesStore) CreateInvites(ownerID string, count int) ([]model.Invite, error) {
+func (is *invitesStore) consume(ctx context.Context, tx *sqlx.Tx, inv invite) error {
+	readStmt, deleteStmt := tx.NamedStmt(is.readStmt), tx.NamedStmt(is.deleteStmt)
+
+	// Confirm existence
+	switch err := readStmt.GetContext(ctx, &inv, inv); {
+	case errors.Is(err, sql.ErrNoRows):
+		return storeerr.ErrInviteConsumed
+	case err != nil:
+		return err
+	}
+
+	err := checkAffected(deleteStmt.ExecContext(ctx, inv))
+	if errors.Is(err, storeerr.ErrNotFound) {
+		return storeerr.ErrInviteConsumed
+	}
+
+	return err
+}
+
+func (is *invitesStore) CreateInvites(ctx context.Context, ownerID string, count int) ([]model.Invite, error) {
 	var (
 		inv invite
 		res []model.Invite
@@ -81,9 +102,9 @@ func (is *invitesStore) CreateInvites(ownerID string, count int) ([]model.Invite
 	}
 
 	for i := 0; i < count; i++ {
-		result, err := is.createStmt.Exec(inv)
+		result, err := is.createStmt.ExecContext(ctx, inv)
 		if err != nil {
-			return res, err
+			return res, storeErr(err)
 		}
 
 		newID, err := result.LastInsertId()
@@ -98,21 +119,22 @@ func (is *invitesStore) CreateInvites(ownerID string, count int) ([]model.Invite
 	return res, nil
 }
 
-func (is *invitesStore) ReadInvite(id string) (res model.Invite, err error) {
+func (is *invitesStore) ReadInvite(ctx context.Context, id string) (res model.Invite, err error) {
 	var inv invite
 
 	if err = inv.setID(id); err != nil {
 		return
 	}
 
-	if err = is.readStmt.Get(&inv, inv); err != nil {
+	if err = is.readStmt.GetContext(ctx, &inv, inv); err != nil {
+		err = storeErr(err)
 		return
 	}
 
 	return inv.toModel(), nil
 }
 
-func (is *invitesStore) UpdateInvite(id string, mData model.InviteUpdate) error {
+func (is *invitesStore) UpdateInvite(ctx context.Context, id string, mData model.InviteUpdate) error {
 	if mData.OwnerID == nil {
 		return nil
 	}
@@ -127,22 +149,20 @@ func (is *invitesStore) UpdateInvite(id string, mData model.InviteUpdate) error
 		return err
 	}
 
-	_, err := is.updateStmt.Exec(inv)
-	return err
+	return checkAffected(is.updateStmt.ExecContext(ctx, inv))
 }
 
-func (is *invitesStore) DeleteInvite(id string) error {
+func (is *invitesStore) DeleteInvite(ctx context.Context, id string) error {
 	var inv invite
 
 	if err := inv.setID(id); err != nil {
 		return err
 	}
 
-	_, err := is.deleteStmt.Exec(inv)
-	return err
+	return checkAffected(is.deleteStmt.ExecContext(ctx, inv))
 }
 
-func (is *invitesStore) LookupInvites(ownerID string) ([]model.Invite, error) {
+func (is *invitesStore) LookupInvites(ctx context.Context, ownerID string) ([]model.Invite, error) {
 	var (
 		inv     invite
 		invList = make([]invite, 0)
@@ -152,8 +172,8 @@ func (is *invitesStore) LookupInvites(ownerID string) ([]model.Invite, error) {
 		return nil, err
 	}
 
-	if err := is.lookupStmt.Select(&invList, inv); err != nil {
-		return nil, err
+	if err := is.lookupStmt.SelectContext(ctx, &invList, inv); err != nil {
+		return nil, storeErr(err)
 	}
 
 	res := make([]model.Invite, len(invList))
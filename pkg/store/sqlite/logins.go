@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+)
+
+type loginAttempt struct {
+	ID          string    `db:"id"`
+	Failures    int       `db:"failures"`
+	LastFailure time.Time `db:"last_failure"`
+}
+
+func (la loginAttempt) toModel() model.LoginAttempt {
+	return model.LoginAttempt{
+		Key:         la.ID,
+		Failures:    la.Failures,
+		LastFailure: la.LastFailure,
+	}
+}
+
+type loginFailure struct {
+	loginAttempt
+	ResetBefore time.Time `db:"reset_before"`
+}
+
+type loginAttemptsStore struct {
+	db *sqlx.DB
+
+	readStmt, recordStmt, releaseStmt, pruneStmt, deleteStmt *sqlx.NamedStmt
+}
+
+func newLoginAttemptsStore(tableName string, db *sqlx.DB) (*loginAttemptsStore, error) {
+	readStmt, err := db.PrepareNamed("SELECT * FROM " + tableName + " WHERE id=:id")
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: Failures older than the reset point are forgotten => start over
+	recordStmt, err := db.PrepareNamed("INSERT INTO " + tableName + " (id, failures, last_failure) VALUES (:id, 1, :last_failure) ON CONFLICT(id) DO UPDATE SET failures=CASE WHEN last_failure<:reset_before THEN 1 ELSE failures+1 END, last_failure=excluded.last_failure")
+	if err != nil {
+		return nil, err
+	}
+
+	releaseStmt, err := db.PrepareNamed("UPDATE " + tableName + " SET failures=failures-1 WHERE id=:id AND failures>0")
+	if err != nil {
+		return nil, err
+	}
+
+	pruneStmt, err := db.PrepareNamed("DELETE FROM " + tableName + " WHERE last_failure<:last_failure")
+	if err != nil {
+		return nil, err
+	}
+
+	deleteStmt, err := db.PrepareNamed("DELETE FROM " + tableName + " WHERE id=:id")
+	if err != nil {
+		return nil, err
+	}
+
+	return &loginAttemptsStore{
+		db: db,
+
+		readStmt:    readStmt,
+		recordStmt:  recordStmt,
+		releaseStmt: releaseStmt,
+		pruneStmt:   pruneStmt,
+		deleteStmt:  deleteStmt,
+	}, nil
+}
+
+func (ls *loginAttemptsStore) ReadLoginAttempt(ctx context.Context, key string) (model.LoginAttempt, error) {
+	var res loginAttempt
+
+	if err := ls.readStmt.GetContext(ctx, &res, loginAttempt{ID: key}); err != nil {
+		return model.LoginAttempt{}, storeErr(err)
+	}
+
+	return res.toModel(), nil
+}
+
+func (ls *loginAttemptsStore) RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (model.LoginAttempt, error) {
+	var (
+		res  loginAttempt
+		data = loginFailure{
+			loginAttempt: loginAttempt{ID: key, LastFailure: time.Now().UTC()},
+			ResetBefore:  resetBefore.UTC(),
+		}
+	)
+
+	tx, err := ls.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return model.LoginAttempt{}, err
+	}
+
+	if _, err := tx.NamedStmt(ls.recordStmt).ExecContext(ctx, data); err != nil {
+		tx.Rollback()
+		return model.LoginAttempt{}, storeErr(err)
+	}
+
+	if err := tx.NamedStmt(ls.readStmt).GetContext(ctx, &res, data); err != nil {
+		tx.Rollback()
+		return model.LoginAttempt{}, storeErr(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.LoginAttempt{}, err
+	}
+
+	return res.toModel(), nil
+}
+
+func (ls *loginAttemptsStore) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	return checkAffected(ls.releaseStmt.ExecContext(ctx, loginAttempt{ID: key}))
+}
+
+func (ls *loginAttemptsStore) PruneLoginAttempts(ctx context.Context, before time.Time) error {
+	_, err := ls.pruneStmt.ExecContext(ctx, loginAttempt{LastFailure: before.UTC()})
+	return storeErr(err)
+}
+
+func (ls *loginAttemptsStore) DeleteLoginAttempt(ctx context.Context, key string) error {
+	return checkAffected(ls.deleteStmt.ExecContext(ctx, loginAttempt{ID: key}))
+}
@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type migration struct {
+	version     int
+	description string
+	statements  func(ConfigTableNames) []string
+}
+
+// NOTE: Append only. Each entry is applied once, in order, and recorded by
+// version in the migrations table. Never edit a migration that has shipped.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create users and invites tables",
+		statements: func(t ConfigTableNames) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS ` + t.Users + ` (
+					id INTEGER PRIMARY KEY,
+					name TEXT NOT NULL,
+					display_name TEXT,
+					password_hash TEXT NOT NULL,
+					admin INTEGER DEFAULT 0
+				)`,
+				`CREATE TABLE IF NOT EXISTS ` + t.Invites + ` (
+					id INTEGER PRIMARY KEY,
+					owner_id INTEGER,
+					FOREIGN KEY(owner_id) REFERENCES ` + t.Users + `(id)
+				)`,
+			}
+		},
+	},
+	{
+		version:     2,
+		description: "enforce unique user names",
+		statements: func(t ConfigTableNames) []string {
+			return []string{
+				`CREATE UNIQUE INDEX IF NOT EXISTS ` + t.Users + `_name_idx ON ` + t.Users + ` (name)`,
+			}
+		},
+	},
+	{
+		version:     3,
+		description: "create refresh tokens table",
+		statements: func(t ConfigTableNames) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS ` + t.RefreshTokens + ` (
+					id TEXT PRIMARY KEY,
+					family_id TEXT NOT NULL,
+					user_id INTEGER NOT NULL,
+					expiration TIMESTAMP NOT NULL,
+					rotated INTEGER DEFAULT 0,
+					FOREIGN KEY(user_id) REFERENCES ` + t.Users + `(id)
+				)`,
+				`CREATE INDEX IF NOT EXISTS ` + t.RefreshTokens + `_family_idx ON ` + t.RefreshTokens + ` (family_id)`,
+			}
+		},
+	},
+	{
+		version:     4,
+		description: "create token revocations table",
+		statements: func(t ConfigTableNames) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS ` + t.Revocations + ` (
+					id TEXT PRIMARY KEY,
+					token_id TEXT,
+					subject TEXT,
+					issued_before TIMESTAMP,
+					expiration TIMESTAMP NOT NULL
+				)`,
+			}
+		},
+	},
+	{
+		version:     5,
+		description: "create login attempts table",
+		statements: func(t ConfigTableNames) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS ` + t.LoginAttempts + ` (
+					id TEXT PRIMARY KEY,
+					failures INTEGER NOT NULL,
+					last_failure TIMESTAMP NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS ` + t.LoginAttempts + `_last_failure_idx ON ` + t.LoginAttempts + ` (last_failure)`,
+			}
+		},
+	},
+}
+
+// LatestVersion TODO.
+func LatestVersion() int { return migrations[len(migrations)-1].version }
+
+// Migrater TODO.
+type Migrater struct {
+	db     *sqlx.DB
+	tables ConfigTableNames
+}
+
+// NewMigrater TODO.
+func NewMigrater(cfg Config) (*Migrater, error) {
+	db, err := connect(cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrater{db: db, tables: cfg.TableNames}, nil
+}
+
+// Close TODO.
+func (m *Migrater) Close() error { return m.db.Close() }
+
+func (m *Migrater) init(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.tables.Migrations+` (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func (m *Migrater) current(ctx context.Context) (int, error) {
+	var res int
+
+	err := m.db.GetContext(ctx, &res, "SELECT COALESCE(MAX(version), 0) FROM "+m.tables.Migrations)
+	return res, err
+}
+
+// Version TODO.
+//
+// Read only, a database without a migrations table is at version 0.
+func (m *Migrater) Version(ctx context.Context) (int, error) {
+	var count int
+
+	qryStr := "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?"
+	if err := m.db.GetContext(ctx, &count, qryStr, m.tables.Migrations); err != nil || count == 0 {
+		return 0, err
+	}
+
+	return m.current(ctx)
+}
+
+// Pending TODO.
+func Pending(current int) []string {
+	var res []string
+
+	for _, item := range migrations {
+		if item.version > current {
+			res = append(res, fmt.Sprintf("%d: %s", item.version, item.description))
+		}
+	}
+
+	return res
+}
+
+// Migrate TODO.
+func (m *Migrater) Migrate(ctx context.Context) (applied int, err error) {
+	if err = m.init(ctx); err != nil {
+		return
+	}
+
+	current, err := m.current(ctx)
+	if err != nil {
+		return
+	}
+
+	if latest := LatestVersion(); current > latest {
+		err = fmt.Errorf("database schema version %d is newer than latest known version %d", current, latest)
+		return
+	}
+
+	for _, item := range migrations {
+		if item.version <= current {
+			continue
+		}
+
+		if err = m.apply(ctx, item); err != nil {
+			err = fmt.Errorf("migration %d (%s) failed: %w", item.version, item.description, err)
+			return
+		}
+
+		applied++
+	}
+
+	return
+}
+
+func (m *Migrater) apply(ctx context.Context, item migration) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range item.statements(m.tables) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	qryStr := "INSERT INTO " + m.tables.Migrations + " (version, description) VALUES (?, ?)"
+	if _, err := tx.ExecContext(ctx, qryStr, item.version, item.description); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
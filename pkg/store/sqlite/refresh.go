@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+)
+
+type refreshToken struct {
+	ID         string    `db:"id"`
+	FamilyID   string    `db:"family_id"`
+	UserID     uint64    `db:"user_id"`
+	Expiration time.Time `db:"expiration"`
+	Rotated    bool      `db:"rotated"`
+}
+
+func newRefreshToken(data model.RefreshToken) (refreshToken, error) {
+	rt := refreshToken{
+		ID:         data.ID,
+		FamilyID:   data.FamilyID,
+		Expiration: data.Expiration.UTC(),
+		Rotated:    data.Rotated,
+	}
+
+	return rt, rt.setUserID(data.UserID)
+}
+
+func (rt *refreshToken) setUserID(id string) (err error) {
+	rt.UserID, err = parseID(id)
+	return
+}
+
+func (rt refreshToken) toModel() model.RefreshToken {
+	return model.RefreshToken{
+		ID:         rt.ID,
+		FamilyID:   rt.FamilyID,
+		UserID:     strconv.FormatInt(int64(rt.UserID), 10),
+		Expiration: rt.Expiration,
+		Rotated:    rt.Rotated,
+	}
+}
+
+type refreshTokensStore struct {
+	db *sqlx.DB
+
+	createStmt, readStmt, rotateStmt, deleteFamilyStmt, revokeStmt, revokeUserStmt *sqlx.NamedStmt
+}
+
+func newRefreshTokensStore(tableName string, db *sqlx.DB) (*refreshTokensStore, error) {
+	createStmt, err := db.PrepareNamed("INSERT INTO " + tableName + " (id, family_id, user_id, expiration, rotated) VALUES (:id, :family_id, :user_id, :expiration, :rotated)")
+	if err != nil {
+		return nil, err
+	}
+
+	readStmt, err := db.PrepareNamed("SELECT * FROM " + tableName + " WHERE id=:id")
+	if err != nil {
+		return nil, err
+	}
+
+	rotateStmt, err := db.PrepareNamed("UPDATE " + tableName + " SET rotated=1 WHERE id=:id AND rotated=0")
+	if err != nil {
+		return nil, err
+	}
+
+	deleteFamilyStmt, err := db.PrepareNamed("DELETE FROM " + tableName + " WHERE family_id=:family_id")
+	if err != nil {
+		return nil, err
+	}
+
+	revokeStmt, err := db.PrepareNamed("DELETE FROM " + tableName + " WHERE family_id=(SELECT family_id FROM " + tableName + " WHERE id=:id)")
+	if err != nil {
+		return nil, err
+	}
+
+	revokeUserStmt, err := db.PrepareNamed("DELETE FROM " + tableName + " WHERE user_id=:user_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return &refreshTokensStore{
+		db: db,
+
+		createStmt:       createStmt,
+		readStmt:         readStmt,
+		rotateStmt:       rotateStmt,
+		deleteFamilyStmt: deleteFamilyStmt,
+		revokeStmt:       revokeStmt,
+		revokeUserStmt:   revokeUserStmt,
+	}, nil
+}
+
+func (rs *refreshTokensStore) CreateRefreshToken(ctx context.Context, data model.RefreshToken) error {
+	rt, err := newRefreshToken(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = rs.createStmt.ExecContext(ctx, rt)
+	return storeErr(err)
+}
+
+func (rs *refreshTokensStore) rotate(ctx context.Context, tx *sqlx.Tx, id string, next *refreshToken) error {
+	prev := refreshToken{ID: id}
+
+	switch err := tx.NamedStmt(rs.readStmt).GetContext(ctx, &prev, prev); {
+	case errors.Is(err, sql.ErrNoRows):
+		return storeerr.NotFound(err)
+	case err != nil:
+		return err
+	}
+
+	// Presenting an already rotated token => revoke the entire family
+	if prev.Rotated {
+		if _, err := tx.NamedStmt(rs.deleteFamilyStmt).ExecContext(ctx, prev); err != nil {
+			return err
+		}
+		return storeerr.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(prev.Expiration) {
+		return storeerr.ErrRefreshTokenExpired
+	}
+
+	if err := checkAffected(tx.NamedStmt(rs.rotateStmt).ExecContext(ctx, prev)); err != nil {
+		return err
+	}
+
+	next.FamilyID, next.UserID = prev.FamilyID, prev.UserID
+
+	_, err := tx.NamedStmt(rs.createStmt).ExecContext(ctx, next)
+	return storeErr(err)
+}
+
+func (rs *refreshTokensStore) RotateRefreshToken(ctx context.Context, id string, next model.RefreshToken) (model.RefreshToken, error) {
+	nextRT := refreshToken{
+		ID:         next.ID,
+		Expiration: next.Expiration.UTC(),
+	}
+
+	tx, err := rs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	switch err := rs.rotate(ctx, tx, id, &nextRT); {
+	case errors.Is(err, storeerr.ErrRefreshTokenReused):
+		// Persist the family revocation, but still report the reuse
+		if commitErr := tx.Commit(); commitErr != nil {
+			return model.RefreshToken{}, commitErr
+		}
+		return model.RefreshToken{}, err
+	case err != nil:
+		tx.Rollback()
+		return model.RefreshToken{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return nextRT.toModel(), nil
+}
+
+func (rs *refreshTokensStore) RevokeRefreshToken(ctx context.Context, id string) error {
+	return checkAffected(rs.revokeStmt.ExecContext(ctx, refreshToken{ID: id}))
+}
+
+func (rs *refreshTokensStore) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	var rt refreshToken
+	if err := rt.setUserID(userID); err != nil {
+		return err
+	}
+
+	_, err := rs.revokeUserStmt.ExecContext(ctx, rt)
+	return err
+}
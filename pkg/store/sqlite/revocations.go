@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
+)
+
+const revocationSubjectPrefix = "sub:"
+
+type revocation struct {
+	ID           string     `db:"id"`
+	TokenID      *string    `db:"token_id"`
+	Subject      *string    `db:"subject"`
+	IssuedBefore *time.Time `db:"issued_before"`
+	Expiration   time.Time  `db:"expiration"`
+}
+
+func newRevocation(data model.Revocation) revocation {
+	res := revocation{Expiration: data.Expiration.UTC()}
+
+	if data.TokenID != "" {
+		res.ID, res.TokenID = data.TokenID, &data.TokenID
+		return res
+	}
+
+	issuedBefore := data.IssuedBefore.UTC()
+	res.ID, res.Subject, res.IssuedBefore = revocationSubjectPrefix+data.Subject, &data.Subject, &issuedBefore
+	return res
+}
+
+func (r revocation) toModel() model.Revocation {
+	res := model.Revocation{Expiration: r.Expiration}
+
+	if r.TokenID != nil {
+		res.TokenID = *r.TokenID
+	}
+
+	if r.Subject != nil {
+		res.Subject = *r.Subject
+	}
+
+	if r.IssuedBefore != nil {
+		res.IssuedBefore = *r.IssuedBefore
+	}
+
+	return res
+}
+
+type revocationsStore struct {
+	createStmt, consumeStmt, listStmt, pruneStmt *sqlx.NamedStmt
+}
+
+func newRevocationsStore(tableName string, db *sqlx.DB) (*revocationsStore, error) {
+	createStmt, err := db.PrepareNamed("INSERT OR REPLACE INTO " + tableName + " (id, token_id, subject, issued_before, expiration) VALUES (:id, :token_id, :subject, :issued_before, :expiration)")
+	if err != nil {
+		return nil, err
+	}
+
+	consumeStmt, err := db.PrepareNamed("INSERT INTO " + tableName + " (id, token_id, subject, issued_before, expiration) VALUES (:id, :token_id, :subject, :issued_before, :expiration)")
+	if err != nil {
+		return nil, err
+	}
+
+	listStmt, err := db.PrepareNamed("SELECT * FROM " + tableName + " WHERE expiration>:expiration")
+	if err != nil {
+		return nil, err
+	}
+
+	pruneStmt, err := db.PrepareNamed("DELETE FROM " + tableName + " WHERE expiration<=:expiration")
+	if err != nil {
+		return nil, err
+	}
+
+	return &revocationsStore{
+		createStmt:  createStmt,
+		consumeStmt: consumeStmt,
+		listStmt:    listStmt,
+		pruneStmt:   pruneStmt,
+	}, nil
+}
+
+func (rs *revocationsStore) CreateRevocation(ctx context.Context, data model.Revocation) error {
+	_, err := rs.createStmt.ExecContext(ctx, newRevocation(data))
+	return storeErr(err)
+}
+
+// NOTE: Unlike CreateRevocation, fails when the token id is already revoked,
+// so exactly one caller consumes a single use token
+func (rs *revocationsStore) ConsumeToken(ctx context.Context, data model.Revocation) error {
+	_, err := rs.consumeStmt.ExecContext(ctx, newRevocation(data))
+	if errors.Is(storeErr(err), storeerr.ErrConflict) {
+		return storeerr.ErrTokenConsumed
+	}
+	return storeErr(err)
+}
+
+func (rs *revocationsStore) ListRevocations(ctx context.Context) ([]model.Revocation, error) {
+	var (
+		now     = revocation{Expiration: time.Now().UTC()}
+		revList = make([]revocation, 0)
+	)
+
+	if err := rs.listStmt.SelectContext(ctx, &revList, now); err != nil {
+		return nil, storeErr(err)
+	}
+
+	res := make([]model.Revocation, len(revList))
+	for i, item := range revList {
+		res[i] = item.toModel()
+	}
+
+	return res, nil
+}
+
+// NOTE: Expired revocations are no longer needed, tokens they cover are
+// expired too
+func (rs *revocationsStore) PruneRevocations(ctx context.Context, before time.Time) error {
+	_, err := rs.pruneStmt.ExecContext(ctx, revocation{Expiration: before.UTC()})
+	return storeErr(err)
+}
@@ -1,12 +1,17 @@
 package sqlite
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/oligarch316/go-auth-service/pkg/model"
+	"github.com/oligarch316/go-auth-service/pkg/password"
+	"github.com/oligarch316/go-auth-service/pkg/store/storeerr"
 )
 
 type user struct {
@@ -17,15 +22,27 @@ type user struct {
 	Admin        bool    `db:"admin"`
 }
 
-func (u *user) setID(id string) error {
-	idInt, err := strconv.ParseInt(id, 10, 64)
-	u.ID = uint64(idInt)
-	return err
+func newUser(passwords *password.Registry, name, pw string, mData model.UserUpdate) (user, error) {
+	u := user{
+		Name:        name,
+		DisplayName: mData.DisplayName,
+	}
+
+	if mData.Admin != nil {
+		u.Admin = *mData.Admin
+	}
+
+	return u, u.setPasswordHash(passwords, pw)
+}
+
+func (u *user) setID(id string) (err error) {
+	u.ID, err = parseID(id)
+	return
 }
 
-func (u *user) setPasswordHash(password string) error {
+func (u *user) setPasswordHash(passwords *password.Registry, pw string) error {
 	ph := make(model.PasswordHash, 0)
-	err := ph.Set(password)
+	err := ph.Set(passwords, pw)
 	u.PasswordHash = ph
 	return err
 }
@@ -48,11 +65,12 @@ func (u user) toModel() model.User {
 type usersStore struct {
 	db        *sqlx.DB
 	tableName string
+	passwords *password.Registry
 
 	createStmt, readStmt, deleteStmt, lookupStmt *sqlx.NamedStmt
 }
 
-func newUsersStore(tableName string, db *sqlx.DB) (*usersStore, error) {
+func newUsersStore(tableName string, db *sqlx.DB, passwords *password.Registry) (*usersStore, error) {
 	createStmt, err := db.PrepareNamed("INSERT INTO " + tableName + " (name, display_name, password_hash, admin) VALUES (:name, :display_name, :password_hash, :admin)")
 	if err != nil {
 		return nil, err
@@ -76,6 +94,7 @@ func newUsersStore(tableName string, db *sqlx.DB) (*usersStore, error) {
 	return &usersStore{
 		db:        db,
 		tableName: tableName,
+		passwords: passwords,
 
 		createStmt: createStmt,
 		readStmt:   readStmt,
@@ -84,50 +103,74 @@ func newUsersStore(tableName string, db *sqlx.DB) (*usersStore, error) {
 	}, nil
 }
 
-func (us *usersStore) CreateUser(name, password string, mData model.UserUpdate) (model.User, error) {
-	u := user{
-		Name:        name,
-		DisplayName: mData.DisplayName,
+func (us *usersStore) insert(ctx context.Context, tx *sqlx.Tx, u *user) error {
+	lookupStmt, createStmt := tx.NamedStmt(us.lookupStmt), tx.NamedStmt(us.createStmt)
+
+	// Check for an existing user of the same name
+	var existing user
+	switch err := lookupStmt.GetContext(ctx, &existing, u); {
+	case err == nil:
+		return storeerr.ErrUsernameTaken
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
 	}
 
-	if err := u.setPasswordHash(password); err != nil {
-		return model.User{}, err
+	result, err := createStmt.ExecContext(ctx, u)
+	switch err = storeErr(err); {
+	case errors.Is(err, storeerr.ErrConflict):
+		return storeerr.ErrUsernameTaken
+	case err != nil:
+		return err
 	}
 
-	if mData.Admin != nil {
-		u.Admin = *mData.Admin
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return err
 	}
 
-	result, err := us.createStmt.Exec(u)
+	u.ID = uint64(newID)
+	return nil
+}
+
+func (us *usersStore) CreateUser(ctx context.Context, name, password string, mData model.UserUpdate) (model.User, error) {
+	u, err := newUser(us.passwords, name, password, mData)
 	if err != nil {
 		return model.User{}, err
 	}
 
-	newID, err := result.LastInsertId()
+	tx, err := us.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return model.User{}, err
 	}
 
-	u.ID = uint64(newID)
+	if err := us.insert(ctx, tx, &u); err != nil {
+		tx.Rollback()
+		return model.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.User{}, err
+	}
 
 	return u.toModel(), nil
 }
 
-func (us *usersStore) ReadUser(id string) (res model.User, err error) {
+func (us *usersStore) ReadUser(ctx context.Context, id string) (res model.User, err error) {
 	var u user
 
 	if err = u.setID(id); err != nil {
 		return
 	}
 
-	if err = us.readStmt.Get(&u, u); err != nil {
+	if err = us.readStmt.GetContext(ctx, &u, u); err != nil {
+		err = storeErr(err)
 		return
 	}
 
 	return u.toModel(), nil
 }
 
-func (us *usersStore) UpdateUser(id string, mData model.UserUpdate) error {
+func (us *usersStore) UpdateUser(ctx context.Context, id string, mData model.UserUpdate) error {
 	var u user
 
 	if err := u.setID(id); err != nil {
@@ -137,7 +180,7 @@ func (us *usersStore) UpdateUser(id string, mData model.UserUpdate) error {
 	var setItems []string
 
 	if mData.Password != nil {
-		if err := u.setPasswordHash(*mData.Password); err != nil {
+		if err := u.setPasswordHash(us.passwords, *mData.Password); err != nil {
 			return err
 		}
 		setItems = append(setItems, "password_hash=:password_hash")
@@ -158,28 +201,91 @@ func (us *usersStore) UpdateUser(id string, mData model.UserUpdate) error {
 	}
 
 	qryStr := fmt.Sprintf("UPDATE %s SET %s WHERE id=:id", us.tableName, strings.Join(setItems, ","))
-	_, err := us.db.NamedExec(qryStr, u)
-
-	return err
+	return checkAffected(us.db.NamedExecContext(ctx, qryStr, u))
 }
 
-func (us *usersStore) DeleteUser(id string) error {
+func (us *usersStore) DeleteUser(ctx context.Context, id string) error {
 	var u user
 
 	if err := u.setID(id); err != nil {
 		return err
 	}
 
-	_, err := us.deleteStmt.Exec(u)
-	return err
+	return checkAffected(us.deleteStmt.ExecContext(ctx, u))
 }
 
-func (us *usersStore) LookupUser(name string) (model.User, error) {
+func (us *usersStore) LookupUser(ctx context.Context, name string) (model.User, error) {
 	u := user{Name: name}
 
-	if err := us.lookupStmt.Get(&u, u); err != nil {
-		return model.User{}, err
+	if err := us.lookupStmt.GetContext(ctx, &u, u); err != nil {
+		return model.User{}, storeErr(err)
 	}
 
 	return u.toModel(), nil
 }
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (us *usersStore) filterClause(filter model.UserFilter) (string, map[string]interface{}) {
+	var (
+		clauses []string
+		args    = make(map[string]interface{})
+	)
+
+	if filter.NamePrefix != "" {
+		clauses = append(clauses, `name LIKE :name_prefix ESCAPE '\'`)
+		args["name_prefix"] = likeEscaper.Replace(filter.NamePrefix) + "%"
+	}
+
+	if filter.Admin != nil {
+		clauses = append(clauses, "admin=:admin")
+		args["admin"] = *filter.Admin
+	}
+
+	if len(clauses) < 1 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+func (us *usersStore) ListUsers(ctx context.Context, filter model.UserFilter, offset, limit int) ([]model.User, error) {
+	where, args := us.filterClause(filter)
+
+	// Negative limit => no limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	args["offset"], args["limit"] = offset, limit
+
+	qryStr, qryArgs, err := us.db.BindNamed("SELECT * FROM "+us.tableName+where+" ORDER BY id LIMIT :limit OFFSET :offset", args)
+	if err != nil {
+		return nil, err
+	}
+
+	userList := make([]user, 0)
+	if err := us.db.SelectContext(ctx, &userList, qryStr, qryArgs...); err != nil {
+		return nil, storeErr(err)
+	}
+
+	res := make([]model.User, len(userList))
+	for i, item := range userList {
+		res[i] = item.toModel()
+	}
+
+	return res, nil
+}
+
+func (us *usersStore) CountUsers(ctx context.Context, filter model.UserFilter) (int, error) {
+	where, args := us.filterClause(filter)
+
+	qryStr, qryArgs, err := us.db.BindNamed("SELECT COUNT(*) FROM "+us.tableName+where, args)
+	if err != nil {
+		return 0, err
+	}
+
+	var res int
+	err = us.db.GetContext(ctx, &res, qryStr, qryArgs...)
+	return res, storeErr(err)
+}
@@ -0,0 +1,59 @@
+package storeerr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds TODO.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrInvalidID  = errors.New("invalid id")
+	ErrConstraint = errors.New("constraint violation")
+)
+
+var (
+	// ErrInviteConsumed TODO.
+	ErrInviteConsumed error = Error{Kind: ErrConflict, Err: errors.New("invite does not exist or has already been consumed")}
+
+	// ErrUsernameTaken TODO.
+	ErrUsernameTaken error = Error{Kind: ErrConflict, Err: errors.New("user name already taken")}
+
+	// ErrRefreshTokenReused TODO.
+	ErrRefreshTokenReused error = Error{Kind: ErrConflict, Err: errors.New("refresh token already rotated")}
+
+	// ErrTokenConsumed TODO.
+	ErrTokenConsumed error = Error{Kind: ErrConflict, Err: errors.New("token has already been consumed")}
+
+	// ErrRefreshTokenExpired TODO.
+	ErrRefreshTokenExpired error = Error{Kind: ErrNotFound, Err: errors.New("refresh token expired")}
+)
+
+// Error TODO.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// NotFound TODO.
+func NotFound(err error) error { return Error{Kind: ErrNotFound, Err: err} }
+
+// Conflict TODO.
+func Conflict(err error) error { return Error{Kind: ErrConflict, Err: err} }
+
+// InvalidID TODO.
+func InvalidID(id string, err error) error {
+	return Error{Kind: ErrInvalidID, Err: fmt.Errorf("'%s': %w", id, err)}
+}
+
+// Constraint TODO.
+func Constraint(err error) error { return Error{Kind: ErrConstraint, Err: err} }
+
+func (e Error) Error() string { return fmt.Sprintf("%s: %s", e.Kind, e.Err) }
+
+// Is TODO.
+func (e Error) Is(target error) bool { return target == e.Kind }
+
+// Unwrap TODO.
+func (e Error) Unwrap() error { return e.Err }
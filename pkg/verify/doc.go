@@ -0,0 +1,30 @@
+/*
+Package verify validates access tokens issued by the auth service in process,
+for services that consume those tokens but don't want an HTTP hop per request.
+
+A Verifier pulls public keys from the secret service (cached, refreshed in the
+background) and checks signature, issuer, audience, expiry and, optionally,
+scopes. Verified claims are placed into the request context:
+
+	v, err := verify.New(
+		verify.WithAudience("orders"),
+		verify.WithIssuers("authsvc"),
+		verify.WithScopes("orders:read"),
+		verify.WithCacheOptions(token.WithClientConfig(clientCfg)),
+	)
+	if err != nil { ... }
+	defer v.Close()
+
+	// net/http
+	http.Handle("/orders", v.Middleware(ordersHandler))
+
+	// gRPC
+	grpc.NewServer(
+		grpc.UnaryInterceptor(v.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(v.StreamServerInterceptor()),
+	)
+
+	// Within a handler
+	subject, ok := verify.SubjectFromContext(ctx)
+*/
+package verify
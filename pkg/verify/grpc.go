@@ -0,0 +1,68 @@
+package verify
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const metadataKeyAuthorization = "authorization"
+
+func (v *Verifier) verifyGRPC(ctx context.Context) (context.Context, error) {
+	var hdrVal string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(metadataKeyAuthorization); len(vals) > 0 {
+			hdrVal = vals[0]
+		}
+	}
+
+	ctx, err := v.verifyContext(ctx, hdrVal)
+
+	switch {
+	case errors.Is(err, ErrInsufficientScope):
+		return ctx, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return ctx, status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+	}
+
+	return ctx, nil
+}
+
+// UnaryServerInterceptor rejects calls without a valid bearer token in the
+// "authorization" metadata, and otherwise places the verified claims in the
+// handler context.
+func (v *Verifier) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := v.verifyGRPC(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor.
+func (v *Verifier) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := v.verifyGRPC(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *serverStream) Context() context.Context { return ss.ctx }
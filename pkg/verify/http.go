@@ -0,0 +1,28 @@
+package verify
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Middleware rejects requests without a valid bearer token, and otherwise
+// calls next with the verified claims in the request context.
+//
+// Rejections are 401 Unauthorized, or 403 Forbidden when only scopes are
+// lacking.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := v.verifyContext(r.Context(), r.Header.Get("Authorization"))
+
+		switch {
+		case errors.Is(err, ErrInsufficientScope):
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		case err != nil:
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		default:
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+	})
+}
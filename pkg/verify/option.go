@@ -0,0 +1,86 @@
+package verify
+
+import (
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+	"github.com/oligarch316/go-skeleton/pkg/config/types"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures a Verifier.
+type Option func(*Verifier)
+
+// WithAudience sets the audience tokens must be issued for. Required.
+func WithAudience(name string) Option {
+	return func(v *Verifier) { v.validater.Claims.AudienceName = name }
+}
+
+// WithIssuers sets the issuers tokens are accepted from. Required.
+func WithIssuers(names ...string) Option {
+	return func(v *Verifier) { v.validater.Claims.AllowedIssuers = ctype.NewStringSet(names...) }
+}
+
+// WithScopes sets scopes every token must grant.
+func WithScopes(scopes ...string) Option {
+	return func(v *Verifier) { v.scopes = scopes }
+}
+
+// WithRevocations enables revocation checks against the given list.
+func WithRevocations(revocations *token.RevocationList) Option {
+	return func(v *Verifier) { v.validater.Revocations = revocations }
+}
+
+// WithCache shares an existing key cache. The Verifier does not close it.
+func WithCache(cache *token.Cache) Option {
+	return func(v *Verifier) { v.cache = cache }
+}
+
+// WithCacheOptions configures the key cache created by the Verifier.
+func WithCacheOptions(opts ...token.CacheOption) Option {
+	return func(v *Verifier) { v.cacheOpts = append(v.cacheOpts, opts...) }
+}
+
+// WithOnErrorHook registers a hook called for every rejected token.
+func WithOnErrorHook(hook func(error)) Option {
+	return func(v *Verifier) { v.onError = hook }
+}
+
+// Config is a serializable Verifier configuration.
+type Config struct {
+	Audience string            `json:"audience"`
+	Issuers  []string          `json:"issuers"`
+	Scopes   []string          `json:"scopes"`
+	Cache    token.ConfigCache `json:"cache"`
+}
+
+// DefaultConfig returns a Config with default key cache settings.
+func DefaultConfig() Config {
+	return Config{Cache: token.DefaultCacheConfig()}
+}
+
+// Options converts the Config into Verifier options.
+func (c Config) Options() []Option {
+	return []Option{
+		WithAudience(c.Audience),
+		WithIssuers(c.Issuers...),
+		WithScopes(c.Scopes...),
+		WithCacheOptions(c.Cache.Options()...),
+	}
+}
+
+// MarshalLogObject TODO.
+func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("audience", c.Audience)
+	enc.AddArray("issuers", zapcore.ArrayMarshalerFunc(func(aenc zapcore.ArrayEncoder) error {
+		for _, item := range c.Issuers {
+			aenc.AppendString(item)
+		}
+		return nil
+	}))
+	enc.AddArray("scopes", zapcore.ArrayMarshalerFunc(func(aenc zapcore.ArrayEncoder) error {
+		for _, item := range c.Scopes {
+			aenc.AppendString(item)
+		}
+		return nil
+	}))
+	return enc.AddObject("cache", c.Cache)
+}
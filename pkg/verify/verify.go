@@ -0,0 +1,152 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/oligarch316/go-auth-service/pkg/http/secret/token"
+)
+
+var (
+	// ErrUnauthenticated wraps any failure to verify a presented token.
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrInsufficientScope is returned for valid tokens missing a required scope.
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// Claims are the verified claims of a token.
+type Claims struct {
+	token.StandardClaims
+
+	// Space delimited, per RFC 8693
+	Scope string `json:"scope,omitempty"`
+}
+
+// Scopes returns the individual scopes granted by the token.
+func (c Claims) Scopes() []string { return strings.Fields(c.Scope) }
+
+// HasScope reports whether the token grants the given scope.
+func (c Claims) HasScope(scope string) bool {
+	for _, item := range c.Scopes() {
+		if item == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx carrying the given claims.
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext returns the verified claims carried by ctx, if any.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// SubjectFromContext returns the verified subject carried by ctx, if any.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	claims, ok := FromContext(ctx)
+	return claims.Subject, ok
+}
+
+// Verifier validates tokens against keys served by the secret service.
+type Verifier struct {
+	validater token.Validater
+	scopes    []string
+
+	cache     *token.Cache
+	ownsCache bool
+	cacheOpts []token.CacheOption
+
+	onError func(error)
+}
+
+// New creates a Verifier. Unless WithCache is given, a key cache is created
+// from the WithCacheOptions and released by Close.
+func New(opts ...Option) (*Verifier, error) {
+	res := &Verifier{onError: func(error) {}}
+
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	if res.validater.Claims.AudienceName == "" {
+		return nil, errors.New("verify: audience is required")
+	}
+
+	if len(res.validater.Claims.AllowedIssuers) == 0 {
+		return nil, errors.New("verify: at least one issuer is required")
+	}
+
+	if res.cache == nil {
+		cache, err := token.NewCache(res.cacheOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("verify: failed to create key cache: %w", err)
+		}
+
+		res.cache, res.ownsCache = cache, true
+	}
+
+	res.validater.Secret = res.cache
+	return res, nil
+}
+
+// Close releases the key cache, if owned by the Verifier.
+func (v *Verifier) Close() error {
+	if v.ownsCache {
+		return v.cache.Close()
+	}
+	return nil
+}
+
+// Verify validates a compact serialized token and returns its claims.
+//
+// Failures wrap either ErrUnauthenticated or ErrInsufficientScope.
+func (v *Verifier) Verify(tokenStr string) (Claims, error) {
+	var claims Claims
+
+	if err := v.cache.Validate(tokenStr, &claims); err != nil {
+		return claims, fmt.Errorf("%w: %s", ErrUnauthenticated, err)
+	}
+
+	if err := v.validater.CheckClaims(claims.StandardClaims); err != nil {
+		return claims, fmt.Errorf("%w: %s", ErrUnauthenticated, err)
+	}
+
+	for _, scope := range v.scopes {
+		if !claims.HasScope(scope) {
+			return claims, fmt.Errorf("%w: missing '%s'", ErrInsufficientScope, scope)
+		}
+	}
+
+	return claims, nil
+}
+
+// VerifyAuthorization validates the token carried by an "Authorization:
+// Bearer <token>" header value.
+func (v *Verifier) VerifyAuthorization(hdrVal string) (Claims, error) {
+	tokenStr, err := token.ParseAuthorization(hdrVal)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrUnauthenticated, err)
+	}
+
+	return v.Verify(tokenStr)
+}
+
+func (v *Verifier) verifyContext(ctx context.Context, hdrVal string) (context.Context, error) {
+	claims, err := v.VerifyAuthorization(hdrVal)
+	if err != nil {
+		v.onError(err)
+		return ctx, err
+	}
+
+	return NewContext(ctx, claims), nil
+}